@@ -0,0 +1,147 @@
+package diagnostics
+
+import (
+	"database/sql" // Database/sql package for database operations
+	"fmt"          // For formatted I/O operations
+	"strconv"      // For writing the threshold in the conditional format
+	"strings"      // For string manipulation
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+/*
+ * AlertRule is a query's `alert` setting, the "if this value is high, investigate" guidance of a diagnostic
+ * query turned into a threshold flagging the cells that need attention.
+ *
+ * Fields:
+ * - Column: The numeric column checked, its name or its column label.
+ * - Op: The comparison operator, one of >, >=, <, <=, = and !=.
+ * - Value: The threshold the column values are compared to.
+ */
+type AlertRule struct {
+	Column string  `json:"column" toml:"column"` // Numeric column checked
+	Op     string  `json:"op" toml:"op"`         // Comparison operator
+	Value  float64 `json:"value" toml:"value"`   // Threshold compared to
+}
+
+// Excel operator of every `alert` comparison operator, "==" and "<>" are accepted as aliases
+var alertOperators = map[string]string{
+	">": ">", ">=": ">=", "<": "<", "<=": "<=", "=": "=", "==": "=", "!=": "<>", "<>": "<>",
+}
+
+/*
+ * validateAlertRule returns the problem of a query's `alert` rule, nil when the query has none or it is valid.
+ *
+ * Notes:
+ * - An alert on a column with a value map is rejected: the mapped cells hold display text, which the red
+ *   conditional format cannot compare to the threshold, Excel ranks any text above every number.
+ */
+func validateAlertRule(query Query) error {
+	rule := query.Alert
+	if rule == nil {
+		return nil
+	}
+	if strings.TrimSpace(rule.Column) == "" {
+		return fmt.Errorf("alert has no column")
+	}
+	if _, ok := alertOperators[strings.TrimSpace(rule.Op)]; !ok {
+		return fmt.Errorf("alert has the unknown operator %q, expected >, >=, <, <=, = or !=", rule.Op)
+	}
+	for column := range query.ValueMaps {
+		if strings.EqualFold(column, rule.Column) || strings.EqualFold(query.ColumnLabels[column], rule.Column) {
+			return fmt.Errorf("alert column %q has a value map, its cells hold text that cannot be compared to the threshold", rule.Column)
+		}
+	}
+	return nil
+}
+
+/*
+ * matches reports whether a column value meets the alert rule, values that are not numbers never do.
+ */
+func (rule *AlertRule) matches(v interface{}) bool {
+	n, ok := numericValue(v)
+	if !ok {
+		return false
+	}
+	switch alertOperators[strings.TrimSpace(rule.Op)] {
+	case ">":
+		return n > rule.Value
+	case ">=":
+		return n >= rule.Value
+	case "<":
+		return n < rule.Value
+	case "<=":
+		return n <= rule.Value
+	case "=":
+		return n == rule.Value
+	case "<>":
+		return n != rule.Value
+	}
+	return false
+}
+
+// String returns the rule as written in the queries file, e.g. "wait_time_ms > 10000"
+func (rule *AlertRule) String() string {
+	return fmt.Sprintf("%s %s %s", rule.Column, strings.TrimSpace(rule.Op), strconv.FormatFloat(rule.Value, 'f', -1, 64))
+}
+
+/*
+ * alertColumn returns the index of the query's `alert` column among the sheet columns, matching its name or
+ * label case insensitively, or -1 when the query has no alert. A missing or non numeric column is reported and
+ * returns -1.
+ */
+func alertColumn(columns []string, columnTypes []*sql.ColumnType, query Query) int {
+	rule := query.Alert
+	if rule == nil || validateAlertRule(query) != nil {
+		return -1
+	}
+
+	labels := applyColumnLabels(columns, query)
+	for i := range columns {
+		if !strings.EqualFold(columns[i], rule.Column) && !strings.EqualFold(labels[i], rule.Column) {
+			continue
+		}
+		if typeName := strings.ToUpper(columnTypes[i].DatabaseTypeName()); !rankableColumnTypes[typeName] {
+			logWarn("Query %s: alert column %q is %s, not numeric, nothing is flagged", query.Name, rule.Column, typeName)
+			return -1
+		}
+		return i
+	}
+	logWarn("Query %s: alert column %q is not a column of the result, nothing is flagged", query.Name, rule.Column)
+	return -1
+}
+
+/*
+ * highlightAlerts fills the cells of the alert column meeting the query's `alert` rule in red.
+ *
+ * Notes:
+ * - The matching rows are counted while they are written, see `writeRows`, the conditional format compares
+ *   each cell to the threshold so the highlight follows the values when the rows are sorted in Excel later.
+ * - The alert column is written as numbers, DECIMAL and MONEY values included, so Excel can compare them.
+ */
+func (s *resultSheet) highlightAlerts() {
+	if s.alertColumn == -1 || s.rowIndex <= resultFirstDataRow {
+		return
+	}
+	rule := s.query.Alert
+
+	column, _ := excelize.ColumnNumberToName(s.alertColumn + s.firstColumn())
+	threshold := strconv.FormatFloat(rule.Value, 'f', -1, 64)
+	formula := fmt.Sprintf("AND(ISNUMBER(%s%d),%s%d%s%s)", column, resultFirstDataRow, column, resultFirstDataRow, alertOperators[strings.TrimSpace(rule.Op)], threshold)
+
+	styleID, err := s.report.conditionalStyle("alert", &excelize.Style{
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}},
+		Font: &excelize.Font{Color: "9C0006", Bold: true},
+	})
+	if err != nil {
+		logError("Failed to create the alert style: %v", err)
+		return
+	}
+	cells := fmt.Sprintf("%s%d:%s%d", column, resultFirstDataRow, column, s.rowIndex-1)
+	err = s.f.SetConditionalFormat(s.name, cells, []excelize.ConditionalFormatOptions{
+		{Type: "formula", Criteria: formula, Format: &styleID},
+	})
+	if err != nil {
+		logError("Failed to highlight the alerts of sheet %s: %v", s.name, err)
+	}
+}
@@ -0,0 +1,77 @@
+package diagnostics
+
+import (
+	"database/sql/driver" // For the rows of the fake result
+	"strings"             // For checking the problems
+	"testing"             // For the test framework
+
+	"github.com/xuri/excelize/v2" // For the workbook the rows are written to
+)
+
+/*
+ * TestValidateAlertRule checks the alert rules rejected, including an alert on a column with a value map, named by
+ * the column or by its label.
+ */
+func TestValidateAlertRule(t *testing.T) {
+	valueMaps := map[string]map[string]string{"state": {"1": "ONLINE"}}
+	tests := []struct {
+		name    string
+		query   Query
+		wantErr string
+	}{
+		{name: "no alert", query: Query{}},
+		{name: "valid", query: Query{Alert: &AlertRule{Column: "wait_ms", Op: ">", Value: 100}, ValueMaps: valueMaps}},
+		{name: "alias operator", query: Query{Alert: &AlertRule{Column: "wait_ms", Op: " <> ", Value: 0}}},
+		{name: "no column", query: Query{Alert: &AlertRule{Op: ">"}}, wantErr: "alert has no column"},
+		{name: "unknown operator", query: Query{Alert: &AlertRule{Column: "wait_ms", Op: "=>"}}, wantErr: "unknown operator"},
+		{name: "value mapped column", query: Query{Alert: &AlertRule{Column: "STATE", Op: ">", Value: 1}, ValueMaps: valueMaps}, wantErr: "has a value map"},
+		{name: "value mapped column by label", query: Query{Alert: &AlertRule{Column: "Database State", Op: ">", Value: 1}, ValueMaps: valueMaps, ColumnLabels: map[string]string{"state": "Database State"}}, wantErr: "has a value map"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAlertRule(tt.query)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateAlertRule = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateAlertRule = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	problems := validateQueries(Queries{Queries: []Query{{Name: "Databases", Query: "SELECT 1", Alert: &AlertRule{Column: "state", Op: ">", Value: 1}, ValueMaps: valueMaps}}})
+	if len(problems) != 1 || !strings.Contains(problems[0].Error(), "has a value map") {
+		t.Errorf("validateQueries = %v, want the alert on the value mapped column reported", problems)
+	}
+}
+
+/*
+ * TestAlertsCountedWithValueMaps writes a result whose other column has a value map and checks every row meeting
+ * the alert rule is counted in the outcome, the mapped cells do not skip the count.
+ */
+func TestAlertsCountedWithValueMaps(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+	report := newExcelReport(f, RunOptions{})
+	query := Query{
+		Name:      "Waits",
+		Alert:     &AlertRule{Column: "wait_ms", Op: ">", Value: 100},
+		ValueMaps: map[string]map[string]string{"state": {"1": "RUNNING"}},
+	}
+	outcome := report.findings.addOutcome(query, "1_Waits")
+	rows := queryFakeRows(t, fakeResultSet{
+		columns: []string{"state", "wait_ms"},
+		types:   []string{"INT", "BIGINT"},
+		rows:    [][]driver.Value{{int64(1), int64(420)}, {int64(1), int64(12)}, {int64(2), int64(101)}},
+	})
+
+	if err := writeQueryResults(rows, query, report, "1_Waits"); err != nil {
+		t.Fatalf("writeQueryResults: %v", err)
+	}
+	if outcome.Alerts != 2 {
+		t.Errorf("counted %d alerts, want 2", outcome.Alerts)
+	}
+}
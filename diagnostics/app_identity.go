@@ -0,0 +1,70 @@
+package diagnostics
+
+import (
+	"fmt"     // For formatted I/O operations
+	"net/url" // For escaping connection string parameters
+	"strings" // For string manipulation
+
+	"github.com/magiconair/properties" // For reading the application properties
+)
+
+// Values of the APP_INTENT property, passed to the driver's `ApplicationIntent` parameter
+const (
+	appIntentReadOnly  = "ReadOnly"  // Route the connection to a readable secondary of an availability group listener
+	appIntentReadWrite = "ReadWrite" // Connect to the primary replica, the default
+)
+
+/*
+ * readAppProperties reads the application properties of a configuration file into `sqlServerConfig`.
+ *
+ * Parameters:
+ * - sqlProperties: The loaded properties file.
+ * - sqlServerConfig: The configuration being read, updated in place.
+ *
+ * Returns:
+ * - An error for an unknown `APP_INTENT` value, or ReadOnly without a database.
+ *
+ * Functionality:
+ * 1. `APP_NAME` is the program_name of the sessions in sys.dm_exec_sessions, so DBAs can identify the tool.
+ *    The driver reports "go-mssqldb" when it is not set.
+ * 2. `APP_INTENT` is ReadOnly or ReadWrite, matched case insensitively. ReadOnly routes the connection to a
+ *    readable secondary when DB_HOST is an availability group listener with read-only routing.
+ *
+ * Notes:
+ * - Both properties only apply to the connection string built from the properties, a `USER_DEFINED` or
+ *   `CONNSTR_FILE` connection string is never changed, add `app name` and `ApplicationIntent` to it directly.
+ * - The driver requires a database with ReadOnly, as the listener routes per database.
+ */
+func readAppProperties(sqlProperties *properties.Properties, sqlServerConfig *SQLServerConfig) error {
+	sqlServerConfig.AppName = strings.TrimSpace(sqlProperties.GetString("APP_NAME", ""))
+
+	intent := strings.TrimSpace(sqlProperties.GetString("APP_INTENT", ""))
+	switch {
+	case intent == "":
+	case strings.EqualFold(intent, appIntentReadOnly):
+		sqlServerConfig.AppIntent = appIntentReadOnly
+	case strings.EqualFold(intent, appIntentReadWrite):
+		sqlServerConfig.AppIntent = appIntentReadWrite
+	default:
+		return fmt.Errorf("APP_INTENT has the unknown value %q, expected %s or %s", intent, appIntentReadOnly, appIntentReadWrite)
+	}
+	if sqlServerConfig.AppIntent == appIntentReadOnly && sqlServerConfig.SQLServerDB == "" {
+		return fmt.Errorf("APP_INTENT=%s needs DB_NAME, read-only routing is configured per database", appIntentReadOnly)
+	}
+	return nil
+}
+
+/*
+ * appParameters returns the `app name` and `ApplicationIntent` parameters appended to a connection string built
+ * from the configuration, each prefixed with "&", empty when neither property is set.
+ */
+func appParameters(sqlConfig SQLServerConfig) string {
+	parameters := ""
+	if sqlConfig.AppName != "" {
+		parameters += "&app+name=" + url.QueryEscape(sqlConfig.AppName)
+	}
+	if sqlConfig.AppIntent != "" {
+		parameters += "&ApplicationIntent=" + sqlConfig.AppIntent
+	}
+	return parameters
+}
@@ -0,0 +1,100 @@
+package diagnostics
+
+import (
+	"strings" // For comparing the headers
+	"testing" // For the test framework
+)
+
+/*
+ * TestApplyColumnLabels checks the headers of a result take the labels of the mapped columns, keep the names of
+ * the others, and ignore blank labels and labels of columns the result does not have.
+ */
+func TestApplyColumnLabels(t *testing.T) {
+	columns := []string{"wait_type", "wait_time_ms", "signal_wait_time_ms"}
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   []string
+	}{
+		{name: "no labels", labels: nil, want: []string{"wait_type", "wait_time_ms", "signal_wait_time_ms"}},
+		{name: "some columns labelled", labels: map[string]string{"wait_type": "Wait Type", "wait_time_ms": "Wait Time (ms)"}, want: []string{"Wait Type", "Wait Time (ms)", "signal_wait_time_ms"}},
+		{name: "blank label", labels: map[string]string{"wait_type": "  "}, want: []string{"wait_type", "wait_time_ms", "signal_wait_time_ms"}},
+		{name: "label of a missing column", labels: map[string]string{"waiting_tasks_count": "Tasks", "signal_wait_time_ms": "Signal Wait (ms)"}, want: []string{"wait_type", "wait_time_ms", "Signal Wait (ms)"}},
+		{name: "case sensitive column names", labels: map[string]string{"Wait_Type": "Wait Type"}, want: []string{"wait_type", "wait_time_ms", "signal_wait_time_ms"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := applyColumnLabels(columns, Query{Name: "Wait Stats", ColumnLabels: tt.labels})
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("applyColumnLabels = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if columns[0] != "wait_type" {
+		t.Errorf("applyColumnLabels changed the column names to %q", columns)
+	}
+}
+
+/*
+ * TestMapCellValue checks the scanned values are matched on their cleaned text, whatever their Go type, with
+ * NULL matched by the "NULL" key and unmapped values left alone.
+ */
+func TestMapCellValue(t *testing.T) {
+	valueMap := map[string]string{"1": "ONLINE", "6": "OFFLINE", "true": "Yes", "NULL": "(none)", "SIMPLE": "Simple recovery"}
+	tests := []struct {
+		name        string
+		valueMap    map[string]string
+		value       interface{}
+		wantDisplay string
+		wantOK      bool
+	}{
+		{name: "integer", valueMap: valueMap, value: int64(1), wantDisplay: "ONLINE", wantOK: true},
+		{name: "decimal scanned as text", valueMap: valueMap, value: []byte("6"), wantDisplay: "OFFLINE", wantOK: true},
+		{name: "string", valueMap: valueMap, value: "SIMPLE", wantDisplay: "Simple recovery", wantOK: true},
+		{name: "bit", valueMap: valueMap, value: true, wantDisplay: "Yes", wantOK: true},
+		{name: "NULL", valueMap: valueMap, value: nil, wantDisplay: "(none)", wantOK: true},
+		{name: "unmapped value", valueMap: valueMap, value: int64(2)},
+		{name: "decimal with a scale", valueMap: valueMap, value: []byte("1.00")},
+		{name: "case sensitive text", valueMap: valueMap, value: "simple"},
+		{name: "no value map", valueMap: nil, value: int64(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			display, ok := mapCellValue(tt.valueMap, tt.value)
+			if display != tt.wantDisplay || ok != tt.wantOK {
+				t.Errorf("mapCellValue(%v) = %q, %v, want %q, %v", tt.value, display, ok, tt.wantDisplay, tt.wantOK)
+			}
+		})
+	}
+}
+
+/*
+ * TestCollapseLineBreaks checks every line break is replaced with one space together with the indentation around
+ * it, the leading and trailing line breaks are dropped, and the whitespace within a line is kept.
+ */
+func TestCollapseLineBreaks(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "single line", text: "SELECT  a FROM t", want: "SELECT  a FROM t"},
+		{name: "indented SQL", text: "SELECT a,\n       b\n  FROM t\n WHERE a = 1", want: "SELECT a, b FROM t WHERE a = 1"},
+		{name: "Windows line breaks", text: "line 1\r\nline 2\r\n", want: "line 1 line 2"},
+		{name: "blank lines", text: "a\n\n\n\tb", want: "a b"},
+		{name: "trailing spaces before a break", text: "a   \n b", want: "a b"},
+		{name: "leading and trailing breaks", text: "\n  <plan>\n    <node/>\n  </plan>\n", want: "<plan> <node/> </plan>"},
+		{name: "only line breaks", text: "\r\n\n", want: ""},
+		{name: "tab within a line", text: "a\tb\nc", want: "a\tb c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := collapseLineBreaks(tt.text); got != tt.want {
+				t.Errorf("collapseLineBreaks(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+	if got := cleanCellValue([]byte("SELECT 1\n  FROM t")); got != "SELECT 1 FROM t" {
+		t.Errorf("cleanCellValue of a multi line text = %q, want it on one line", got)
+	}
+}
@@ -0,0 +1,116 @@
+package diagnostics
+
+import (
+	"context"             // For cancelling the plan capture of an interrupted run
+	"database/sql"        // For the dedicated connection of the plan capture
+	"database/sql/driver" // For discarding a connection left in SHOWPLAN mode
+	"fmt"                 // For formatted I/O operations
+	"os"                  // For creating the plans folder and files
+	"path/filepath"       // For building the file paths
+)
+
+// Suffix of the folder next to the output holding the plans captured for the queries with `capturePlan`
+const capturedPlansFolderSuffix = "_plans"
+
+/*
+ * capturedPlan is the estimated execution plan of a query with `capturePlan`.
+ *
+ * Fields:
+ * - Sheet: The result sheet of the query, naming its .sqlplan files.
+ * - Statement: The 1 based number of the plan among those SQL Server returned for the batch.
+ * - XML: The showplan XML, as SSMS opens it from a .sqlplan file.
+ */
+type capturedPlan struct {
+	Sheet     string
+	Statement int
+	XML       string
+}
+
+/*
+ * captureEstimatedPlan returns the estimated plans of a query, compiled under SET SHOWPLAN_XML ON without running it.
+ *
+ * Parameters:
+ * - ctx: Cancels the compilation.
+ * - db: The connection pool, a dedicated connection is taken from it for the capture.
+ * - opts: The run options, the query's parameters are bound from `Params`.
+ * - query: The query whose plans are captured.
+ * - sheetName: The result sheet of the query.
+ *
+ * Returns:
+ * - The plans, one per plan row SQL Server returned.
+ * - An error when SHOWPLAN_XML cannot be set or the query cannot be compiled, such as a statement referencing a
+ *   temporary table the batch creates, which does not exist as the batch is not executed.
+ *
+ * Notes:
+ * - SET SHOWPLAN_XML must be the only statement of its batch, so it is sent alone on the dedicated connection and
+ *   the query follows as its own batch. The query must not contain SET SHOWPLAN_XML itself.
+ * - The connection is returned to the pool only once SHOWPLAN_XML is OFF again, otherwise it is discarded so no
+ *   later query silently gets plans instead of rows.
+ */
+func captureEstimatedPlan(ctx context.Context, db *sql.DB, opts RunOptions, query Query, sheetName string) ([]capturedPlan, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open a connection for the plan: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SET SHOWPLAN_XML ON"); err != nil {
+		return nil, fmt.Errorf("failed to set SHOWPLAN_XML ON: %v", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "SET SHOWPLAN_XML OFF"); err != nil {
+			logWarn("Failed to set SHOWPLAN_XML OFF after the plan of %s, discarding its connection: %v", sheetName, err)
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+	}()
+
+	rows, err := conn.QueryContext(ctx, query.Query, queryArgs(query, opts.Params)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile the query: %v", err)
+	}
+	defer rows.Close()
+
+	var plans []capturedPlan
+	for {
+		for rows.Next() {
+			var plan string
+			if err := rows.Scan(&plan); err != nil {
+				return nil, fmt.Errorf("failed to read the plan: %v", err)
+			}
+			plans = append(plans, capturedPlan{Sheet: sheetName, Statement: len(plans) + 1, XML: plan})
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to compile the query: %v", err)
+	}
+	return plans, nil
+}
+
+/*
+ * writeCapturedPlans writes every captured plan to "<Sr.No>_<Name>.sqlplan" in a "<baseName>_plans" folder next
+ * to the output, further plans of a batch suffixed with their number, such as "3_WaitStats_2.sqlplan".
+ *
+ * Returns:
+ * - The folder written, or an error if the folder or a file cannot be created.
+ */
+func writeCapturedPlans(plans []capturedPlan, baseName string) (string, error) {
+	folder := baseName + capturedPlansFolderSuffix
+	if err := os.MkdirAll(folder, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %v", folder, err)
+	}
+
+	for _, plan := range plans {
+		name := plan.Sheet
+		if plan.Statement > 1 {
+			name = fmt.Sprintf("%s_%d", plan.Sheet, plan.Statement)
+		}
+		fileName := filepath.Join(folder, name+".sqlplan")
+		if err := os.WriteFile(fileName, []byte(plan.XML), 0o644); err != nil {
+			return "", fmt.Errorf("failed to write %s: %v", fileName, err)
+		}
+	}
+	return folder, nil
+}
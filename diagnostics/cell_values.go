@@ -0,0 +1,185 @@
+package diagnostics
+
+import (
+	"database/sql" // Database/sql package for column type information
+	"strconv"      // For parsing decimal and money values
+	"strings"      // For string manipulation
+	"time"         // For recognizing date and time values
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+// Most significant digits an Excel number keeps, longer decimals are written as text so no digit is lost
+const maxExcelDigits = 15
+
+// Number formats of the date and time cells, keyed by the SQL Server type, dateTimeNumberFormat for the others
+var dateNumberFormats = map[string]string{
+	"DATE": "yyyy-mm-dd",
+}
+
+// Number format of the date and time cells whose type has no entry in dateNumberFormats
+const dateTimeNumberFormat = "yyyy-mm-dd hh:mm:ss"
+
+/*
+ * typedCellValue returns the value written to a result cell, keeping the native type of the scanned value so
+ * Excel sorts, filters and sums the column as numbers, dates or booleans instead of text.
+ *
+ * Parameters:
+ * - v: The value scanned into an interface{} for the cell.
+ * - columnType: The driver column type of the cell's column, nil when unknown.
+ *
+ * Returns:
+ * - The value to write: a number for the integer and floating point values and the DECIMAL, NUMERIC and MONEY
+ *   values, a time.Time for the date and time values, a bool for BIT values, or the text of cleanCellValue.
+ * - The number format the cell needs to display the value, empty for every value but the dates and the DECIMAL,
+ *   NUMERIC and MONEY values, see `decimalNumberFormat`.
+ *
+ * Notes:
+ * - NULL stays the "NULL" text, an empty cell would read as a missing value.
+ * - DECIMAL and NUMERIC values with more than 15 significant digits stay text, Excel would round them.
+ * - TIME values are scanned as a time on January 1 of year 1, before the first date Excel knows, and stay text.
+ */
+func typedCellValue(v interface{}, columnType *sql.ColumnType) (interface{}, string) {
+	typeName := ""
+	if columnType != nil {
+		typeName = strings.ToUpper(columnType.DatabaseTypeName())
+	}
+
+	switch value := v.(type) {
+	case int64, int32, int, float64, float32, bool:
+		return value, ""
+	case time.Time:
+		if typeName == "TIME" {
+			break
+		}
+		if numberFormat, ok := dateNumberFormats[typeName]; ok {
+			return value, numberFormat
+		}
+		return value, dateTimeNumberFormat
+	case []byte:
+		if isDecimalType(typeName) && significantDigits(string(value)) <= maxExcelDigits {
+			if n, err := strconv.ParseFloat(string(value), 64); err == nil {
+				return n, decimalNumberFormat(string(value), columnType)
+			}
+		}
+	}
+	return cleanCellValue(v), ""
+}
+
+/*
+ * decimalNumberFormat returns the number format showing a DECIMAL, NUMERIC or MONEY value with every digit of its
+ * scale, such as "0.0000" for DECIMAL(10,4), so 12345.6700 keeps its trailing zeros and is never shown in
+ * scientific notation by the General format.
+ *
+ * Notes:
+ * - The scale is the column's when the driver reports it, the number of digits after the point of the value
+ *   otherwise. A scale of 0 gives "0".
+ */
+func decimalNumberFormat(text string, columnType *sql.ColumnType) string {
+	scale := -1
+	if columnType != nil {
+		if _, columnScale, ok := columnType.DecimalSize(); ok {
+			scale = int(columnScale)
+		}
+	}
+	if scale == -1 {
+		scale = 0
+		if point := strings.IndexByte(text, '.'); point != -1 {
+			scale = len(text) - point - 1
+		}
+	}
+	if scale <= 0 {
+		return "0"
+	}
+	return "0." + strings.Repeat("0", scale)
+}
+
+/*
+ * significantDigits counts the digits of a decimal text without its leading zeros.
+ */
+func significantDigits(text string) int {
+	digits := strings.TrimLeft(strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, text), "0")
+	return len(digits)
+}
+
+/*
+ * multilineText returns the text of a scanned text value holding line breaks, with every CR LF and lone CR
+ * turned into a LF as Excel expects, and false for the other values.
+ */
+func multilineText(v interface{}) (string, bool) {
+	var text string
+	switch value := v.(type) {
+	case []byte:
+		text = string(value)
+	case string:
+		text = value
+	default:
+		return "", false
+	}
+	if !strings.ContainsAny(text, "\r\n") {
+		return "", false
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n"), true
+}
+
+/*
+ * setTypedCellValue writes a scanned value to a result cell with typedCellValue, styling the date and decimal cells
+ * with their number format. The styles are created once per Excel file and cached by the report.
+ * With `-preserve-newlines` a multi line text keeps its line breaks in a wrapped, top aligned cell, so a query
+ * plan or SQL text stays readable.
+ * It returns the value written.
+ */
+func (s *resultSheet) setTypedCellValue(cell string, v interface{}, columnType *sql.ColumnType) interface{} {
+	if s.opts.PreserveNewlines {
+		if text, ok := multilineText(v); ok {
+			if styleID, err := s.report.cellStyle("wrap_text", &excelize.Style{Alignment: &excelize.Alignment{WrapText: true, Vertical: "top"}}); err == nil {
+				s.f.SetCellStyle(s.name, cell, cell, styleID)
+			}
+			s.f.SetCellValue(s.name, cell, text)
+			return text
+		}
+	}
+
+	// The style is set after the value, SetCellValue gives a time.Time cell a new style of its own, which would
+	// otherwise replace the cached one on every date cell
+	value, numberFormat := typedCellValue(v, columnType)
+	s.f.SetCellValue(s.name, cell, value)
+	s.setNumberFormat(cell, numberFormat)
+	return value
+}
+
+/*
+ * setNumericCellValue writes a scanned value as a number to a cell of a column Excel must compare or format as
+ * numbers, the format hinted, `highlightTop` and `alert` columns, even when the value is numeric text or a decimal
+ * beyond the 15 digits setTypedCellValue keeps as text. A DECIMAL, NUMERIC or MONEY value gets the number format
+ * of its scale as in setTypedCellValue, which a format hint replaces once the sheet is finished.
+ * It returns the value written, or false when the value is not a number and nothing was written.
+ */
+func (s *resultSheet) setNumericCellValue(cell string, v interface{}, columnType *sql.ColumnType) (interface{}, bool) {
+	n, ok := numericValue(v)
+	if !ok {
+		return nil, false
+	}
+	if text, isText := v.([]byte); isText && columnType != nil && isDecimalType(columnType.DatabaseTypeName()) {
+		s.setNumberFormat(cell, decimalNumberFormat(string(text), columnType))
+	}
+	s.f.SetCellValue(s.name, cell, n)
+	return n, true
+}
+
+/*
+ * setNumberFormat styles a cell with a number format, nothing when the format is empty.
+ */
+func (s *resultSheet) setNumberFormat(cell string, numberFormat string) {
+	if numberFormat == "" {
+		return
+	}
+	if styleID, err := s.report.cellStyle("number_format:"+numberFormat, &excelize.Style{CustomNumFmt: &numberFormat}); err == nil {
+		s.f.SetCellStyle(s.name, cell, cell, styleID)
+	}
+}
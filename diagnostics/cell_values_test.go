@@ -0,0 +1,238 @@
+package diagnostics
+
+import (
+	"testing" // For the test framework
+	"time"    // For the date and time values
+
+	"github.com/xuri/excelize/v2" // For reading back the written cells
+)
+
+/*
+ * TestDecimalNumberFormat checks the number format shows every digit of the scale read from the value, as the
+ * driver returns the text of a DECIMAL(10,4) with its 4 digits, and "0" for a scale of 0.
+ */
+func TestDecimalNumberFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "DECIMAL(10,4)", text: "12345.6700", want: "0.0000"},
+		{name: "DECIMAL(10,4) below one", text: "0.0001", want: "0.0000"},
+		{name: "negative MONEY", text: "-42.1500", want: "0.0000"},
+		{name: "scale 0", text: "1234567890", want: "0"},
+		{name: "scale 0 negative", text: "-7", want: "0"},
+		{name: "DECIMAL(38,10)", text: "1.0000000000", want: "0.0000000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decimalNumberFormat(tt.text, nil); got != tt.want {
+				t.Errorf("decimalNumberFormat(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+/*
+ * TestSignificantDigits checks the digits counted against the 15 Excel keeps, without the sign, the point and the
+ * leading zeros, so a DECIMAL beyond 15 digits stays text.
+ */
+func TestSignificantDigits(t *testing.T) {
+	tests := []struct {
+		text      string
+		want      int
+		fitsExcel bool
+	}{
+		{text: "12345.6700", want: 9, fitsExcel: true},
+		{text: "0.0001", want: 1, fitsExcel: true},
+		{text: "-000123", want: 3, fitsExcel: true},
+		{text: "0", want: 0, fitsExcel: true},
+		{text: "123456789012345", want: 15, fitsExcel: true},
+		{text: "1234567890123456", want: 16},
+		{text: "12345678901234567890.1234", want: 24},
+		{text: "0.1234567890123456", want: 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got := significantDigits(tt.text)
+			if got != tt.want {
+				t.Errorf("significantDigits(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+			if fits := got <= maxExcelDigits; fits != tt.fitsExcel {
+				t.Errorf("significantDigits(%q) within the %d digits of Excel = %v, want %v", tt.text, maxExcelDigits, fits, tt.fitsExcel)
+			}
+		})
+	}
+}
+
+/*
+ * TestSetNumericCellValue checks the format hinted, ranked and alert columns get numbers for the integers,
+ * floats and numeric text, and nothing for the other values, which setTypedCellValue writes instead.
+ */
+func TestSetNumericCellValue(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+	s := &resultSheet{report: newExcelReport(f, RunOptions{}), f: f, name: "Sheet1"}
+
+	tests := []struct {
+		name   string
+		value  interface{}
+		want   float64
+		wantOK bool
+	}{
+		{name: "integer", value: int64(420), want: 420, wantOK: true},
+		{name: "float", value: 12.5, want: 12.5, wantOK: true},
+		{name: "numeric text", value: []byte("12345.6700"), want: 12345.67, wantOK: true},
+		{name: "text", value: []byte("CXPACKET"), wantOK: false},
+		{name: "NULL", value: nil, wantOK: false},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cell, _ := excelize.CoordinatesToCellName(1, i+1)
+			written, ok := s.setNumericCellValue(cell, tt.value, nil)
+			if ok != tt.wantOK {
+				t.Fatalf("setNumericCellValue(%v) ok = %v, want %v", tt.value, ok, tt.wantOK)
+			}
+			if !ok {
+				if value, _ := f.GetCellValue("Sheet1", cell); value != "" {
+					t.Errorf("setNumericCellValue(%v) wrote %q, want nothing", tt.value, value)
+				}
+				return
+			}
+			if written != tt.want {
+				t.Errorf("setNumericCellValue(%v) wrote %v, want %v", tt.value, written, tt.want)
+			}
+			if cellType, _ := f.GetCellType("Sheet1", cell); cellType == excelize.CellTypeInlineString || cellType == excelize.CellTypeSharedString {
+				t.Errorf("setNumericCellValue(%v) wrote text", tt.value)
+			}
+		})
+	}
+}
+
+/*
+ * TestDecimalCellDisplay writes DECIMAL values through the column types the driver reports, with setTypedCellValue
+ * for a plain column and setNumericCellValue for a hinted, ranked or alert column, and checks the text Excel shows
+ * keeps every digit of the scale, 12345.6700 and not 12345.67 or 1.23456E4.
+ */
+func TestDecimalCellDisplay(t *testing.T) {
+	rows := queryFakeRows(t, fakeResultSet{
+		columns: []string{"avg_wait", "total_bytes", "long_value"},
+		types:   []string{"DECIMAL", "DECIMAL", "DECIMAL"},
+		scales:  map[int][2]int64{0: {10, 4}, 1: {18, 0}, 2: {38, 4}},
+	})
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("ColumnTypes: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		column  int
+		value   []byte
+		numeric bool
+		want    string
+	}{
+		{name: "DECIMAL(10,4)", column: 0, value: []byte("12345.6700"), want: "12345.6700"},
+		{name: "DECIMAL(10,4) hinted, ranked or alert column", column: 0, value: []byte("12345.6700"), numeric: true, want: "12345.6700"},
+		{name: "DECIMAL(10,4) below one", column: 0, value: []byte("0.0500"), want: "0.0500"},
+		{name: "DECIMAL(18,0)", column: 1, value: []byte("123456789012345"), want: "123456789012345"},
+		{name: "DECIMAL(18,0) hinted, ranked or alert column", column: 1, value: []byte("987654321"), numeric: true, want: "987654321"},
+		{name: "more than 15 digits stays text", column: 2, value: []byte("12345678901234567890.1234"), want: "12345678901234567890.1234"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := excelize.NewFile()
+			defer f.Close()
+			s := &resultSheet{report: newExcelReport(f, RunOptions{}), f: f, name: "Sheet1"}
+			cell, _ := excelize.CoordinatesToCellName(1, i+1)
+			if tt.numeric {
+				if _, ok := s.setNumericCellValue(cell, tt.value, columnTypes[tt.column]); !ok {
+					t.Fatalf("setNumericCellValue(%s) wrote nothing", tt.value)
+				}
+			} else {
+				s.setTypedCellValue(cell, tt.value, columnTypes[tt.column])
+			}
+			if got, err := f.GetCellValue("Sheet1", cell); err != nil || got != tt.want {
+				t.Errorf("cell shows %q (%v), want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+/*
+ * TestSetTypedCellValue writes the values go-mssqldb scans for an integer, a decimal, a datetime, a date, a bit and a
+ * text column, and checks each cell gets the native type and value, and the date cells the date number format,
+ * registered once per file however many cells use it.
+ */
+func TestSetTypedCellValue(t *testing.T) {
+	rows := queryFakeRows(t, fakeResultSet{
+		columns: []string{"session_id", "cpu_pct", "login_time", "login_date", "is_user_process", "status"},
+		types:   []string{"INT", "DECIMAL", "DATETIME", "DATE", "BIT", "NVARCHAR"},
+		scales:  map[int][2]int64{1: {10, 2}},
+	})
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("ColumnTypes: %v", err)
+	}
+	loginTime := time.Date(2025, 11, 27, 10, 30, 0, 0, time.UTC)
+
+	f := excelize.NewFile()
+	defer f.Close()
+	report := newExcelReport(f, RunOptions{})
+	s := &resultSheet{report: report, f: f, name: "Sheet1"}
+
+	tests := []struct {
+		name         string
+		column       int
+		value        interface{}
+		wantType     excelize.CellType
+		wantRaw      string
+		wantShown    string
+		numberFormat string
+	}{
+		// Numbers are written without a type attribute, which Excel reads as a number
+		{name: "integer", column: 0, value: int64(420), wantType: excelize.CellTypeUnset, wantRaw: "420", wantShown: "420"},
+		{name: "decimal", column: 1, value: []byte("12.50"), wantType: excelize.CellTypeUnset, wantRaw: "12.5", wantShown: "12.50", numberFormat: "0.00"},
+		{name: "datetime", column: 2, value: loginTime, wantType: excelize.CellTypeUnset, wantRaw: "45988.4375", wantShown: "2025-11-27 10:30:00", numberFormat: dateTimeNumberFormat},
+		{name: "second datetime", column: 2, value: loginTime.Add(time.Hour), wantType: excelize.CellTypeUnset, wantRaw: "45988.479166666664", wantShown: "2025-11-27 11:30:00", numberFormat: dateTimeNumberFormat},
+		{name: "date", column: 3, value: loginTime.Truncate(24 * time.Hour), wantType: excelize.CellTypeUnset, wantRaw: "45988", wantShown: "2025-11-27", numberFormat: "yyyy-mm-dd"},
+		{name: "bit", column: 4, value: true, wantType: excelize.CellTypeBool, wantRaw: "1", wantShown: "TRUE"},
+		{name: "text", column: 5, value: "running", wantType: excelize.CellTypeSharedString, wantRaw: "running", wantShown: "running"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cell, _ := excelize.CoordinatesToCellName(1, i+1)
+			s.setTypedCellValue(cell, tt.value, columnTypes[tt.column])
+
+			if cellType, _ := f.GetCellType("Sheet1", cell); cellType != tt.wantType {
+				t.Errorf("cell type = %v, want %v", cellType, tt.wantType)
+			}
+			if raw, _ := f.GetCellValue("Sheet1", cell, excelize.Options{RawCellValue: true}); raw != tt.wantRaw {
+				t.Errorf("raw value = %q, want %q", raw, tt.wantRaw)
+			}
+			if shown, _ := f.GetCellValue("Sheet1", cell); shown != tt.wantShown {
+				t.Errorf("shown value = %q, want %q", shown, tt.wantShown)
+			}
+			styleID, _ := f.GetCellStyle("Sheet1", cell)
+			if tt.numberFormat == "" {
+				if styleID != 0 {
+					t.Errorf("cell has style %d, want none", styleID)
+				}
+				return
+			}
+			if want, ok := report.styles["cell:number_format:"+tt.numberFormat]; !ok || styleID != want {
+				t.Errorf("cell has style %d, want the %q style registered for the file (%d)", styleID, tt.numberFormat, want)
+			}
+		})
+	}
+
+	// One style per number format, the two datetime cells share theirs, and a further date cell adds no style
+	if len(report.styles) != 3 {
+		t.Errorf("registered %d styles %v, want one per number format", len(report.styles), report.styles)
+	}
+	styles := f.Styles.CellXfs.Count
+	s.setTypedCellValue("B1", loginTime.Add(2*time.Hour), columnTypes[2])
+	if f.Styles.CellXfs.Count != styles {
+		t.Errorf("a further datetime cell grew the styles of the file from %d to %d", styles, f.Styles.CellXfs.Count)
+	}
+}
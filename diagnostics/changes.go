@@ -0,0 +1,263 @@
+package diagnostics
+
+import (
+	"fmt"     // For formatted I/O operations
+	"os"      // For checking for an existing changes workbook
+	"strings" // For string manipulation
+	"time"    // For working with date and time
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+// Name of the sheet holding the change log of a scheduled run
+const changesSheetName = "changes"
+
+/*
+ * resultSnapshot holds the rows of the `-changes-query` result of one iteration, as the cell text
+ * written to the report, so consecutive iterations can be compared.
+ */
+type resultSnapshot struct {
+	Columns []string   // Column names of the result
+	Rows    [][]string // Cell text of every row
+}
+
+/*
+ * startSnapshot returns a new snapshot to fill when the query is the one named by `snapshotQuery`,
+ * recording it in the findings. It returns nil for any other query or once a snapshot was started.
+ */
+func (findings *ReportFindings) startSnapshot(snapshotQuery string, query Query) *resultSnapshot {
+	if snapshotQuery == "" || findings.Snapshot != nil || !strings.EqualFold(query.Name, snapshotQuery) {
+		return nil
+	}
+	findings.Snapshot = &resultSnapshot{}
+	return findings.Snapshot
+}
+
+/*
+ * add appends the text of a scanned row, values holding *interface{} as passed to rows.Scan.
+ */
+func (s *resultSnapshot) add(values []interface{}) {
+	row := make([]string, len(values))
+	for i, val := range values {
+		row[i] = cleanCellValue(*(val.(*interface{})))
+	}
+	s.Rows = append(s.Rows, row)
+}
+
+/*
+ * snapshotDelta is the difference between two snapshots of the same query.
+ */
+type snapshotDelta struct {
+	Added   [][]string    // Rows whose key is only in the current snapshot
+	Removed [][]string    // Rows whose key is only in the previous snapshot
+	Changed [][2][]string // Previous and current row for keys whose row differs
+}
+
+/*
+ * keyColumnIndexes resolves the key column names against the snapshot columns, case insensitively.
+ * Without key columns the whole row is the key, so rows can only be added or removed.
+ */
+func keyColumnIndexes(columns []string, keyColumns []string) ([]int, error) {
+	var indexes []int
+	for _, key := range keyColumns {
+		found := -1
+		for i, column := range columns {
+			if strings.EqualFold(column, key) {
+				found = i
+				break
+			}
+		}
+		if found == -1 {
+			return nil, fmt.Errorf("key column %q is not in the result columns %s", key, strings.Join(columns, ", "))
+		}
+		indexes = append(indexes, found)
+	}
+	if len(indexes) == 0 {
+		for i := range columns {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, nil
+}
+
+/*
+ * compareSnapshots computes the rows added, removed and changed between the previous and current snapshot.
+ *
+ * Parameters:
+ * - previous: The snapshot of the previous iteration.
+ * - current: The snapshot of this iteration.
+ * - keyColumns: The columns identifying a row, the whole row when empty.
+ *
+ * Returns:
+ * - The delta, in the row order of the snapshots, or an error if a key column is missing.
+ *
+ * Notes:
+ * - Rows sharing a key are compared in order, extra occurrences count as added or removed.
+ */
+func compareSnapshots(previous *resultSnapshot, current *resultSnapshot, keyColumns []string) (snapshotDelta, error) {
+	var delta snapshotDelta
+	keys, err := keyColumnIndexes(current.Columns, keyColumns)
+	if err != nil {
+		return delta, err
+	}
+	rowKey := func(row []string) string {
+		parts := make([]string, len(keys))
+		for i, index := range keys {
+			if index < len(row) {
+				parts[i] = row[index]
+			}
+		}
+		return strings.Join(parts, "\x00")
+	}
+
+	previousRows := make(map[string][][]string)
+	for _, row := range previous.Rows {
+		key := rowKey(row)
+		previousRows[key] = append(previousRows[key], row)
+	}
+
+	for _, row := range current.Rows {
+		key := rowKey(row)
+		matches := previousRows[key]
+		if len(matches) == 0 {
+			delta.Added = append(delta.Added, row)
+			continue
+		}
+		if strings.Join(matches[0], "\x00") != strings.Join(row, "\x00") {
+			delta.Changed = append(delta.Changed, [2][]string{matches[0], row})
+		}
+		previousRows[key] = matches[1:]
+	}
+
+	for _, row := range previous.Rows {
+		key := rowKey(row)
+		if len(previousRows[key]) > 0 && strings.Join(previousRows[key][0], "\x00") == strings.Join(row, "\x00") {
+			delta.Removed = append(delta.Removed, row)
+			previousRows[key] = previousRows[key][1:]
+		}
+	}
+	return delta, nil
+}
+
+/*
+ * changeLog is the "changes" workbook of a scheduled run, kept open across iterations together
+ * with the snapshot of the previous iteration.
+ */
+type changeLog struct {
+	f          *excelize.File
+	fileName   string
+	query      string
+	keyColumns []string
+	previous   *resultSnapshot
+	nextRow    int
+}
+
+/*
+ * newChangeLog opens the changes workbook of a run, appending to it when it exists from an interrupted run.
+ *
+ * Parameters:
+ * - runID: The run ID, naming the workbook "sql_diagnostics_run_<id>_changes.xlsx".
+ * - query: The name of the query whose results are compared.
+ * - keyColumns: The columns identifying a row of the query.
+ *
+ * Returns:
+ * - The change log, or an error when the workbook of the interrupted run exists but cannot be opened.
+ */
+func newChangeLog(runID string, query string, keyColumns []string) (*changeLog, error) {
+	c := &changeLog{
+		fileName:   fmt.Sprintf("sql_diagnostics_run_%s_changes.xlsx", runID),
+		query:      query,
+		keyColumns: keyColumns,
+		nextRow:    1,
+	}
+
+	if _, err := os.Stat(c.fileName); err == nil {
+		f, err := excelize.OpenFile(c.fileName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the changes workbook %s: %v", c.fileName, err)
+		}
+		rows, _ := f.GetRows(changesSheetName)
+		c.f = f
+		c.nextRow = len(rows) + 2
+		return c, nil
+	}
+
+	c.f = excelize.NewFile()
+	c.f.SetSheetName("Sheet1", changesSheetName)
+	return c, nil
+}
+
+/*
+ * record appends the delta block of an iteration to the "changes" sheet and saves the workbook.
+ *
+ * Parameters:
+ * - iteration: The 1 based iteration number.
+ * - at: When the iteration started.
+ * - current: The snapshot of the iteration, nil when the query did not run or failed.
+ *
+ * Functionality:
+ * 1. Writes a title row with the iteration, its time and the counts of new, removed and changed rows.
+ * 2. Writes a header row and one row per change, "new" and "removed" rows hold the row itself while
+ *    "changed" rows are written twice, as "changed (was)" and "changed (now)".
+ * 3. The first iteration, or the first after resuming, only records the baseline row count.
+ * 4. Leaves a blank row after the block and saves the workbook.
+ */
+func (c *changeLog) record(iteration int, at time.Time, current *resultSnapshot) {
+	// A snapshot without columns belongs to a query that failed before returning a result
+	if current != nil && current.Columns == nil {
+		current = nil
+	}
+
+	title := fmt.Sprintf("Iteration %d at %s", iteration, at.Format(time.RFC3339))
+	writeRow := func(values ...interface{}) {
+		for colIndex, value := range values {
+			cell, _ := excelize.CoordinatesToCellName(colIndex+1, c.nextRow)
+			c.f.SetCellValue(changesSheetName, cell, value)
+		}
+		c.nextRow++
+	}
+	cells := func(change string, row []string) []interface{} {
+		values := []interface{}{change}
+		for _, value := range row {
+			values = append(values, value)
+		}
+		return values
+	}
+
+	switch {
+	case current == nil:
+		writeRow(title, fmt.Sprintf("query %s returned no result, nothing compared", c.query))
+	case c.previous == nil:
+		writeRow(title, fmt.Sprintf("baseline of %d row(s), nothing to compare", len(current.Rows)))
+	default:
+		delta, err := compareSnapshots(c.previous, current, c.keyColumns)
+		if err != nil {
+			logError("Failed to compare the %s results: %v", c.query, err)
+			writeRow(title, fmt.Sprintf("comparison failed: %v", err))
+			break
+		}
+		writeRow(title, fmt.Sprintf("%d new, %d removed, %d changed", len(delta.Added), len(delta.Removed), len(delta.Changed)))
+		if len(delta.Added)+len(delta.Removed)+len(delta.Changed) > 0 {
+			writeRow(cells("change", current.Columns)...)
+			for _, row := range delta.Added {
+				writeRow(cells("new", row)...)
+			}
+			for _, row := range delta.Removed {
+				writeRow(cells("removed", row)...)
+			}
+			for _, rows := range delta.Changed {
+				writeRow(cells("changed (was)", rows[0])...)
+				writeRow(cells("changed (now)", rows[1])...)
+			}
+		}
+	}
+	c.nextRow++
+
+	if current != nil {
+		c.previous = current
+	}
+
+	if err := saveWorkbook(c.f, c.fileName); err != nil {
+		logError("Failed to save the changes workbook %s: %v", c.fileName, err)
+	}
+}
@@ -0,0 +1,75 @@
+package diagnostics
+
+import (
+	"fmt"          // For formatted I/O operations
+	"strings"      // For splitting multi line values
+	"time"         // For measuring date values
+	"unicode/utf8" // For counting the characters of a value
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+// Default of `-max-col-width`, in Excel character units
+const defaultMaxColWidth = 80
+
+// Widest column Excel accepts, in character units
+const maxExcelColWidth = 255
+
+// Narrowest fitted column, so short codes and flags keep a clickable width
+const minFittedColWidth = 6
+
+// Characters added to the widest value, so the text does not touch the cell border or the filter button
+const colWidthPadding = 2
+
+/*
+ * measure records the character count of a value written to a sheet column, used to fit the column width.
+ *
+ * Parameters:
+ * - column: The 1 based sheet column.
+ * - value: The value written to the cell, the text of a number or date is measured as Excel displays it roughly.
+ */
+func (s *resultSheet) measure(column int, value interface{}) {
+	if s.opts.MaxColWidth <= 0 {
+		return
+	}
+	for len(s.widths) < column {
+		s.widths = append(s.widths, 0)
+	}
+
+	length := 0
+	switch v := value.(type) {
+	case string:
+		// A multi line text kept by -preserve-newlines is as wide as its longest line
+		for _, line := range strings.Split(v, "\n") {
+			length = max(length, utf8.RuneCountInString(line))
+		}
+	case time.Time:
+		length = len(dateTimeNumberFormat)
+	default:
+		length = len(fmt.Sprintf("%v", v))
+	}
+	s.widths[column-1] = max(s.widths[column-1], length)
+}
+
+/*
+ * fitColumnWidths sets the width of every sheet column from the widest value written to it, for `-max-col-width`.
+ *
+ * Notes:
+ * - excelize cannot measure rendered text, the width approximates one Excel character unit per character, which
+ *   fits the default Calibri 11 font for most text.
+ * - Columns are capped at `MaxColWidth`, a query plan or SQL text column stays readable instead of thousands
+ *   of units wide, its cells keep their full text.
+ */
+func (s *resultSheet) fitColumnWidths() {
+	if s.opts.MaxColWidth <= 0 {
+		return
+	}
+	limit := min(s.opts.MaxColWidth, maxExcelColWidth)
+	for i, length := range s.widths {
+		width := min(max(length+colWidthPadding, minFittedColWidth), limit)
+		column, _ := excelize.ColumnNumberToName(i + 1)
+		if err := s.f.SetColWidth(s.name, column, column, float64(width)); err != nil {
+			logError("Failed to set the width of column %s of sheet %s: %v", column, s.name, err)
+		}
+	}
+}
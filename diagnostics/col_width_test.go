@@ -0,0 +1,47 @@
+package diagnostics
+
+import (
+	"database/sql/driver" // For the values of the fake result
+	"strings"             // For building the long value
+	"testing"             // For the test framework
+
+	"github.com/xuri/excelize/v2" // For reading back the column widths
+)
+
+/*
+ * TestFitColumnWidths writes a row holding a 5000 character query plan next to a short id, and checks the plan
+ * column is capped at -max-col-width while the id column stays narrow, the cell keeping the full text.
+ */
+func TestFitColumnWidths(t *testing.T) {
+	plan := strings.Repeat("x", 5000)
+	rows := queryFakeRows(t, fakeResultSet{
+		columns: []string{"id", "query_plan"},
+		types:   []string{"INT", "NVARCHAR"},
+		rows:    [][]driver.Value{{int64(7), plan}},
+	})
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("ColumnTypes: %v", err)
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	report := newExcelReport(f, RunOptions{MaxColWidth: 80})
+	s := newResultSheet(report, "1_Plans", Query{Name: "Plans"}, []string{"id", "query_plan"}, columnTypes, false)
+	if err := s.writeRows(rows, 1); err != nil {
+		t.Fatalf("writeRows: %v", err)
+	}
+	s.finish()
+
+	// The plan column is fitted to the cap, not left at the default width, the id column to its header
+	if width, err := f.GetColWidth("1_Plans", "B"); err != nil || width != 80 {
+		t.Errorf("query_plan column is %v wide (%v), want the cap of 80", width, err)
+	}
+	if width, err := f.GetColWidth("1_Plans", "A"); err != nil || width != minFittedColWidth {
+		t.Errorf("id column is %v wide (%v), want the narrowest fitted width %d", width, err, minFittedColWidth)
+	}
+	cell, _ := excelize.CoordinatesToCellName(2, resultFirstDataRow)
+	if value, _ := f.GetCellValue("1_Plans", cell); value != plan {
+		t.Errorf("query_plan cell holds %d characters, want the full %d", len(value), len(plan))
+	}
+}
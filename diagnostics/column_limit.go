@@ -0,0 +1,43 @@
+package diagnostics
+
+import (
+	"fmt" // For formatted I/O operations
+)
+
+// Actions of `-max-columns-action` for a result wider than `-max-columns`
+const (
+	maxColumnsTruncate = "truncate"
+	maxColumnsFail     = "fail"
+)
+
+/*
+ * checkColumnCount enforces `-max-columns` with the "fail" action on the columns of a result.
+ *
+ * Returns:
+ * - An error naming the column count and the limit when the result is too wide and the action is "fail",
+ *   nil otherwise. With the "truncate" action wide results are cut by keptColumns instead.
+ */
+func checkColumnCount(opts RunOptions, columns []string) error {
+	if opts.MaxColumns <= 0 || len(columns) <= opts.MaxColumns || opts.MaxColumnsAction != maxColumnsFail {
+		return nil
+	}
+	return fmt.Errorf("the result has %d columns, more than the %d allowed by -max-columns, select fewer columns or raise the limit", len(columns), opts.MaxColumns)
+}
+
+/*
+ * keptColumns returns how many of the `count` columns of a result are written, the first `-max-columns` ones
+ * when the result is wider, all of them otherwise.
+ */
+func keptColumns(opts RunOptions, count int) int {
+	if opts.MaxColumns > 0 && count > opts.MaxColumns {
+		return opts.MaxColumns
+	}
+	return count
+}
+
+/*
+ * truncationNote returns the text written after the last header of a truncated result sheet.
+ */
+func truncationNote(kept, count int) string {
+	return fmt.Sprintf("(truncated: first %d of %d columns shown, -max-columns)", kept, count)
+}
@@ -0,0 +1,70 @@
+package diagnostics
+
+import (
+	"database/sql/driver" // For the values of the fake result
+	"fmt"                 // For naming the columns
+	"strings"             // For checking the error
+	"testing"             // For the test framework
+
+	"github.com/xuri/excelize/v2" // For reading back the result sheet
+)
+
+/*
+ * TestWriteQueryResultsMaxColumns writes a 300 column result with -max-columns 255 and checks the truncate action
+ * keeps the first 255 columns followed by the truncation note, and the fail action fails the query without a sheet.
+ */
+func TestWriteQueryResultsMaxColumns(t *testing.T) {
+	const count, limit = 300, 255
+	columns := make([]string, count)
+	row := make([]driver.Value, count)
+	for i := range columns {
+		columns[i] = fmt.Sprintf("c%d", i+1)
+		row[i] = int64(i + 1)
+	}
+
+	tests := []struct {
+		name    string
+		action  string
+		wantErr string
+	}{
+		{name: "truncate", action: maxColumnsTruncate},
+		{name: "fail", action: maxColumnsFail, wantErr: "the result has 300 columns, more than the 255 allowed by -max-columns"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := excelize.NewFile()
+			defer f.Close()
+			report := newExcelReport(f, RunOptions{MaxColumns: limit, MaxColumnsAction: tt.action})
+			rows := queryFakeRows(t, fakeResultSet{columns: columns, rows: [][]driver.Value{row}})
+
+			err := writeQueryResults(rows, Query{Name: "Wide"}, report, "1_Wide")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("writeQueryResults error = %v, want %q", err, tt.wantErr)
+				}
+				if idx, _ := f.GetSheetIndex("1_Wide"); idx != -1 {
+					t.Errorf("the failed query has a sheet")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("writeQueryResults: %v", err)
+			}
+
+			rowsRead, err := f.GetRows("1_Wide")
+			if err != nil {
+				t.Fatalf("GetRows: %v", err)
+			}
+			header, data := rowsRead[resultHeaderRow-1], rowsRead[resultFirstDataRow-1]
+			if len(header) != limit+1 || header[0] != "c1" || header[limit-1] != "c255" {
+				t.Fatalf("header has %d cells from %q to %q, want c1 to c255 and the note", len(header), header[0], header[len(header)-1])
+			}
+			if header[limit] != truncationNote(limit, count) {
+				t.Errorf("cell after the last header = %q, want %q", header[limit], truncationNote(limit, count))
+			}
+			if len(data) != limit || data[limit-1] != "255" {
+				t.Errorf("data row has %d cells ending with %q, want the 255 values of the kept columns", len(data), data[len(data)-1])
+			}
+		})
+	}
+}
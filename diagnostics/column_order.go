@@ -0,0 +1,107 @@
+package diagnostics
+
+import (
+	"strings" // For string manipulation
+)
+
+/*
+ * splitColumnList splits a comma separated list of column names from the command line, dropping empty names.
+ */
+func splitColumnList(value string) []string {
+	var columns []string
+	for _, column := range strings.Split(value, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+/*
+ * frontColumnList returns the columns to move to the front of a query's results, the query's own
+ * `columnsToFront` when set, the global `-columns-to-front` list otherwise.
+ */
+func frontColumnList(query Query, opts RunOptions) []string {
+	if len(query.ColumnsToFront) > 0 {
+		return query.ColumnsToFront
+	}
+	return opts.ColumnsToFront
+}
+
+/*
+ * columnOrder computes the display order of a result's columns with the `front` columns moved to the leftmost
+ * positions in the given order, the other columns following in their original order.
+ *
+ * Parameters:
+ * - columns: The column names of the result, in the order the query returns them.
+ * - query: The query, a front name matches a column name or its column label, case insensitively.
+ * - front: The names of the columns to move to the front.
+ *
+ * Returns:
+ * - order: The result column index shown at each display position, nil when the order is unchanged.
+ * - missing: The front names matching no column, they are ignored.
+ */
+func columnOrder(columns []string, query Query, front []string) ([]int, []string) {
+	if len(front) == 0 {
+		return nil, nil
+	}
+
+	labels := applyColumnLabels(columns, query)
+	used := make([]bool, len(columns))
+	order := make([]int, 0, len(columns))
+	var missing []string
+	for _, name := range front {
+		found := false
+		for i := range columns {
+			if !used[i] && (strings.EqualFold(columns[i], name) || strings.EqualFold(labels[i], name)) {
+				used[i] = true
+				order = append(order, i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, name)
+		}
+	}
+	if len(order) == 0 {
+		return nil, missing
+	}
+
+	for i := range columns {
+		if !used[i] {
+			order = append(order, i)
+		}
+	}
+	return order, missing
+}
+
+/*
+ * reorderColumns returns the items of a result, its column names or types, in the display order computed by
+ * columnOrder, or the items unchanged when the order is nil.
+ */
+func reorderColumns[T any](items []T, order []int) []T {
+	if order == nil {
+		return items
+	}
+	reordered := make([]T, len(order))
+	for i, index := range order {
+		reordered[i] = items[index]
+	}
+	return reordered
+}
+
+/*
+ * scanTargets returns the scan destinations of a row in the order the query returns its columns, so
+ * rows.Scan fills `values` directly in the display order computed by columnOrder.
+ */
+func scanTargets(values []interface{}, order []int) []interface{} {
+	if order == nil {
+		return values
+	}
+	targets := make([]interface{}, len(values))
+	for i, index := range order {
+		targets[index] = values[i]
+	}
+	return targets
+}
@@ -0,0 +1,95 @@
+package diagnostics
+
+import (
+	"fmt"     // For formatting the orders
+	"strings" // For comparing the names
+	"testing" // For the test framework
+)
+
+/*
+ * TestColumnOrder checks the front columns are matched by name or label, case insensitively, moved to the left in
+ * the given order with the other columns after them in their original order, and the unknown names returned.
+ */
+func TestColumnOrder(t *testing.T) {
+	columns := []string{"session_id", "login_name", "cpu_time", "wait_type", "cpu_time"}
+	query := Query{Name: "Sessions", ColumnLabels: map[string]string{"login_name": "Login"}}
+	tests := []struct {
+		name        string
+		front       []string
+		wantOrder   []int
+		wantMissing []string
+	}{
+		{name: "no front columns", front: nil, wantOrder: nil},
+		{name: "by name", front: []string{"wait_type", "session_id"}, wantOrder: []int{3, 0, 1, 2, 4}},
+		{name: "by label", front: []string{"Login"}, wantOrder: []int{1, 0, 2, 3, 4}},
+		{name: "case insensitive", front: []string{"WAIT_TYPE"}, wantOrder: []int{3, 0, 1, 2, 4}},
+		{name: "duplicate column names", front: []string{"cpu_time", "cpu_time"}, wantOrder: []int{2, 4, 0, 1, 3}},
+		{name: "unknown name ignored", front: []string{"blocked_by", "wait_type"}, wantOrder: []int{3, 0, 1, 2, 4}, wantMissing: []string{"blocked_by"}},
+		{name: "only unknown names", front: []string{"blocked_by", "host_name"}, wantOrder: nil, wantMissing: []string{"blocked_by", "host_name"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, missing := columnOrder(columns, query, tt.front)
+			if (order == nil) != (tt.wantOrder == nil) || fmt.Sprint(order) != fmt.Sprint(tt.wantOrder) {
+				t.Errorf("columnOrder(%q) order = %v, want %v", tt.front, order, tt.wantOrder)
+			}
+			if strings.Join(missing, ",") != strings.Join(tt.wantMissing, ",") {
+				t.Errorf("columnOrder(%q) missing = %q, want %q", tt.front, missing, tt.wantMissing)
+			}
+		})
+	}
+}
+
+/*
+ * TestReorderColumns checks the names and the scan targets of a row follow the display order, so the values
+ * scanned in the query's order come out in the display order, and a nil order leaves everything unchanged.
+ */
+func TestReorderColumns(t *testing.T) {
+	columns := []string{"session_id", "login_name", "wait_type"}
+	tests := []struct {
+		name  string
+		order []int
+		want  []string
+	}{
+		{name: "unchanged", order: nil, want: []string{"session_id", "login_name", "wait_type"}},
+		{name: "last column first", order: []int{2, 0, 1}, want: []string{"wait_type", "session_id", "login_name"}},
+		{name: "swapped", order: []int{1, 0, 2}, want: []string{"login_name", "session_id", "wait_type"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reorderColumns(columns, tt.order); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("reorderColumns(%v) = %q, want %q", tt.order, got, tt.want)
+			}
+
+			// Scan a row the way rows.Scan does, in the order the query returns the columns
+			values := make([]interface{}, len(columns))
+			for i := range values {
+				values[i] = new(interface{})
+			}
+			for i, target := range scanTargets(values, tt.order) {
+				*(target.(*interface{})) = columns[i]
+			}
+			for i, value := range values {
+				if got := *(value.(*interface{})); got != tt.want[i] {
+					t.Errorf("value %d scanned as %v, want %s", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+	if columns[0] != "session_id" {
+		t.Errorf("reorderColumns changed the column names to %q", columns)
+	}
+}
+
+/*
+ * TestFrontColumnList checks a query's own columnsToFront take precedence over -columns-to-front.
+ */
+func TestFrontColumnList(t *testing.T) {
+	opts := RunOptions{ColumnsToFront: splitColumnList(" wait_type, ,session_id ")}
+	if got := frontColumnList(Query{}, opts); strings.Join(got, ",") != "wait_type,session_id" {
+		t.Errorf("frontColumnList without columnsToFront = %q, want the -columns-to-front list", got)
+	}
+	if got := frontColumnList(Query{ColumnsToFront: []string{"login_name"}}, opts); strings.Join(got, ",") != "login_name" {
+		t.Errorf("frontColumnList with columnsToFront = %q, want the query's list", got)
+	}
+}
@@ -0,0 +1,103 @@
+package diagnostics
+
+import (
+	"regexp"  // For matching the comment tag lines
+	"strings" // For string manipulation
+)
+
+// A comment tag line such as "-- @description Top waits since the last restart" or "-- description: Top waits"
+var commentTagLine = regexp.MustCompile(`^--\s*(?:@(\w+)\s*|(\w+)\s*:)(.*)$`)
+
+/*
+ * queryCommentTags holds the metadata found in the comment tags leading a query's SQL.
+ *
+ * Fields:
+ * - Name: The text of `-- @name`.
+ * - Description: The text of `-- @description`, repeated tags are joined with a space.
+ * - Notes: The text of `-- @notes`, repeated tags are joined with a space.
+ * - Tags: The comma separated values of every `-- @tags`.
+ */
+type queryCommentTags struct {
+	Name        string
+	Description string
+	Notes       string
+	Tags        []string
+}
+
+/*
+ * parseCommentTags extracts the metadata comment tags from the leading comment block of a SQL text.
+ *
+ * Parameters:
+ * - sqlText: The SQL of a query, or the content of a .sql file.
+ *
+ * Returns:
+ * - The tags found, empty when the SQL has no leading comment tags.
+ *
+ * Notes:
+ * - Only the "--" comment lines before the first SQL statement are read, blank lines in the block are skipped.
+ * - Unknown tags and plain comment lines are ignored, so the block can also hold free text.
+ *
+ * The supported tags are:
+ *
+ *   -- @name Wait statistics
+ *   -- @description Top waits since the last restart
+ *   -- @notes Cleared by DBCC SQLPERF('sys.dm_os_wait_stats', CLEAR)
+ *   -- @tags waits, performance
+ *
+ * Each tag can also be written as "-- description: Top waits since the last restart", the form of the .sql
+ * files read with `-queries-dir`.
+ */
+func parseCommentTags(sqlText string) queryCommentTags {
+	var tags queryCommentTags
+	for _, line := range strings.Split(sqlText, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		match := commentTagLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		value := strings.TrimSpace(match[3])
+		switch strings.ToLower(match[1] + match[2]) {
+		case "name":
+			tags.Name = value
+		case "description":
+			tags.Description = strings.TrimSpace(tags.Description + " " + value)
+		case "notes":
+			tags.Notes = strings.TrimSpace(tags.Notes + " " + value)
+		case "tags":
+			for _, tag := range strings.Split(value, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" && !containsString(tags.Tags, tag) {
+					tags.Tags = append(tags.Tags, tag)
+				}
+			}
+		}
+	}
+	return tags
+}
+
+/*
+ * applyCommentTags fills the metadata of a query from the comment tags leading its SQL.
+ *
+ * Notes:
+ * - Fields set explicitly in the JSON or TOML file take precedence, comment tags only fill the empty ones.
+ */
+func applyCommentTags(query *Query) {
+	tags := parseCommentTags(query.Query)
+	if query.Name == "" {
+		query.Name = tags.Name
+	}
+	if query.Description == "" {
+		query.Description = tags.Description
+	}
+	if query.Notes == "" {
+		query.Notes = tags.Notes
+	}
+	if len(query.Tags) == 0 {
+		query.Tags = tags.Tags
+	}
+}
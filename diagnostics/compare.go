@@ -0,0 +1,346 @@
+package diagnostics
+
+import (
+	"fmt"     // For formatted I/O operations
+	"strconv" // For reading the numeric cells of both workbooks
+	"strings" // For string manipulation
+	"time"    // For the timestamp of the comparison workbook
+
+	"github.com/xuri/excelize/v2" // For reading the compared workbooks and writing the comparison
+)
+
+// Name of the sheet listing every compared sheet and its row counts
+const compareSummarySheetName = "compare_summary"
+
+// Row status of the comparison sheets and sheet status of the compare_summary sheet
+const (
+	compareChanged   = "changed"
+	compareUnchanged = "unchanged"
+	compareOnlyInOld = "only in old"
+	compareOnlyInNew = "only in new"
+	compareMatched   = "matched"
+)
+
+/*
+ * compareSheetResult is the outcome of comparing one sheet name of the two workbooks, written as a row of
+ * the compare_summary sheet.
+ */
+type compareSheetResult struct {
+	Sheet   string // Name of the sheet
+	Status  string // matched, only in old or only in new
+	Matched int    // Rows whose key is in both sheets
+	Changed int    // Matched rows with at least one different value
+	OnlyOld int    // Rows whose key is only in the old sheet
+	OnlyNew int    // Rows whose key is only in the new sheet
+	Note    string // Why a sheet could not be compared
+}
+
+/*
+ * compareWorkbooks writes a workbook comparing two result workbooks of this tool, for `-compare`. It runs
+ * nothing against the database.
+ *
+ * Parameters:
+ * - oldFile: The workbook of the earlier run.
+ * - newFile: The workbook of the later run.
+ * - output: The `-output` directory or file name template, empty for the working directory.
+ *
+ * Returns:
+ * - The name of the comparison workbook, or an error if a workbook cannot be read or the comparison saved.
+ *
+ * Functionality:
+ * 1. Matches the sheets of both workbooks by name. A sheet of a single workbook is only listed on the
+ *    compare_summary sheet, as "only in old" or "only in new".
+ * 2. Compares every matched sheet with compareSheet, writing a sheet of the same name.
+ * 3. Writes the compare_summary sheet first, with the status and row counts of every sheet, and saves the
+ *    workbook as "sql_diagnostics_compare_<timestamp>.xlsx".
+ */
+func compareWorkbooks(oldFile string, newFile string, output string) (string, error) {
+	oldBook, err := excelize.OpenFile(oldFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %v", oldFile, err)
+	}
+	defer oldBook.Close()
+	newBook, err := excelize.OpenFile(newFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %v", newFile, err)
+	}
+	defer newBook.Close()
+
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetSheetName("Sheet1", compareSummarySheetName)
+	report := newExcelReport(f, RunOptions{})
+
+	oldSheets := make(map[string]bool)
+	for _, name := range oldBook.GetSheetList() {
+		oldSheets[name] = true
+	}
+	newSheets := make(map[string]bool)
+
+	var results []compareSheetResult
+	for _, name := range newBook.GetSheetList() {
+		newSheets[name] = true
+		if !oldSheets[name] {
+			results = append(results, compareSheetResult{Sheet: name, Status: compareOnlyInNew})
+			continue
+		}
+		results = append(results, compareSheet(report, oldBook, newBook, name))
+	}
+	for _, name := range oldBook.GetSheetList() {
+		if !newSheets[name] {
+			results = append(results, compareSheetResult{Sheet: name, Status: compareOnlyInOld})
+		}
+	}
+
+	writeCompareSummary(f, oldFile, newFile, results)
+
+	baseName, err := outputBaseName(output, runTarget{}, "compare_", time.Now())
+	if err != nil {
+		return "", err
+	}
+	fileName := baseName + ".xlsx"
+	if err := saveWorkbook(f, fileName); err != nil {
+		return "", fmt.Errorf("failed to save %s: %v", fileName, err)
+	}
+	return fileName, nil
+}
+
+/*
+ * compareSheet compares the sheet of the same name in both workbooks and writes the comparison to a sheet of
+ * that name.
+ *
+ * Parameters:
+ * - report: The comparison workbook.
+ * - oldBook: The workbook of the earlier run.
+ * - newBook: The workbook of the later run.
+ * - name: The name of the sheet in both workbooks.
+ *
+ * Returns:
+ * - The row counts of the sheet for the compare_summary sheet.
+ *
+ * Functionality:
+ * 1. The header row of each sheet, below its title block, is its header, the rows are joined on the value of their first column. Rows
+ *    sharing a key are joined in order, extra occurrences are only in one sheet.
+ * 2. The columns are those of the new sheet, a column of the old sheet is matched by name case insensitively,
+ *    old columns missing from the new sheet are not written.
+ * 3. Every row gets a "Row status" first: "changed", "unchanged", "only in new", or "only in old" for the rows
+ *    of the old sheet without a match, written last with their old values.
+ * 4. Every column in both sheets holding numbers, other than the key, gets a "<column> (delta)" column after the
+ *    values: the new value minus the old one when both are numbers, empty otherwise and for unmatched rows.
+ * 5. Positive deltas are red and negative ones green: most diagnostic figures (waits, reads, CPU, counts) are
+ *    better lower. The colors are conditional formats, so they can be edited in Excel for other figures.
+ *
+ * Notes:
+ * - The values are compared as the text of the cells, as written by the runs.
+ */
+func compareSheet(report *excelReport, oldBook *excelize.File, newBook *excelize.File, name string) compareSheetResult {
+	result := compareSheetResult{Sheet: name, Status: compareMatched}
+
+	oldRows, err := oldBook.GetRows(name)
+	if err == nil {
+		var newRows [][]string
+		if newRows, err = newBook.GetRows(name); err == nil {
+			oldRows, newRows = resultTableRows(oldRows), resultTableRows(newRows)
+			if len(oldRows) == 0 || len(newRows) == 0 {
+				result.Note = "empty sheet, nothing compared"
+				return result
+			}
+			writeCompareSheet(report, name, oldRows, newRows, &result)
+			return result
+		}
+	}
+	logError("Failed to read the %s sheet: %v", name, err)
+	result.Note = fmt.Sprintf("failed to read the sheet: %v", err)
+	return result
+}
+
+/*
+ * writeCompareSheet joins the rows of the old and new sheet and writes them with their deltas, counting the
+ * rows into `result`.
+ */
+func writeCompareSheet(report *excelReport, name string, oldRows [][]string, newRows [][]string, result *compareSheetResult) {
+	f := report.f
+	oldHeader, newHeader := oldRows[0], newRows[0]
+
+	// The old column of every new column, -1 when the old sheet does not have it
+	oldColumn := make([]int, len(newHeader))
+	var shared []int
+	for i, column := range newHeader {
+		oldColumn[i] = -1
+		for j, candidate := range oldHeader {
+			if strings.EqualFold(strings.TrimSpace(candidate), strings.TrimSpace(column)) {
+				oldColumn[i] = j
+				shared = append(shared, i)
+				break
+			}
+		}
+	}
+
+	// The shared columns with a number in the new sheet get a delta column, the key column never does
+	var deltas []int
+	for _, i := range shared {
+		if i == 0 {
+			continue
+		}
+		for _, row := range newRows[1:] {
+			if _, err := strconv.ParseFloat(strings.TrimSpace(cellAt(row, i)), 64); err == nil {
+				deltas = append(deltas, i)
+				break
+			}
+		}
+	}
+
+	// The old rows of every key, consumed in order as the new rows are joined
+	oldByKey := make(map[string][][]string)
+	var oldKeys []string
+	for _, row := range oldRows[1:] {
+		key := cellAt(row, 0)
+		if _, seen := oldByKey[key]; !seen {
+			oldKeys = append(oldKeys, key)
+		}
+		oldByKey[key] = append(oldByKey[key], row)
+	}
+
+	if _, err := f.NewSheet(name); err != nil {
+		logError("Failed to create the %s comparison sheet: %v", name, err)
+		return
+	}
+	header := []interface{}{"Row status"}
+	for _, column := range newHeader {
+		header = append(header, column)
+	}
+	for _, i := range deltas {
+		header = append(header, newHeader[i]+" (delta)")
+	}
+	f.SetSheetRow(name, "A1", &header)
+
+	rowIndex := 2
+	writeRow := func(status string, newRow []string, oldRow []string) {
+		values := []interface{}{status}
+		for i := range newHeader {
+			switch {
+			case newRow != nil:
+				values = append(values, cellAt(newRow, i))
+			case oldColumn[i] >= 0:
+				values = append(values, cellAt(oldRow, oldColumn[i]))
+			default:
+				values = append(values, "")
+			}
+		}
+		for _, i := range deltas {
+			oldValue, oldErr := strconv.ParseFloat(strings.TrimSpace(cellAt(oldRow, oldColumn[i])), 64)
+			newValue, newErr := strconv.ParseFloat(strings.TrimSpace(cellAt(newRow, i)), 64)
+			if newRow == nil || oldRow == nil || oldErr != nil || newErr != nil {
+				values = append(values, nil)
+				continue
+			}
+			values = append(values, newValue-oldValue)
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, rowIndex)
+		f.SetSheetRow(name, cell, &values)
+		rowIndex++
+	}
+
+	for _, row := range newRows[1:] {
+		key := cellAt(row, 0)
+		matches := oldByKey[key]
+		if len(matches) == 0 {
+			result.OnlyNew++
+			writeRow(compareOnlyInNew, row, nil)
+			continue
+		}
+		oldByKey[key] = matches[1:]
+		result.Matched++
+
+		status := compareUnchanged
+		for _, i := range shared {
+			if cellAt(row, i) != cellAt(matches[0], oldColumn[i]) {
+				status = compareChanged
+				break
+			}
+		}
+		if status == compareChanged {
+			result.Changed++
+		}
+		writeRow(status, row, matches[0])
+	}
+	for _, key := range oldKeys {
+		for _, row := range oldByKey[key] {
+			result.OnlyOld++
+			writeRow(compareOnlyInOld, nil, row)
+		}
+	}
+
+	highlightDeltas(report, name, len(newHeader)+2, len(newHeader)+1+len(deltas), rowIndex-1)
+}
+
+/*
+ * cellAt returns the text of a cell of a row read with GetRows, which drops the empty trailing cells.
+ */
+func cellAt(row []string, index int) string {
+	if index < 0 || index >= len(row) {
+		return ""
+	}
+	return row[index]
+}
+
+/*
+ * highlightDeltas colors the delta columns of a comparison sheet, increases red and decreases green.
+ *
+ * Parameters:
+ * - report: The comparison workbook.
+ * - sheet: The comparison sheet.
+ * - firstColumn: The 1 based index of the first delta column.
+ * - lastColumn: The 1 based index of the last delta column.
+ * - lastRow: The last row of the sheet.
+ */
+func highlightDeltas(report *excelReport, sheet string, firstColumn int, lastColumn int, lastRow int) {
+	if firstColumn > lastColumn || lastRow < 2 {
+		return
+	}
+	increase, err := report.conditionalStyle("compare_increase", &excelize.Style{
+		Font: &excelize.Font{Color: "9C0006"},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}},
+	})
+	if err != nil {
+		logError("Failed to create the delta increase style: %v", err)
+		return
+	}
+	decrease, err := report.conditionalStyle("compare_decrease", &excelize.Style{
+		Font: &excelize.Font{Color: "006100"},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"C6EFCE"}},
+	})
+	if err != nil {
+		logError("Failed to create the delta decrease style: %v", err)
+		return
+	}
+
+	firstCell, _ := excelize.CoordinatesToCellName(firstColumn, 2)
+	lastCell, _ := excelize.CoordinatesToCellName(lastColumn, lastRow)
+	err = report.f.SetConditionalFormat(sheet, firstCell+":"+lastCell, []excelize.ConditionalFormatOptions{
+		{Type: "cell", Criteria: "greater than", Value: "0", Format: &increase},
+		{Type: "cell", Criteria: "less than", Value: "0", Format: &decrease},
+	})
+	if err != nil {
+		logError("Failed to highlight the deltas of the %s sheet: %v", sheet, err)
+	}
+}
+
+/*
+ * writeCompareSummary writes the compare_summary sheet: the compared files, then one row per sheet name with its
+ * status and row counts. Sheets of a single workbook have no counts.
+ */
+func writeCompareSummary(f *excelize.File, oldFile string, newFile string, results []compareSheetResult) {
+	f.SetSheetRow(compareSummarySheetName, "A1", &[]interface{}{"Old workbook", oldFile})
+	f.SetSheetRow(compareSummarySheetName, "A2", &[]interface{}{"New workbook", newFile})
+	f.SetSheetRow(compareSummarySheetName, "A4", &[]interface{}{"Sheet", "Status", "Matched rows", "Changed rows", "Rows only in old", "Rows only in new", "Note"})
+
+	for i, result := range results {
+		values := []interface{}{result.Sheet, result.Status}
+		if result.Status == compareMatched {
+			values = append(values, result.Matched, result.Changed, result.OnlyOld, result.OnlyNew, result.Note)
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, i+5)
+		f.SetSheetRow(compareSummarySheetName, cell, &values)
+	}
+}
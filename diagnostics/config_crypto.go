@@ -0,0 +1,198 @@
+package diagnostics
+
+import (
+	"bytes"           // For detecting the encrypted config header
+	"crypto/aes"      // For the AES cipher
+	"crypto/cipher"   // For the GCM mode
+	"crypto/pbkdf2"   // For deriving the key from the passphrase
+	"crypto/rand"     // For the salt and nonce
+	"crypto/sha256"   // For the key derivation hash
+	"encoding/base64" // For storing the encrypted config as text
+	"errors"          // For the wrong passphrase error
+	"fmt"             // For formatted I/O operations
+	"os"              // For reading and writing the config files
+	"strings"         // For string manipulation
+
+	"golang.org/x/term" // For reading the passphrase without echo
+)
+
+// First line of an encrypted config file, followed by the base64 encoded salt, nonce and ciphertext
+const encryptedConfigHeader = "SQLDIAG-ENCRYPTED-CONFIG v1"
+
+// Environment variable holding the passphrase of an encrypted config file, prompted for when not set
+const configPassphraseEnv = "SQLDIAG_CONFIG_PASSPHRASE"
+
+// Key derivation settings, PBKDF2 with SHA-256 deriving an AES-256 key
+const (
+	configKDFIterations = 600000
+	configSaltSize      = 16
+	configKeySize       = 32
+)
+
+// Returned when the passphrase does not decrypt the config, GCM cannot tell it apart from a tampered file
+var errWrongPassphrase = errors.New("wrong passphrase or corrupted file")
+
+// The passphrase entered at the prompt, kept so a scheduled run only asks once
+var configPassphrase string
+
+/*
+ * isEncryptedConfig reports whether the content of a config file was written by `-encrypt-config`.
+ */
+func isEncryptedConfig(content []byte) bool {
+	return bytes.HasPrefix(content, []byte(encryptedConfigHeader))
+}
+
+/*
+ * deriveConfigKey derives the AES-256 key from the passphrase and salt.
+ */
+func deriveConfigKey(passphrase string, salt []byte) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, passphrase, salt, configKDFIterations, configKeySize)
+}
+
+/*
+ * encryptConfig encrypts the content of a config file with AES-256-GCM under a key derived from the passphrase.
+ *
+ * Returns:
+ * - The encrypted file content: the header line followed by base64(salt | nonce | ciphertext).
+ */
+func encryptConfig(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, configSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, err := deriveConfigKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	// The header is authenticated too, so it cannot be swapped for another version
+	blob := append(append(salt, nonce...), gcm.Seal(nil, nonce, plaintext, []byte(encryptedConfigHeader))...)
+	return []byte(encryptedConfigHeader + "\n" + base64.StdEncoding.EncodeToString(blob) + "\n"), nil
+}
+
+/*
+ * decryptConfig decrypts the content of a config file written by `encryptConfig`.
+ *
+ * Returns:
+ * - The plaintext properties, or `errWrongPassphrase` when the passphrase does not match.
+ */
+func decryptConfig(content []byte, passphrase string) ([]byte, error) {
+	encoded := strings.TrimSpace(strings.TrimPrefix(string(content), encryptedConfigHeader))
+	blob, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("the encrypted config is not valid base64: %v", err)
+	}
+	if len(blob) < configSaltSize {
+		return nil, errWrongPassphrase
+	}
+	salt, rest := blob[:configSaltSize], blob[configSaltSize:]
+
+	key, err := deriveConfigKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) < gcm.NonceSize() {
+		return nil, errWrongPassphrase
+	}
+	plaintext, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], []byte(encryptedConfigHeader))
+	if err != nil {
+		return nil, errWrongPassphrase
+	}
+	return plaintext, nil
+}
+
+/*
+ * readConfigPassphrase returns the passphrase from SQLDIAG_CONFIG_PASSPHRASE, or prompts for it without echo.
+ *
+ * Parameters:
+ * - confirm: Prompt twice and require both entries to match, used when encrypting.
+ */
+func readConfigPassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv(configPassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	if configPassphrase != "" && !confirm {
+		return configPassphrase, nil
+	}
+
+	prompt := func(label string) (string, error) {
+		fmt.Print(label)
+		passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		return string(passphrase), err
+	}
+
+	passphrase, err := prompt("Config passphrase: ")
+	if err != nil {
+		return "", fmt.Errorf("failed to read the passphrase, set %s when not running in a terminal: %v", configPassphraseEnv, err)
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("the passphrase cannot be empty")
+	}
+	if confirm {
+		again, err := prompt("Repeat the passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", fmt.Errorf("the passphrases do not match")
+		}
+	}
+	configPassphrase = passphrase
+	return passphrase, nil
+}
+
+/*
+ * encryptConfigFile implements `-encrypt-config`, writing "<file>.enc" next to the plaintext properties file.
+ *
+ * Parameters:
+ * - filePath: The plaintext properties file.
+ *
+ * Returns:
+ * - The path of the encrypted file, or an error.
+ *
+ * Notes:
+ * - The plaintext file is left in place, delete it once the encrypted file has been checked.
+ */
+func encryptConfigFile(filePath string) (string, error) {
+	plaintext, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	if isEncryptedConfig(plaintext) {
+		return "", fmt.Errorf("%s is already encrypted", filePath)
+	}
+	passphrase, err := readConfigPassphrase(true)
+	if err != nil {
+		return "", err
+	}
+	content, err := encryptConfig(plaintext, passphrase)
+	if err != nil {
+		return "", err
+	}
+	encryptedPath := filePath + ".enc"
+	if err := os.WriteFile(encryptedPath, content, 0600); err != nil {
+		return "", err
+	}
+	return encryptedPath, nil
+}
@@ -0,0 +1,148 @@
+package diagnostics
+
+import (
+	"errors"  // For unwrapping the driver errors
+	"fmt"     // For formatted I/O operations
+	"strings" // For string manipulation
+
+	mssql "github.com/microsoft/go-mssqldb" // For the SQL Server error number and state
+	"github.com/microsoft/go-mssqldb/msdsn" // For resolving the host, port and authentication of the connection string
+)
+
+// Guidance for the states of error 18456 (login failed), as listed in the server error log
+var loginFailedStates = map[uint8]string{
+	2:  "the login does not exist on the server, check the user name",
+	5:  "the login does not exist on the server, check the user name",
+	6:  "a Windows login was given as a SQL Server login, use integrated security (TRUSTED=true) instead",
+	7:  "the login is disabled and the password is wrong, enable the login and check the password",
+	8:  "the password is wrong",
+	9:  "the password is invalid",
+	11: "the login is valid but has no access to the server, grant it CONNECT SQL",
+	12: "the login is valid but has no access to the server, grant it CONNECT SQL",
+	18: "the password must be changed before the login can connect",
+	38: "the database requested by the login cannot be opened, check the database name and the user mapping",
+	40: "the default database of the login cannot be opened, change the login's default database",
+	58: "SQL Server logins are not allowed, the server only accepts Windows authentication (mixed mode is off)",
+}
+
+// Guidance for the SQL Server error numbers commonly returned while connecting
+var connectionErrorNumbers = map[int32]string{
+	4060:  "the database cannot be opened: check the database name, that it is online, and that the login is mapped to a user in it",
+	18452: "the Windows login comes from an untrusted domain, or the server does not accept Windows authentication for it",
+	18470: "the login is disabled, enable it with ALTER LOGIN ... ENABLE",
+	18487: "the password of the login has expired, change it before connecting",
+	18488: "the password of the login must be changed before connecting",
+	40532: "the server name in the login is not the Azure SQL server the host points to, use user@server or check the host",
+	40615: "the client IP address is not allowed by the Azure SQL firewall, add a firewall rule for it",
+}
+
+/*
+ * connectionErrorGuidance classifies a connection failure and returns what to check to fix it.
+ *
+ * Parameters:
+ * - err: The error returned while connecting.
+ *
+ * Returns:
+ * - The guidance for the error, a generic checklist when the error is not recognized.
+ *
+ * Notes:
+ * - SQL Server usually reports state 1 for a failed login to the client and writes the real state to its error
+ *   log, the guidance then points to the log. The states listed in `loginFailedStates` are used when reported.
+ * - Errors that are not SQL Server errors are classified from their message: TLS, timeouts, unreachable hosts.
+ */
+func connectionErrorGuidance(err error) string {
+	var sqlErr mssql.Error
+	if errors.As(err, &sqlErr) {
+		if sqlErr.Number == 18456 {
+			if guidance, ok := loginFailedStates[sqlErr.State]; ok {
+				return fmt.Sprintf("login failed (state %d): %s", sqlErr.State, guidance)
+			}
+			return "login failed: check the user name and password, or find the state of error 18456 in the server error log for the exact reason"
+		}
+		if guidance, ok := connectionErrorNumbers[sqlErr.Number]; ok {
+			return guidance
+		}
+	}
+
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "x509") || strings.Contains(message, "certificate") || strings.Contains(message, "tls"):
+		return "the TLS handshake failed: check CA_CERT, use HOST_NAME_IN_CERTIFICATE when connecting by IP address or alias, or check the server's encryption settings"
+	case strings.Contains(message, "no such host"):
+		return "the host name cannot be resolved, check DB_HOST and DNS"
+	case strings.Contains(message, "connection refused"):
+		return "the host refused the connection, check DB_PORT and that SQL Server listens on TCP"
+	case strings.Contains(message, "timeout") || strings.Contains(message, "deadline exceeded"):
+		return "the server did not answer in time, check the host, port, firewalls and that the server is running, or raise -ping-timeout"
+	case strings.Contains(message, "unable to open tcp connection"):
+		return "the server cannot be reached, check the host, port and firewalls"
+	}
+	return "check the host, port, database name and credentials of the connection"
+}
+
+/*
+ * describeConnectionTarget returns the host, port, database and authentication mode resolved from the connection
+ * string, never its password, so a failure report shows what was actually tried.
+ */
+func describeConnectionTarget(connectionString string) string {
+	config, err := msdsn.Parse(connectionString)
+	if err != nil {
+		return "the connection string cannot be parsed"
+	}
+
+	host := config.Host
+	if config.Instance != "" {
+		host += `\` + config.Instance
+	}
+	port := "default port"
+	if config.Port != 0 {
+		port = fmt.Sprintf("port %d", config.Port)
+	}
+	database := config.Database
+	if database == "" {
+		database = "the login's default database"
+	}
+	auth := "integrated security"
+	if method := config.Parameters[azureADParameter]; method != "" {
+		auth = "Azure AD authentication " + method
+	} else if config.User != "" {
+		auth = "SQL Server login " + config.User
+	}
+	return fmt.Sprintf("host %s, %s, database %s, %s", host, port, database, auth)
+}
+
+/*
+ * connectionFailure formats a connection failure with its guidance, the masked connection string and the
+ * resolved target, for the fatal message of connectToDB.
+ */
+func connectionFailure(err error, connectionString string) string {
+	return fmt.Sprintf("%v\n  Guidance: %s\n  Connection string: %s\n  Resolved target: %s", err, connectionErrorGuidance(err), maskConnectionString(connectionString), describeConnectionTarget(connectionString))
+}
+
+/*
+ * runTarget is the server and database a run connects to, as resolved from its connection string.
+ */
+type runTarget struct {
+	Host     string // Host, with its instance name and port when set
+	Database string // Database name, empty for the login's default database
+}
+
+/*
+ * connectionRunTarget resolves the server and database of a connection string, never its credentials, for the
+ * output formats recording where the results came from. A connection string that cannot be parsed gives an
+ * empty target.
+ */
+func connectionRunTarget(connectionString string) runTarget {
+	config, err := msdsn.Parse(connectionString)
+	if err != nil {
+		return runTarget{}
+	}
+	host := config.Host
+	if config.Instance != "" {
+		host += `\` + config.Instance
+	}
+	if config.Port != 0 {
+		host += fmt.Sprintf(",%d", config.Port)
+	}
+	return runTarget{Host: host, Database: config.Database}
+}
@@ -0,0 +1,134 @@
+package diagnostics
+
+import (
+	"context"      // For bounding the health check of a reused pool
+	"database/sql" // Database/sql package for database operations
+	"fmt"          // For formatted I/O operations
+	"time"         // For the lifetime of the pool connections
+)
+
+/*
+ * connectionPool keeps the *sql.DB of a scheduled run open across its iterations, so the connections and
+ * their session setup are not established again every interval.
+ *
+ * Fields:
+ * - connectionString: The connection string the pool was opened with, a different one rebuilds the pool.
+ * - db: The open pool, nil before the first iteration.
+ * - opened: The number of times a pool was opened, the first iteration, reconnections and rebuilds.
+ * - reused: The number of iterations that reused the open pool.
+ */
+type connectionPool struct {
+	connectionString string
+	db               *sql.DB
+	opened           int
+	reused           int
+}
+
+/*
+ * connect returns the pool for the next run of the queries.
+ *
+ * Parameters:
+ * - sqlConfig: The SQL Server configuration, read again by every iteration.
+ * - opts: The run options, `PingTimeout` bounds the health check of a reused pool.
+ *
+ * Returns:
+ * - The open *sql.DB, owned by the pool, the caller must not close it.
+ * - A connectionFailedError if the pool had to be opened and the database cannot be reached, see `connectToDB`.
+ *
+ * Functionality:
+ * 1. Opens the pool with `connectToDB` the first time.
+ * 2. Rebuilds the pool when the connection settings changed since the previous iteration, as the
+ *    configuration file is read again every iteration.
+ * 3. Otherwise pings the open pool and reuses it, a failing ping closes it and connects again.
+ */
+func (p *connectionPool) connect(sqlConfig SQLServerConfig, opts RunOptions) (*sql.DB, error) {
+	connectionString := buildConnectionString(sqlConfig)
+
+	if p.db != nil && connectionString != p.connectionString {
+		logInfo("The connection settings changed, rebuilding the connection pool.")
+		p.close()
+	}
+
+	if p.db != nil {
+		ctx := context.Background()
+		if opts.PingTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.PingTimeout)
+			defer cancel()
+		}
+		err := p.db.PingContext(ctx)
+		if err == nil {
+			p.reused++
+			return p.db, nil
+		}
+		logWarn("The open connection pool failed its health check, reconnecting: %v", err)
+		p.close()
+	}
+
+	db, err := connectToDB(sqlConfig, opts)
+	if err != nil {
+		return nil, connectionFailedError{err}
+	}
+	p.db = db
+	p.connectionString = connectionString
+	p.opened++
+	return p.db, nil
+}
+
+/*
+ * close closes the open pool, if any.
+ */
+func (p *connectionPool) close() {
+	if p.db != nil {
+		p.db.Close()
+		p.db = nil
+	}
+}
+
+/*
+ * String summarizes the connection churn of the run, how many pools were opened against how many iterations
+ * reused one.
+ */
+func (p *connectionPool) String() string {
+	return fmt.Sprintf("connection pool opened %d time(s), reused by %d iteration(s)", p.opened, p.reused)
+}
+
+/*
+ * applyPoolSettings sizes the connection pool of `db` from the run options.
+ *
+ * Parameters:
+ * - db: The database opened by `connectToDB`.
+ * - opts: The run options, with `Parallel`, `MaxOpenConns`, `MaxIdleConns` and `ConnMaxLifetime`.
+ *
+ * Notes:
+ * - By default the pool opens one connection per `-parallel` worker plus one, held by the `-pre-sql` hooks for the
+ *   whole run or used by the checks between queries, instead of the unlimited connections of database/sql.
+ * - The idle connections default to all the open ones, database/sql keeps only 2, so a scheduled run reuses its
+ *   connections across iterations, see `connectionPool`.
+ * - More idle connections than open ones are reduced to the open ones by database/sql.
+ */
+func applyPoolSettings(db *sql.DB, opts RunOptions) {
+	maxOpen := opts.MaxOpenConns
+	if maxOpen <= 0 {
+		maxOpen = max(opts.Parallel, 1) + 1
+	}
+	maxIdle := opts.MaxIdleConns
+	if maxIdle <= 0 {
+		maxIdle = maxOpen
+	}
+
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	logDebug("Connection pool: at most %d open and %d idle connection(s), reused for %s", maxOpen, maxIdle, connectionLifetime(opts.ConnMaxLifetime))
+}
+
+/*
+ * connectionLifetime describes the reuse limit of the pool connections for the log.
+ */
+func connectionLifetime(lifetime time.Duration) string {
+	if lifetime <= 0 {
+		return "the whole run"
+	}
+	return "at most " + lifetime.String()
+}
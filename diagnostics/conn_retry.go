@@ -0,0 +1,88 @@
+package diagnostics
+
+import (
+	"context"      // For bounding each ping
+	"database/sql" // Database/sql package for database operations
+	"errors"       // For unwrapping the driver errors
+	"strings"      // For string manipulation
+	"time"         // For the backoff between attempts
+
+	mssql "github.com/microsoft/go-mssqldb" // For the SQL Server error number
+)
+
+// Default of `-connect-retries`
+const defaultConnectRetries = 3
+
+// Wait before the first retry, doubled for every further retry up to maxConnectRetryDelay
+const (
+	firstConnectRetryDelay = time.Second
+	maxConnectRetryDelay   = 30 * time.Second
+)
+
+// SQL Server error numbers of transient connection failures, worth retrying after a short wait. A failover,
+// a reconfiguration or throttling of Azure SQL returns these, a wrong password or database name never does.
+var transientErrorNumbers = map[int32]bool{
+	64:    true, // The connection was dropped while logging in
+	233:   true, // No process is on the other end of the pipe
+	4221:  true, // Login to a read secondary failed while it was catching up
+	10053: true, // The connection was aborted by the host
+	10054: true, // The connection was reset by the peer
+	10060: true, // The connection attempt timed out
+	10928: true, // The resource limit of the database was reached
+	10929: true, // The minimum guarantee of the database was not met
+	18401: true, // The server is in script upgrade mode
+	40197: true, // The service failed to process the request, usually a failover
+	40501: true, // The service is busy
+	40613: true, // The database is not currently available
+	49918: true, // Not enough resources to process the request
+	49919: true, // Too many create or update operations in progress
+	49920: true, // Too many operations in progress
+}
+
+/*
+ * isTransientConnectionError reports whether a failed connection attempt is worth retrying: one of the
+ * `transientErrorNumbers`, a timeout or a connection reset. Login failures and other errors fail fast.
+ */
+func isTransientConnectionError(err error) bool {
+	var sqlErr mssql.Error
+	if errors.As(err, &sqlErr) {
+		return transientErrorNumbers[sqlErr.Number]
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	message := strings.ToLower(err.Error())
+	for _, transient := range []string{"timeout", "connection reset", "broken pipe", "unexpected eof"} {
+		if strings.Contains(message, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+ * connectRetryDelay returns the wait before retry number `retry`, counted from 0: 1s, 2s, 4s and so on,
+ * capped at maxConnectRetryDelay.
+ */
+func connectRetryDelay(retry int) time.Duration {
+	delay := firstConnectRetryDelay << min(retry, 5)
+	return min(delay, maxConnectRetryDelay)
+}
+
+/*
+ * pingDB pings the server once, bounded by the ping timeout when one is set.
+ *
+ * Returns:
+ * - Whether the ping ran into the ping timeout.
+ * - The error of the ping, nil when the server answered.
+ */
+func pingDB(db *sql.DB, pingTimeout time.Duration) (bool, error) {
+	ctx := context.Background()
+	if pingTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, pingTimeout)
+		defer cancel()
+	}
+	err := db.PingContext(ctx)
+	return err != nil && ctx.Err() == context.DeadlineExceeded, err
+}
@@ -0,0 +1,182 @@
+package diagnostics
+
+import (
+	"database/sql" // Database/sql package for column type information
+	"fmt"          // For formatted I/O operations
+	"strings"      // For string manipulation
+	"unicode/utf8" // For measuring cell widths in characters
+)
+
+// Narrowest a column is truncated to when a table is wider than `-console-width`
+const consoleMinColumnWidth = 3
+
+/*
+ * consoleWriter is the ResultWriter for `-format=console`. Each result is rendered to stdout as an aligned,
+ * bordered table under the result name, no file is written.
+ *
+ * Fields:
+ * - width: The maximum table width in characters, 0 for no limit.
+ * - name: The name of the current result.
+ * - columns: The column names of the current result.
+ * - rows: The cleaned cell text of the current result, held until the column widths are known.
+ */
+type consoleWriter struct {
+	width   int
+	name    string
+	columns []string
+	rows    [][]string
+}
+
+/*
+ * newConsoleWriter creates the console writer.
+ *
+ * Parameters:
+ * - opts: A `RunOptions` struct, `ConsoleWidth` limits the table width.
+ * - baseName: Unused, nothing is written to disk.
+ *
+ * Returns:
+ * - ResultWriter: The writer, ready to receive results.
+ * - error: Always nil.
+ */
+func newConsoleWriter(opts RunOptions, baseName string) (ResultWriter, error) {
+	return &consoleWriter{width: opts.ConsoleWidth}, nil
+}
+
+/*
+ * BeginResult starts buffering a result.
+ */
+func (w *consoleWriter) BeginResult(name string, query Query, columns []string, columnTypes []*sql.ColumnType) error {
+	w.name = name
+	w.columns = columns
+	w.rows = nil
+	return nil
+}
+
+/*
+ * WriteRow buffers the cleaned text of a row, as written to the Excel cells.
+ */
+func (w *consoleWriter) WriteRow(values []interface{}) error {
+	row := make([]string, len(values))
+	for i, v := range values {
+		row[i] = cleanCellValue(v)
+	}
+	w.rows = append(w.rows, row)
+	return nil
+}
+
+/*
+ * EndResult renders the buffered result as a table.
+ */
+func (w *consoleWriter) EndResult() error {
+	fmt.Print(renderConsoleTable(w.name, w.columns, w.rows, w.width))
+	w.rows = nil
+	return nil
+}
+
+/*
+ * Close has nothing to flush, every result is printed by EndResult.
+ */
+func (w *consoleWriter) Close() error {
+	return nil
+}
+
+/*
+ * consoleColumnWidths returns the width of every column, the longest of its header and cells, shrinking
+ * the widest columns until the table fits in `maxWidth` characters or every column is at its minimum.
+ */
+func consoleColumnWidths(columns []string, rows [][]string, maxWidth int) []int {
+	widths := make([]int, len(columns))
+	for i, column := range columns {
+		widths[i] = utf8.RuneCountInString(column)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if n := utf8.RuneCountInString(cell); i < len(widths) && n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	if maxWidth <= 0 {
+		return widths
+	}
+
+	// Every column takes its width plus a space on either side and one border
+	tableWidth := func() int {
+		total := 1
+		for _, width := range widths {
+			total += width + 3
+		}
+		return total
+	}
+	for tableWidth() > maxWidth {
+		widest := 0
+		for i, width := range widths {
+			if width > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= consoleMinColumnWidth {
+			break
+		}
+		widths[widest]--
+	}
+	return widths
+}
+
+/*
+ * fitConsoleCell pads the text to the width, truncating it with an ellipsis when it is longer.
+ */
+func fitConsoleCell(text string, width int) string {
+	n := utf8.RuneCountInString(text)
+	if n > width {
+		runes := []rune(text)
+		return string(runes[:width-1]) + "…"
+	}
+	return text + strings.Repeat(" ", width-n)
+}
+
+/*
+ * renderConsoleTable renders a result as a bordered table with its name as heading.
+ *
+ * Example:
+ * == 1_Version (1 row) ==
+ * +---------+------+
+ * | version | port |
+ * +---------+------+
+ * | 16.0.1  | 1433 |
+ * +---------+------+
+ */
+func renderConsoleTable(name string, columns []string, rows [][]string, maxWidth int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n== %s (%d row(s)) ==\n", name, len(rows))
+	if len(columns) == 0 {
+		return b.String()
+	}
+
+	widths := consoleColumnWidths(columns, rows, maxWidth)
+
+	border := "+"
+	for _, width := range widths {
+		border += strings.Repeat("-", width+2) + "+"
+	}
+	line := func(cells []string) {
+		b.WriteString("|")
+		for i, width := range widths {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			b.WriteString(" " + fitConsoleCell(cell, width) + " |")
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString(border + "\n")
+	line(columns)
+	b.WriteString(border + "\n")
+	for _, row := range rows {
+		line(row)
+	}
+	b.WriteString(border + "\n")
+	return b.String()
+}
@@ -0,0 +1,122 @@
+package diagnostics
+
+import (
+	"database/sql"  // Database/sql package for column type information
+	"encoding/csv"  // For writing the CSV files
+	"fmt"           // For formatted I/O operations
+	"os"            // For creating the output directory and files
+	"path/filepath" // For building the file paths
+	"time"          // For formatting date and time values
+)
+
+/*
+ * exportText returns the text of a scanned value for the CSV and JSON formats. Unlike cleanCellValue the
+ * line breaks are kept, both formats quote them, and dates are written in RFC 3339.
+ *
+ * Returns:
+ * - The text of the value, and false for NULL.
+ */
+func exportText(v interface{}) (string, bool) {
+	switch value := v.(type) {
+	case nil:
+		return "", false
+	case []byte:
+		return string(value), true
+	case string:
+		return value, true
+	case time.Time:
+		return value.Format(time.RFC3339Nano), true
+	}
+	return fmt.Sprint(v), true
+}
+
+/*
+ * csvWriter is the ResultWriter for `-format=csv`. Every result is written to its own "<name>.csv" file,
+ * named after its sheet, in a "<baseName>_csv" directory.
+ *
+ * Fields:
+ * - dir: The output directory.
+ * - file: The file of the current result.
+ * - writer: The CSV writer of the current result.
+ * - fileCount: The number of files written so far.
+ */
+type csvWriter struct {
+	dir       string
+	file      *os.File
+	writer    *csv.Writer
+	fileCount int
+}
+
+/*
+ * newCSVWriter creates the CSV writer and its output directory.
+ *
+ * Parameters:
+ * - opts: Unused, the CSV format has no options.
+ * - baseName: The timestamped prefix of the output directory.
+ *
+ * Returns:
+ * - ResultWriter: The writer, ready to receive results.
+ * - error: An error if the output directory cannot be created.
+ */
+func newCSVWriter(opts RunOptions, baseName string) (ResultWriter, error) {
+	dir := baseName + "_csv"
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %v", dir, err)
+	}
+	return &csvWriter{dir: dir}, nil
+}
+
+/*
+ * BeginResult creates the CSV file of a result and writes its header row.
+ */
+func (w *csvWriter) BeginResult(name string, query Query, columns []string, columnTypes []*sql.ColumnType) error {
+	if err := w.EndResult(); err != nil {
+		return err
+	}
+
+	fileName := filepath.Join(w.dir, name+".csv")
+	file, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", fileName, err)
+	}
+	w.file = file
+	w.writer = csv.NewWriter(file)
+	w.fileCount++
+	return w.writer.Write(columns)
+}
+
+/*
+ * WriteRow writes a row to the current CSV file, NULL values are written as empty fields.
+ */
+func (w *csvWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i], _ = exportText(v)
+	}
+	return w.writer.Write(record)
+}
+
+/*
+ * EndResult flushes and closes the CSV file of the current result.
+ */
+func (w *csvWriter) EndResult() error {
+	if w.writer == nil {
+		return nil
+	}
+	w.writer.Flush()
+	err := w.writer.Error()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.writer, w.file = nil, nil
+	return err
+}
+
+/*
+ * Close completes a result left open by a failed query and reports the output directory.
+ */
+func (w *csvWriter) Close() error {
+	err := w.EndResult()
+	logInfo("%d CSV file(s) written to %s", w.fileCount, w.dir)
+	return err
+}
@@ -0,0 +1,67 @@
+package diagnostics
+
+import (
+	"context"             // For running the query with its context
+	"database/sql"        // Database/sql package for database operations
+	"database/sql/driver" // For discarding the dedicated connection
+	"fmt"                 // For formatted I/O operations
+)
+
+/*
+ * queryRunner runs a query or starts a transaction, implemented by the pool (*sql.DB) and by a single connection
+ * taken from it (*sql.Conn).
+ */
+type queryRunner interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+/*
+ * runQueryRows runs a query with `queryRows`, on a connection of its own when the query sets `dedicatedConnection`.
+ *
+ * Parameters:
+ * - ctx: The query context.
+ * - db: The database connection pool.
+ * - opts: The run options, the parameters of the query are bound from `Params`.
+ * - query: The query run.
+ * - text: The SQL sent to the server, the query's SQL with the prefixes of the run options.
+ *
+ * Returns:
+ * - The rows of the query.
+ * - The function closing the rows, to call once the rows are read. With `dedicatedConnection` it also discards the
+ *   connection, so the session state the query leaves behind never reaches another query.
+ * - An error if no connection can be taken or the query fails.
+ *
+ * Notes:
+ * - The connection is taken from the pool like any other, the driver resets the session of a pooled connection with
+ *   sp_reset_connection before reusing it. Discarding it afterwards isolates the queries that change session state
+ *   themselves, and the ones that must not run on a connection shared with a still open result, such as some
+ *   sys.dm_os_waiting_tasks queries.
+ * - The driver does not support MultipleActiveResultSets, a query needing a second active result on its connection
+ *   needs `dedicatedConnection` instead.
+ */
+func runQueryRows(ctx context.Context, db *sql.DB, opts RunOptions, query Query, text string) (*sql.Rows, func(), error) {
+	args := queryArgs(query, opts.Params)
+	if !query.DedicatedConnection {
+		return queryRows(ctx, db, opts, text, args...)
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, func() {}, fmt.Errorf("failed to open the dedicated connection: %v", err)
+	}
+	discard := func() {
+		conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		conn.Close()
+	}
+
+	rows, release, err := queryRows(ctx, conn, opts, text, args...)
+	if err != nil {
+		discard()
+		return nil, func() {}, err
+	}
+	return rows, func() {
+		release()
+		discard()
+	}, nil
+}
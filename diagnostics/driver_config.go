@@ -0,0 +1,106 @@
+package diagnostics
+
+import (
+	"database/sql" // Database/sql package for database operations
+	"fmt"          // For formatted I/O operations
+	"net/url"      // For setting the parameters of a URL connection string
+	"strconv"      // For formatting the connection string parameters
+	"strings"      // For string manipulation
+
+	mssql "github.com/microsoft/go-mssqldb"   // For opening the database from a parsed configuration
+	"github.com/microsoft/go-mssqldb/azuread" // For the Azure AD token authentication connector
+	"github.com/microsoft/go-mssqldb/msdsn"   // For adjusting the parsed connection configuration
+)
+
+// Values of the AUTH_MODE property
+const (
+	authModeSQL     = "sql"     // SQL Server login, or integrated security with TRUSTED=true
+	authModeAzureAD = "azuread" // Azure AD (Entra ID) token of the AZURE_AD_METHOD method
+)
+
+// The connection string parameter naming the Azure AD authentication method
+const azureADParameter = "fedauth"
+
+// The Azure AD method used when AZURE_AD_METHOD is not set, it tries the environment, workload identity, managed
+// identity and `az login` credentials in turn
+const defaultAzureADMethod = azuread.ActiveDirectoryDefault
+
+// Bounds of the TDS packet size accepted by SQL Server
+const (
+	minPacketSize = 512
+	maxPacketSize = 32767
+)
+
+/*
+ * openDB opens the database for the connection string, applying the run options that the driver only takes
+ * through its connection configuration.
+ *
+ * Parameters:
+ * - connectionString: The connection string, in any format supported by the driver.
+ * - opts: A `RunOptions` struct, `PacketSize` sets the TDS packet size.
+ *
+ * Returns:
+ * - The database, or an error if the connection string cannot be parsed.
+ *
+ * Notes:
+ * - go-mssqldb has no fetch or prefetch size: SQL Server streams every row of a result in TDS packets and the
+ *   driver decodes them as `rows.Next` reads, so the number of network round trips of a tall result is set by
+ *   the packet size alone. The default is 4096 bytes, raising it to 32767 sends about 8 times fewer packets for
+ *   the same result, at the cost of larger buffers on both ends. This is an estimate from the packet sizes, it has
+ *   not been measured against a server.
+ * - The server may negotiate a smaller packet size than requested, the driver then uses the server's value.
+ * - A `packet size` parameter already in a user defined connection string is replaced by `-packet-size`.
+ * - The driver's message log is always enabled, so the informational messages of every query are collected
+ *   for the run_summary sheet and the STATISTICS TIME messages for `-statistics-time`, see `serverMessageLogger`.
+ * - A connection string with the `fedauth` parameter, built for `AUTH_MODE=azuread` or user defined, is opened
+ *   with the go-mssqldb Azure AD connector, which gets an Entra ID token for the `fedauth` method.
+ */
+func openDB(connectionString string, opts RunOptions) (*sql.DB, error) {
+	config, err := msdsn.Parse(connectionString)
+	if err != nil {
+		return nil, err
+	}
+	if opts.PacketSize > 0 {
+		config.PacketSize = uint16(min(max(opts.PacketSize, minPacketSize), maxPacketSize))
+	}
+	enableServerMessages(&config)
+
+	// The Azure AD connector parses the connection string itself, the options are passed back as parameters
+	if config.Parameters[azureADParameter] != "" {
+		if opts.PacketSize > 0 {
+			connectionString = withConnectionParameter(connectionString, msdsn.PacketSize, strconv.Itoa(int(config.PacketSize)))
+		}
+		connectionString = withConnectionParameter(connectionString, msdsn.LogParam, strconv.FormatUint(uint64(config.LogFlags), 10))
+		connector, err := azuread.NewConnector(connectionString)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Azure AD authentication: %v", err)
+		}
+		return sql.OpenDB(connector), nil
+	}
+	return sql.OpenDB(mssql.NewConnectorConfig(config)), nil
+}
+
+/*
+ * usesAzureAD reports whether the connection string authenticates with Azure AD, through its `fedauth` parameter.
+ */
+func usesAzureAD(connectionString string) bool {
+	config, err := msdsn.Parse(connectionString)
+	return err == nil && config.Parameters[azureADParameter] != ""
+}
+
+/*
+ * withConnectionParameter sets a parameter of a connection string, replacing its value when already present.
+ * A `sqlserver://` URL gets it as a query parameter, the ADO and ODBC formats as a trailing key=value pair,
+ * which the driver reads over an earlier one.
+ */
+func withConnectionParameter(connectionString string, key string, value string) string {
+	if strings.HasPrefix(connectionString, "sqlserver://") {
+		if u, err := url.Parse(connectionString); err == nil {
+			q := u.Query()
+			q.Set(key, value)
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	return strings.TrimRight(connectionString, "; ") + ";" + key + "=" + value
+}
@@ -0,0 +1,96 @@
+package diagnostics
+
+import (
+	"fmt"     // For formatted I/O operations
+	"strings" // For string manipulation
+)
+
+/*
+ * dryRun prints what a run would do for `-dry-run`, without connecting to SQL Server or writing any file.
+ *
+ * Parameters:
+ * - sqlConfigProp: The path to the SQL Server configuration properties file.
+ * - queries: The queries read and validated from the queries file.
+ * - opts: The run options selected on the command line.
+ *
+ * Returns:
+ * - An error if the configuration cannot be read or misses a required property.
+ *
+ * Functionality:
+ * 1. Reads the configuration with `readSQLConfig` and prints the masked connection string that would be used.
+ * 2. Prints the Sr.No, name, description, sheet and bound parameters of every query, and the queries left out by `-only` or `-tag`.
+ * 3. Prints the sheet name issues of `validateSheetNames` and the risky queries of `findRiskyQueries`, which a run
+ *    would ask to confirm.
+ * 4. Counts the sheets the Excel file would have: TOC, executed_queries, run_summary, one per selected query and the
+ *    report sheets enabled by the options.
+ *
+ * Notes:
+ * - Queries returning several result sets add a sheet per further result set and the hooks with
+ *   `-capture-hook-output` a sheet per result set, these are only known once the batches run.
+ */
+func dryRun(sqlConfigProp string, queries Queries, opts RunOptions) error {
+	sqlConfig, err := runSQLConfig(sqlConfigProp, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Connection string: %s\n", maskConnectionString(buildConnectionString(sqlConfig)))
+	fmt.Printf("Output formats: %s\n", strings.Join(opts.Formats, ", "))
+
+	sheets := []string{tocSheetName, executedQueriesSheetName, runSummarySheetName}
+	fmt.Printf("Queries of %s:\n", queries.QuerySource.Name)
+	for i, query := range queries.Queries {
+		if !selectedQuery(opts, query) {
+			fmt.Printf("  %3d. %s: skipped, not selected by -only or -tag\n", i+1, query.Name)
+			continue
+		}
+		sheetName := createSheetName(i+1, query.Name)
+		sheets = append(sheets, sheetName)
+
+		fmt.Printf("  %3d. %s -> sheet %s\n", i+1, query.Name, sheetName)
+		if query.Description != "" {
+			fmt.Printf("       %s\n", query.Description)
+		}
+		if names, values := queryParams(query, opts.Params); len(names) > 0 {
+			bound := make([]string, len(names))
+			for j, name := range names {
+				bound[j] = fmt.Sprintf("@%s=%v", name, values[j])
+			}
+			fmt.Printf("       Parameters: %s\n", strings.Join(bound, ", "))
+		}
+	}
+
+	for i, statement := range queries.PreRun {
+		fmt.Printf("preRun statement %d, run before the queries: %s\n", i+1, strings.TrimSpace(statement))
+	}
+	for i, statement := range queries.PostRun {
+		fmt.Printf("postRun statement %d, run after the queries: %s\n", i+1, strings.TrimSpace(statement))
+	}
+
+	for _, issue := range validateSheetNames(queries) {
+		fmt.Printf("Sheet name issue, query %d %q (sheet %s): %s\n", issue.Index, issue.Query, issue.Sheet, issue.Issue)
+	}
+	for _, r := range findRiskyQueries(queries, opts) {
+		fmt.Printf("Risky query, needs confirmation, -ack-risky or -yes: %d. %s: %s\n", r.Index, r.Name, strings.Join(r.Patterns, ", "))
+	}
+
+	reportSheets := []struct {
+		enabled bool
+		name    string
+	}{
+		{opts.CheckPermissions, permissionsSheetName},
+		{opts.Overview, overviewSheetName},
+		{opts.StrictScan, dataIssuesSheetName},
+		{opts.ExplainMissingIndex, recommendationsSheetName},
+		{opts.PlanAnalysis, planAnalysisSheetName},
+	}
+	for _, sheet := range reportSheets {
+		if sheet.enabled {
+			sheets = append(sheets, sheet.name)
+		}
+	}
+
+	fmt.Printf("Dry run: the Excel file would have %d sheet(s): %s\n", len(sheets), strings.Join(sheets, ", "))
+	fmt.Println("Queries returning several result sets add a sheet per further result set.")
+	fmt.Println("No connection was opened and no file was written.")
+	return nil
+}
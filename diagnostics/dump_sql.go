@@ -0,0 +1,88 @@
+package diagnostics
+
+import (
+	"fmt"           // For formatted I/O operations
+	"os"            // For creating the sidecar folder and files
+	"path/filepath" // For building the file paths
+	"strings"       // For string manipulation
+	"time"          // For working with date and time
+)
+
+/*
+ * dumpQueriesSQL writes the SQL of every query to its own .sql file for `-dump-sql`, so a single query can be
+ * reproduced in SSMS or sqlcmd exactly as the run executed it.
+ *
+ * Parameters:
+ * - queries: The queries of the run.
+ * - baseName: The timestamped base name of the run's output, the files go to the "<baseName>_sql" folder.
+ * - opts: The run options, the session settings they add to every query are listed in the header.
+ *
+ * Returns:
+ * - The folder written, or an error if the folder or a file cannot be created.
+ *
+ * Notes:
+ * - Each file is named after the query's sheet, "<Sr.No>_<Name>.sql", so it matches the sheet it produced.
+ * - The header comments carry the query metadata and the session settings. The SQL is written exactly as it is
+ *   sent to the server, the values of its @name parameters are bound separately and not part of the file.
+ */
+func dumpQueriesSQL(queries Queries, baseName string, opts RunOptions) (string, error) {
+	folder := baseName + "_sql"
+	if err := os.MkdirAll(folder, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %v", folder, err)
+	}
+
+	generated := time.Now().Format(time.RFC3339)
+	for i, query := range queries.Queries {
+		sheetName := createSheetName(i+1, query.Name)
+
+		var content strings.Builder
+		fmt.Fprintf(&content, "-- Sr.No: %d\n", i+1)
+		fmt.Fprintf(&content, "-- Name: %s\n", query.Name)
+		fmt.Fprintf(&content, "-- Sheet: %s\n", sheetName)
+		writeSQLCommentField(&content, "Description", query.Description)
+		writeSQLCommentField(&content, "Notes", query.Notes)
+		fmt.Fprintf(&content, "-- Generated: %s\n", generated)
+		if opts.StatisticsTime {
+			content.WriteString("-- Session: -statistics-time runs SET STATISTICS TIME ON before the query\n")
+		}
+		content.WriteString("\n")
+		content.WriteString(strings.TrimSpace(query.Query))
+		content.WriteString("\n")
+
+		fileName := filepath.Join(folder, sheetName+".sql")
+		if err := os.WriteFile(fileName, []byte(content.String()), 0o644); err != nil {
+			return "", fmt.Errorf("failed to write %s: %v", fileName, err)
+		}
+	}
+	return folder, nil
+}
+
+/*
+ * dumpSQLFiles runs dumpQueriesSQL for a run, a failure only warns as the diagnostics can still be collected.
+ */
+func dumpSQLFiles(queries Queries, baseName string, opts RunOptions) {
+	folder, err := dumpQueriesSQL(queries, baseName, opts)
+	if err != nil {
+		logError("Failed to dump the query SQL: %v", err)
+		return
+	}
+	logInfo("Query SQL written to %s", folder)
+}
+
+/*
+ * writeSQLCommentField writes a header field as "--" comment lines, one per line of a multi line value,
+ * skipping empty values.
+ */
+func writeSQLCommentField(content *strings.Builder, field string, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	for i, line := range strings.Split(value, "\n") {
+		if i == 0 {
+			fmt.Fprintf(content, "-- %s: %s\n", field, strings.TrimRight(line, "\r"))
+		} else {
+			fmt.Fprintf(content, "--   %s\n", strings.TrimRight(line, "\r"))
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package diagnostics
+
+// Name the embedded queries are reported under, the queries file they are a copy of
+const embeddedQueriesName = "sql_queries.json"
+
+/*
+ * embeddedQueries is the default query suite, sql_queries.json as it was when the binary was built, used by
+ * `readQueries` when the `-queries` file does not exist so the binary runs the standard diagnostics on its own.
+ * The command embeds the file next to its main package and hands it to `Main`, nil runs no default suite.
+ */
+var embeddedQueries []byte
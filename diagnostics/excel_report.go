@@ -0,0 +1,148 @@
+package diagnostics
+
+import (
+	"strconv" // For resolving a Sr.No to its sheet
+	"strings" // For string manipulation
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+/*
+ * excelReport is the Excel workbook being generated for a run together with what every sheet writer
+ * needs from the run: the options, the findings collected across queries and the styles already
+ * registered in the workbook.
+ *
+ * Fields:
+ * - f: The Excel file.
+ * - opts: The run options.
+ * - findings: The `ReportFindings` collected across all queries.
+ * - styles: Style IDs registered in the workbook, keyed by a name chosen by the caller, so each
+ *   style is only added to the workbook once however many sheets use it.
+ * - mirror: The writer of the other `-format` formats, fed the first result set of every query, nil when
+ *   the workbook is the only format.
+ */
+type excelReport struct {
+	f        *excelize.File
+	opts     RunOptions
+	findings *ReportFindings
+	styles   map[string]int
+	mirror   ResultWriter
+}
+
+/*
+ * newExcelReport wraps a new or opened Excel file for a run.
+ */
+func newExcelReport(f *excelize.File, opts RunOptions) *excelReport {
+	return &excelReport{
+		f:        f,
+		opts:     opts,
+		findings: &ReportFindings{Server: opts.Server},
+		styles:   make(map[string]int),
+	}
+}
+
+/*
+ * closeMirror closes the writer of the other `-format` formats once, so their files are complete and released
+ * even when the run fails before the workbook is saved.
+ */
+func (r *excelReport) closeMirror() error {
+	if r.mirror == nil {
+		return nil
+	}
+	mirror := r.mirror
+	r.mirror = nil
+	return mirror.Close()
+}
+
+/*
+ * conditionalStyle returns the ID of a conditional format style, registering it with
+ * NewConditionalStyle the first time the name is used.
+ *
+ * Parameters:
+ * - name: The cache key for the style.
+ * - style: The style definition, only used the first time.
+ *
+ * Returns:
+ * - The style ID, or an error if the style cannot be registered.
+ */
+func (r *excelReport) conditionalStyle(name string, style *excelize.Style) (int, error) {
+	if id, ok := r.styles["conditional:"+name]; ok {
+		return id, nil
+	}
+	id, err := r.f.NewConditionalStyle(style)
+	if err != nil {
+		return 0, err
+	}
+	r.styles["conditional:"+name] = id
+	return id, nil
+}
+
+/*
+ * cellStyle returns the ID of a cell style, registering it with NewStyle the first time the name is used.
+ *
+ * Parameters:
+ * - name: The cache key for the style.
+ * - style: The style definition, only used the first time.
+ *
+ * Returns:
+ * - The style ID, or an error if the style cannot be registered.
+ */
+func (r *excelReport) cellStyle(name string, style *excelize.Style) (int, error) {
+	if id, ok := r.styles["cell:"+name]; ok {
+		return id, nil
+	}
+	id, err := r.f.NewStyle(style)
+	if err != nil {
+		return 0, err
+	}
+	r.styles["cell:"+name] = id
+	return id, nil
+}
+
+/*
+ * setActiveSheet selects the sheet the workbook opens on for `-active-sheet`.
+ *
+ * Parameters:
+ * - f: A pointer to the excelize.File object representing the Excel file.
+ * - activeSheet: A sheet name, or the Sr.No of a query in the executed_queries sheet. Empty selects the default.
+ * - queries: The queries of the run, resolving a Sr.No to the query's sheet.
+ *
+ * Functionality:
+ * 1. Resolves a Sr.No to the sheet name of that query, as created by createSheetName.
+ * 2. Falls back to the executed_queries landing page when no sheet is requested or the requested one does
+ *    not exist, which is logged.
+ * 3. Makes the sheet active, SetActiveSheet also makes it the only selected tab.
+ */
+func setActiveSheet(f *excelize.File, activeSheet string, queries Queries) {
+	name := strings.TrimSpace(activeSheet)
+	if n, err := strconv.Atoi(name); err == nil {
+		if n < 1 || n > len(queries.Queries) {
+			logWarn("-active-sheet %d is not the Sr.No of a query, there are %d queries", n, len(queries.Queries))
+			name = ""
+		} else {
+			name = createSheetName(n, queries.Queries[n-1].Name)
+		}
+	}
+
+	index := -1
+	if name != "" {
+		if index, _ = f.GetSheetIndex(name); index == -1 {
+			logWarn("-active-sheet %s does not exist in the workbook, opening on the default sheet", name)
+		}
+	}
+	if index == -1 {
+		index, _ = f.GetSheetIndex(executedQueriesSheetName)
+	}
+	if index == -1 {
+		return
+	}
+
+	f.SetActiveSheet(index)
+}
+
+/*
+ * boolPtr returns a pointer to the value, for the optional fields of the excelize options.
+ */
+func boolPtr(value bool) *bool {
+	return &value
+}
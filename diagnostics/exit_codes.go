@@ -0,0 +1,57 @@
+package diagnostics
+
+import (
+	"errors" // For recognizing a failed connection behind the run error
+)
+
+// Exit codes of the program, a scheduled or multi-server run exits with the highest code of its runs
+const (
+	exitSuccess          = 0 // Every selected query ran
+	exitQueriesFailed    = 1 // At least one query failed or timed out, also the code of log.Fatalf
+	exitConnectionFailed = 2 // The database could not be reached, no query ran
+)
+
+/*
+ * connectionFailedError is the error of a run whose database could not be reached, returned by
+ * `connectionPool.connect` so the run exits with `exitConnectionFailed`.
+ */
+type connectionFailedError struct {
+	err error
+}
+
+// Error returns the message of the connection failure
+func (e connectionFailedError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the connection failure
+func (e connectionFailedError) Unwrap() error {
+	return e.err
+}
+
+/*
+ * runExitCode returns the exit code of one run of the queries.
+ *
+ * Parameters:
+ * - findings: The findings of the run, counting its failed queries, nil when the run did not start.
+ * - err: The error that kept the run from starting, nil when it ran.
+ *
+ * Returns:
+ * - exitConnectionFailed when the database could not be reached, exitQueriesFailed when the configuration or the
+ *   queries could not be read or a query failed, exitSuccess otherwise.
+ *
+ * Notes:
+ * - A query cancelled by Ctrl-C or SIGTERM counts as failed, an interrupted run is never a full success.
+ */
+func runExitCode(findings *ReportFindings, err error) int {
+	var connectionFailed connectionFailedError
+	switch {
+	case errors.As(err, &connectionFailed):
+		return exitConnectionFailed
+	case err != nil:
+		return exitQueriesFailed
+	case findings != nil && findings.FailedQueries > 0:
+		return exitQueriesFailed
+	}
+	return exitSuccess
+}
@@ -0,0 +1,176 @@
+package diagnostics
+
+import (
+	"database/sql"        // For opening the fake driver
+	"database/sql/driver" // For implementing the fake driver
+	"fmt"                 // For naming the canned results
+	"io"                  // For ending the rows of a result set
+	"sync"                // For the registry of canned results
+	"sync/atomic"         // For the unique keys of the canned results
+	"testing"             // For the test framework
+)
+
+/*
+ * fakeResultSet is a result set returned by the fake driver, standing in for a SQL Server result.
+ *
+ * Fields:
+ * - columns: The column names.
+ * - types: The SQL Server type name of every column, such as "INT" or "DECIMAL", reported by DatabaseTypeName.
+ * - scales: The precision and scale of the DECIMAL columns, keyed by column index, reported by DecimalSize.
+ * - rows: The values of every row, of the types go-mssqldb returns: int64, float64, bool, time.Time, []byte
+ *   for DECIMAL and MONEY, string for text.
+ */
+type fakeResultSet struct {
+	columns []string
+	types   []string
+	scales  map[int][2]int64
+	rows    [][]driver.Value
+}
+
+// Canned results of the fake driver keyed by the query text, and the counter making every key unique
+var (
+	fakeResults   sync.Map
+	fakeResultKey atomic.Int64
+)
+
+func init() {
+	sql.Register("diagfake", fakeDriver{})
+}
+
+/*
+ * openFakeDB opens a database of the fake driver answering the returned query text with the given result sets, for
+ * the tests running a Query.
+ */
+func openFakeDB(t *testing.T, sets ...fakeResultSet) (*sql.DB, string) {
+	t.Helper()
+	key := fmt.Sprintf("fake result %d", fakeResultKey.Add(1))
+	fakeResults.Store(key, sets)
+
+	db, err := sql.Open("diagfake", "")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, key
+}
+
+/*
+ * queryFakeRows returns *sql.Rows over the given result sets, as a batch of several SELECT statements returns
+ * them, so the tests run the code reading the driver without a SQL Server.
+ */
+func queryFakeRows(t *testing.T, sets ...fakeResultSet) *sql.Rows {
+	t.Helper()
+	db, key := openFakeDB(t, sets...)
+	rows, err := db.Query(key)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	t.Cleanup(func() { rows.Close() })
+	return rows
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query: query}, nil }
+
+func (fakeConn) Close() error { return nil }
+
+func (fakeConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("the fake driver has no transactions")
+}
+
+type fakeStmt struct {
+	query string
+}
+
+func (s fakeStmt) Close() error { return nil }
+
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("the fake driver only runs queries")
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	sets, ok := fakeResults.Load(s.query)
+	if !ok {
+		return nil, fmt.Errorf("no canned result for %q", s.query)
+	}
+	return &fakeDriverRows{sets: sets.([]fakeResultSet)}, nil
+}
+
+/*
+ * fakeDriverRows hands out the canned result sets, implementing the optional driver interfaces for the further
+ * result sets and the column types.
+ */
+type fakeDriverRows struct {
+	sets []fakeResultSet
+	set  int
+	row  int
+}
+
+func (r *fakeDriverRows) Columns() []string { return r.sets[r.set].columns }
+
+func (r *fakeDriverRows) Close() error { return nil }
+
+func (r *fakeDriverRows) Next(dest []driver.Value) error {
+	set := r.sets[r.set]
+	if r.row >= len(set.rows) {
+		return io.EOF
+	}
+	copy(dest, set.rows[r.row])
+	r.row++
+	return nil
+}
+
+func (r *fakeDriverRows) HasNextResultSet() bool { return r.set+1 < len(r.sets) }
+
+func (r *fakeDriverRows) NextResultSet() error {
+	if !r.HasNextResultSet() {
+		return io.EOF
+	}
+	r.set, r.row = r.set+1, 0
+	return nil
+}
+
+func (r *fakeDriverRows) ColumnTypeDatabaseTypeName(index int) string {
+	if types := r.sets[r.set].types; index < len(types) {
+		return types[index]
+	}
+	return "NVARCHAR"
+}
+
+func (r *fakeDriverRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	scale, ok := r.sets[r.set].scales[index]
+	return scale[0], scale[1], ok
+}
+
+/*
+ * recordingWriter is a ResultWriter keeping the headers and the rows of every result, for the writer tests.
+ */
+type recordingWriter struct {
+	headers map[string][]string
+	rows    map[string][][]interface{}
+	current string
+}
+
+func (w *recordingWriter) BeginResult(name string, query Query, columns []string, columnTypes []*sql.ColumnType) error {
+	if w.headers == nil {
+		w.headers, w.rows = map[string][]string{}, map[string][][]interface{}{}
+	}
+	w.current, w.headers[name] = name, columns
+	return nil
+}
+
+func (w *recordingWriter) WriteRow(values []interface{}) error {
+	w.rows[w.current] = append(w.rows[w.current], append([]interface{}(nil), values...))
+	return nil
+}
+
+func (w *recordingWriter) EndResult() error { return nil }
+
+func (w *recordingWriter) Close() error { return nil }
@@ -0,0 +1,100 @@
+package diagnostics
+
+import (
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+/*
+ * formatHintNumberFormats maps the format hints accepted in a query's `formatHints` to Excel number formats.
+ * The formats only change how a value is displayed, the cell keeps the raw value returned by the query.
+ *
+ * - percent: A percentage between 0 and 100, shown as 12.3%.
+ * - fraction: A ratio between 0 and 1, shown as a percentage, 0.123 as 12.3%.
+ * - us: Microseconds, shown in milliseconds, 1500 as 1.500 ms.
+ * - ms: Milliseconds, shown as 1,500 ms.
+ * - seconds: Seconds, shown as 12.5 s.
+ * - bytes: Bytes, shown in megabytes of 1,000,000 bytes, 1500000 as 1.5 MB.
+ *
+ * Notes:
+ * - The trailing commas in the us and bytes formats scale the displayed value by 1,000 per comma.
+ */
+var formatHintNumberFormats = map[string]string{
+	"percent":  `0.0"%"`,
+	"fraction": `0.0%`,
+	"us":       `#,##0.000," ms"`,
+	"ms":       `#,##0" ms"`,
+	"seconds":  `#,##0.0" s"`,
+	"bytes":    `#,##0.0,," MB"`,
+}
+
+/*
+ * columnFormatHints resolves the query's format hints to the position of each column.
+ *
+ * Parameters:
+ * - columns: The column names returned by the query.
+ * - query: The `Query` holding the `formatHints` keyed by column name.
+ *
+ * Returns:
+ * - The hint of every column, empty for columns without one, or nil when the query defines no hints.
+ *
+ * Notes:
+ * - Unknown hints and hints for columns missing from the result are logged and ignored.
+ */
+func columnFormatHints(columns []string, query Query) []string {
+	if len(query.FormatHints) == 0 {
+		return nil
+	}
+
+	hints := make([]string, len(columns))
+	found := make(map[string]bool, len(columns))
+	for i, column := range columns {
+		if hint, ok := query.FormatHints[column]; ok {
+			if _, known := formatHintNumberFormats[hint]; known {
+				hints[i] = hint
+			} else {
+				logWarn("Query %s uses the unknown format hint %q for %q, supported hints are percent, fraction, us, ms, seconds and bytes", query.Name, hint, column)
+			}
+		}
+		found[column] = true
+	}
+
+	for column := range query.FormatHints {
+		if !found[column] {
+			logWarn("Query %s defines a format hint for %q, which is not a column of its result", query.Name, column)
+		}
+	}
+
+	return hints
+}
+
+/*
+ * applyFormatHints sets the number format of every hinted column over the data rows of the sheet.
+ *
+ * Parameters:
+ * - report: The `excelReport` caching the number format styles.
+ * - sheetName: The sheet to format.
+ * - hints: The hint of every result column, as returned by columnFormatHints.
+ * - firstColumn: The sheet column of the first result column.
+ * - lastRow: The last data row of the sheet, the data rows start at `resultFirstDataRow`.
+ */
+func applyFormatHints(report *excelReport, sheetName string, hints []string, firstColumn int, lastRow int) {
+	if lastRow < resultFirstDataRow {
+		return
+	}
+	for i, hint := range hints {
+		if hint == "" {
+			continue
+		}
+		numberFormat := formatHintNumberFormats[hint]
+		styleID, err := report.cellStyle("format_hint:"+hint, &excelize.Style{CustomNumFmt: &numberFormat})
+		if err != nil {
+			logError("Failed to create the %s number format: %v", hint, err)
+			continue
+		}
+		top, _ := excelize.CoordinatesToCellName(firstColumn+i, resultFirstDataRow)
+		bottom, _ := excelize.CoordinatesToCellName(firstColumn+i, lastRow)
+		if err := report.f.SetCellStyle(sheetName, top, bottom, styleID); err != nil {
+			logError("Failed to format column %d of sheet %s: %v", firstColumn+i, sheetName, err)
+		}
+	}
+}
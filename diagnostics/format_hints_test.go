@@ -0,0 +1,82 @@
+package diagnostics
+
+import (
+	"strings" // For comparing the hints
+	"testing" // For the test framework
+
+	"github.com/xuri/excelize/v2" // For reading back the number formats of the cells
+)
+
+/*
+ * TestColumnFormatHints checks the hints are resolved to the position of their columns, with unknown hints and
+ * hints of missing columns ignored.
+ */
+func TestColumnFormatHints(t *testing.T) {
+	columns := []string{"database_name", "size_bytes", "pct_used", "avg_wait_us"}
+	tests := []struct {
+		name  string
+		hints map[string]string
+		want  []string
+	}{
+		{name: "no hints", hints: nil, want: nil},
+		{name: "hinted columns", hints: map[string]string{"size_bytes": "bytes", "pct_used": "percent", "avg_wait_us": "us"}, want: []string{"", "bytes", "percent", "us"}},
+		{name: "unknown hint", hints: map[string]string{"size_bytes": "megabytes", "pct_used": "fraction"}, want: []string{"", "", "fraction", ""}},
+		{name: "hint of a missing column", hints: map[string]string{"log_bytes": "bytes"}, want: []string{"", "", "", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := columnFormatHints(columns, Query{Name: "Database Sizes", FormatHints: tt.hints})
+			if (got == nil) != (tt.want == nil) || strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("columnFormatHints = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+/*
+ * TestApplyFormatHints formats the data rows of a sheet and checks every hinted column gets the number format of
+ * its hint from the first to the last data row, while the header row and the other columns keep theirs.
+ */
+func TestApplyFormatHints(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+	report := newExcelReport(f, RunOptions{})
+	const lastRow = resultFirstDataRow + 2
+	applyFormatHints(report, "Sheet1", []string{"", "bytes", "percent"}, 2, lastRow)
+
+	numberFormat := func(column, row int) string {
+		cell, _ := excelize.CoordinatesToCellName(column, row)
+		styleID, err := f.GetCellStyle("Sheet1", cell)
+		if err != nil {
+			t.Fatalf("GetCellStyle(%s): %v", cell, err)
+		}
+		style, err := f.GetStyle(styleID)
+		if err != nil {
+			t.Fatalf("GetStyle(%d): %v", styleID, err)
+		}
+		if style.CustomNumFmt == nil {
+			return ""
+		}
+		return *style.CustomNumFmt
+	}
+
+	tests := []struct {
+		name   string
+		column int
+		row    int
+		want   string
+	}{
+		{name: "bytes on the first data row", column: 3, row: resultFirstDataRow, want: formatHintNumberFormats["bytes"]},
+		{name: "percent on the last data row", column: 4, row: lastRow, want: formatHintNumberFormats["percent"]},
+		{name: "column without a hint", column: 2, row: resultFirstDataRow, want: ""},
+		{name: "header row", column: 3, row: resultFirstDataRow - 1, want: ""},
+		{name: "below the data", column: 3, row: lastRow + 1, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := numberFormat(tt.column, tt.row); got != tt.want {
+				t.Errorf("number format of column %d row %d = %q, want %q", tt.column, tt.row, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package diagnostics
+
+import (
+	"context"      // For cancelling the queries of an interrupted run
+	"database/sql" // For the database connection
+	"fmt"          // For formatted I/O operations
+	"time"         // For the query durations
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+/*
+ * GenerateDiagnostics runs the diagnostic queries against a server and returns the completed workbook without
+ * saving it, for callers embedding the diagnostics and deciding themselves where the report goes.
+ *
+ * Parameters:
+ * - ctx: Cancels the running query and stops the run before the next query.
+ * - cfg: The SQL Server connection configuration, as read by `readSQLConfig`.
+ * - queries: The queries to run, as read by `readQueries`.
+ * - opts: The run options, `ResumeWorkbook`, `SaveEvery`, `DumpSQL` and the formats other than Excel are not used.
+ *
+ * Returns:
+ * - The workbook with every sheet the CLI writes, opened on `ActiveSheet`, for the caller to save or stream.
+ * - The `RunSummary` of the run: the outcome of every query, the failed query count and `FirstError`, with the
+ *   `ReportFindings` holding the estimated plans of the queries with `CapturePlan`, which are not written to files.
+ * - An error when the server cannot be reached, does not run the declared version with `StrictVersion`, the login
+ *   misses a permission with `RequirePermissions` or a setup hook fails, no workbook is returned then. The function
+ *   never exits the process, the caller decides what a failed run means.
+ *
+ * Functionality:
+ * 1. Opens its own connection to the server, closed when the function returns.
+ * 2. Checks the server version against the `QuerySource` of the queries, see `checkServerVersion`.
+ * 3. Runs the queries and writes their sheets and the report sheets, see `generateWorkbook`.
+ *
+ * Notes:
+ * - A failing query is recorded in the summary and the run goes on, it does not make the function fail.
+ * - The function takes the `RunOptions` besides the configuration and the queries, the zero value runs the queries
+ *   as the CLI does without any flag.
+ */
+func GenerateDiagnostics(ctx context.Context, cfg SQLServerConfig, queries Queries, opts RunOptions) (*excelize.File, RunSummary, error) {
+	pool := &connectionPool{}
+	defer pool.close()
+
+	db, err := pool.connect(cfg, opts)
+	if err != nil {
+		return nil, RunSummary{}, err
+	}
+	opts.Server = connectionRunTarget(buildConnectionString(cfg))
+	opts.Redaction = cfg.Redaction
+
+	return generateDiagnostics(ctx, db, queries, opts)
+}
+
+/*
+ * generateDiagnostics is `GenerateDiagnostics` on an open connection: it checks the server version, runs the
+ * queries into a new workbook and summarizes the run.
+ */
+func generateDiagnostics(ctx context.Context, db *sql.DB, queries Queries, opts RunOptions) (*excelize.File, RunSummary, error) {
+	if err := checkServerVersion(db, queries.QuerySource, opts); err != nil {
+		return nil, RunSummary{}, err
+	}
+
+	report := newExcelReport(excelize.NewFile(), opts)
+	if err := generateWorkbook(ctx, db, queries, report, nil, nil); err != nil {
+		report.f.Close()
+		return nil, RunSummary{}, err
+	}
+	return report.f, newRunSummary(report.findings), nil
+}
+
+/*
+ * RunSummary is what a `GenerateDiagnostics` run produced, for the programs importing the package.
+ *
+ * Fields:
+ * - Queries: The outcome of every query run, in query order, the queries left out by `Only` and `Tags` are not listed.
+ * - Succeeded: The number of queries that ran and wrote their result.
+ * - FailedQueries: The number of queries that failed or timed out.
+ * - FirstError: The failure that stopped the run with `StopOnFirstError`, nil otherwise.
+ * - Findings: Everything the run collected, the data issues, the missing indexes and the estimated plans of the
+ *   queries with `CapturePlan` included.
+ */
+type RunSummary struct {
+	Queries       []QuerySummary  // Outcome of every query run, in order
+	Succeeded     int             // Queries that ran successfully
+	FailedQueries int             // Queries that failed or timed out
+	FirstError    error           // Failure that stopped the run with StopOnFirstError
+	Findings      *ReportFindings // Everything the run collected
+}
+
+/*
+ * QuerySummary is the outcome of one query of a `RunSummary`.
+ *
+ * Fields:
+ * - Name: The name of the query.
+ * - Sheet: The result sheet of the query, further result sets are on the sheets suffixed "_2", "_3", ...
+ * - Status: "Success", "Failed" or "Timeout".
+ * - Rows: The number of rows written to the result sheet.
+ * - Duration: How long the query took, from execution to the last row written.
+ * - Error: The error of a failed query, empty when it succeeded.
+ * - Truncated: Whether a result sheet of the query was cut by `MaxRows`.
+ * - Alerts: The number of rows meeting the query's `alert` rule.
+ */
+type QuerySummary struct {
+	Name      string
+	Sheet     string
+	Status    string
+	Rows      int
+	Duration  time.Duration
+	Error     string
+	Truncated bool
+	Alerts    int
+}
+
+/*
+ * newRunSummary summarizes the findings of a run for `GenerateDiagnostics`.
+ */
+func newRunSummary(findings *ReportFindings) RunSummary {
+	summary := RunSummary{FailedQueries: findings.FailedQueries, FirstError: findings.FirstError, Findings: findings}
+	for _, outcome := range findings.Outcomes {
+		summary.Queries = append(summary.Queries, QuerySummary{
+			Name:      outcome.Query.Name,
+			Sheet:     outcome.Sheet,
+			Status:    outcome.Status,
+			Rows:      outcome.Rows,
+			Duration:  outcome.Duration,
+			Error:     outcome.Error,
+			Truncated: outcome.Truncated,
+			Alerts:    outcome.Alerts,
+		})
+		if outcome.Status == statusSuccess {
+			summary.Succeeded++
+		}
+	}
+	return summary
+}
+
+/*
+ * generateWorkbook runs the queries and writes every sheet of the report into the workbook of `report`, the
+ * work shared by `GenerateDiagnostics` and `executeSQLQueriesAndCreateExcel`.
+ *
+ * Parameters:
+ * - ctx: Cancels the running query and stops the loop before the next query.
+ * - db: The connection to the server.
+ * - queries: The queries to run.
+ * - report: The workbook, run options and findings of the run, with the writer mirroring the other formats.
+ * - kept: The queries whose results the resumed workbook already holds, see `resumeWorkbook`, nil for none.
+ * - saveProgress: Called with the number of queries done every `SaveEvery` queries, nil to never save midway.
+ *
+ * Returns:
+ * - An error when a permission is missing with `RequirePermissions`, the hook connection cannot be prepared or the
+ *   `-pre-sql` file or the preRun statements fail, the queries are not run then. Failing queries are recorded in the findings, see `ReportFindings.FirstError`.
+ *
+ * Functionality:
+ * 1. Creates the executed_queries, run_summary, permissions, overview and data_issues sheets in that order.
+ * 2. Runs the setup hooks, then every selected query, ahead of time with `Parallel` above 1.
+ * 3. Runs the teardown hooks and writes the report sheets, the landing page and the TOC, see
+ *    `executeSQLQueriesAndCreateExcel` for the details of each step.
+ */
+func generateWorkbook(ctx context.Context, db *sql.DB, queries Queries, report *excelReport, kept map[string]keptQuery, saveProgress func(done int)) error {
+	f, opts, findings := report.f, report.opts, report.findings
+
+	// Create the executed_queries sheet first
+	writeExecutedQueriesSheet(f, queries, opts, false)
+	if opts.StatisticsTime {
+		writeQueryTimingHeaders(f)
+	}
+
+	// The run_summary sheet follows executed_queries, its rows are written once the queries ran
+	f.NewSheet(runSummarySheetName)
+
+	// Check the login's permissions before any query, the sheet sits right after executed_queries
+	if opts.CheckPermissions {
+		if err := preflightPermissions(db, f, opts); err != nil {
+			return err
+		}
+	}
+
+	// Create the overview and data_issues sheets up front so they sit right after executed_queries
+	if opts.Overview {
+		f.NewSheet(overviewSheetName)
+	}
+	if opts.StrictScan {
+		f.NewSheet(dataIssuesSheetName)
+	}
+
+	// Run the setup hook on its dedicated connection, a failing setup aborts the run
+	hookConn, err := openHookConnection(db, opts, queries)
+	if err != nil {
+		return fmt.Errorf("failed to prepare the SQL hooks: %v", err)
+	}
+	if hookConn != nil {
+		defer hookConn.Close()
+	}
+	if opts.PreSQL != "" {
+		if err := runSQLHook(hookConn, opts.PreSQL, "pre_sql", report); err != nil {
+			return fmt.Errorf("the -pre-sql setup failed: %v", err)
+		}
+	}
+	if err := runStatementHooks(hookConn, queries.PreRun, "preRun"); err != nil {
+		return fmt.Errorf("the preRun statements of the queries file failed: %v", err)
+	}
+
+	// With -parallel the workers run the queries ahead, the loop below writes their results in order
+	var prefetched []chan prefetchedQuery
+	stopPrefetch := func() {}
+	if opts.Parallel > 1 {
+		prefetched, stopPrefetch = prefetchQueries(ctx, db, queries, opts, kept)
+	}
+
+	// Execute each query and create a sheet for each result
+	for i, query := range queries.Queries {
+		if ctx.Err() != nil {
+			logWarn("Interrupted, saving the results written so far.")
+			break
+		}
+		if !selectedQuery(opts, query) {
+			logInfo("Skipping Query: %s, not selected by -only or -tag", query.Name)
+			continue
+		}
+
+		sheetName := createSheetName(i+1, query.Name)
+		if keptResult, ok := kept[sheetName]; ok {
+			logInfo("Keeping Query: %s, its results are already in %s", query.Name, opts.ResumeWorkbook)
+			outcome := findings.addOutcome(query, sheetName)
+			outcome.Status, outcome.Rows, outcome.Duration = statusSuccess, keptResult.Rows, keptResult.Duration
+			outcome.Messages = []string{"Kept from the resumed workbook, not run again"}
+			continue
+		}
+
+		logInfo("Executing Query: %s", query.Name)
+		logDebug("Description: %s", query.Description)
+		logDebug("Query: %s", query.Query)
+
+		outcome := findings.addOutcome(query, sheetName)
+
+		var err error
+		var duration time.Duration
+		var timing *queryTiming
+		var messages *queryMessages
+		if prefetched != nil {
+			// Wait for the worker running the query, then write its results
+			fetched := <-prefetched[i]
+			timing, messages = fetched.timing, fetched.messages
+			duration, err = writePrefetchedQuery(fetched, query, report, sheetName)
+			fetched.releaseBuffer()
+		} else {
+			// Back off while the server is busy
+			if opts.LoadGuard {
+				waitForServerLoad(db, opts)
+			}
+
+			// Execute query and write directly to Excel sheet, collecting the messages the server sends
+			var messageCtx, queryCtx context.Context
+			var cancel context.CancelFunc
+			messageCtx, messages = withQueryMessages(ctx)
+			queryCtx, cancel, timing = queryContext(messageCtx, opts, query.Name)
+			started := time.Now()
+			err = queryTimeoutError(queryCtx, opts, executeQueryToExcel(queryCtx, db, query, report, sheetName))
+			cancel()
+			duration = time.Since(started)
+		}
+		outcome.finish(duration, err)
+		outcome.Messages = messages.list()
+		for _, message := range outcome.Messages {
+			logInfo("Query %s message: %s", query.Name, message)
+		}
+		if timing != nil {
+			timing.Duration = duration
+			writeQueryTiming(f, i, timing)
+			logInfo("Query %s: %s", query.Name, timing)
+		}
+		if err != nil {
+			logError("Failed to execute query %s: %v", query.Name, err)
+			findings.FailedQueries++
+			if opts.StopOnFirstError && ctx.Err() == nil {
+				findings.FirstError = fmt.Errorf("query %d %s failed: %v\nQuery: %s", i+1, query.Name, err, query.Query)
+				break
+			}
+			continue
+		}
+		logInfo("Finished Query: %s in %s", query.Name, duration.Round(time.Millisecond))
+
+		// Compile the query again under SHOWPLAN_XML for its estimated plans, a failure does not fail the query
+		if query.CapturePlan {
+			plans, err := captureEstimatedPlan(ctx, db, opts, query, sheetName)
+			if err != nil {
+				logWarn("Failed to capture the plan of query %s: %v", query.Name, err)
+				outcome.Messages = append(outcome.Messages, fmt.Sprintf("The estimated plan could not be captured: %v", err))
+			} else {
+				findings.CapturedPlans = append(findings.CapturedPlans, plans...)
+				outcome.Messages = append(outcome.Messages, fmt.Sprintf("%d estimated plan(s) captured", len(plans)))
+			}
+		}
+
+		// Periodically save the results written so far, so a crash loses at most the last chunk of queries
+		if saveProgress != nil && opts.SaveEvery > 0 && (i+1)%opts.SaveEvery == 0 && i+1 < len(queries.Queries) {
+			saveProgress(i + 1)
+		}
+	}
+
+	// Stop the workers still running queries ahead of a -stop-on-first-error failure or an interruption
+	stopPrefetch()
+
+	// Run the teardown hook, a failing teardown only warns so the results are still saved
+	if err := runStatementHooks(hookConn, queries.PostRun, "postRun"); err != nil {
+		logWarn("The postRun statements of the queries file failed: %v", err)
+	}
+	if opts.PostSQL != "" {
+		if err := runSQLHook(hookConn, opts.PostSQL, "post_sql", report); err != nil {
+			logWarn("The -post-sql teardown failed: %v", err)
+		}
+	}
+
+	if opts.StrictScan {
+		writeDataIssuesSheet(f, findings.DataIssues)
+		logInfo("Strict scan found %d data issue(s).", len(findings.DataIssues))
+	}
+
+	if opts.ExplainMissingIndex {
+		writeRecommendationsSheet(f, findings.MissingIndexes)
+	}
+
+	if opts.Overview {
+		writeOverviewSheet(f, findings.Outcomes)
+	}
+
+	if opts.PlanAnalysis {
+		writePlanAnalysisSheet(report, findings.PlanOperators)
+		logInfo("Plan analysis captured %d operator(s).", len(findings.PlanOperators))
+	}
+
+	// Complete the executed_queries landing page and open the workbook on it, or on the requested sheet
+	writeRunSummarySheet(f, queries, findings, opts)
+	writeQueryOutcomes(report, queries)
+	writeTOCSheet(f, queries, findings)
+	setActiveSheet(f, opts.ActiveSheet, queries)
+
+	return nil
+}
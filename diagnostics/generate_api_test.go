@@ -0,0 +1,35 @@
+package diagnostics_test
+
+import (
+	"context" // For running the diagnostics
+	"testing" // For the test framework
+	"time"    // For the ping timeout
+
+	"malcolmpereira/getSQLServerDiagnostics/diagnostics" // The package as other modules import it
+)
+
+/*
+ * TestGenerateDiagnosticsUnreachableServer calls GenerateDiagnostics as an importing program does, and checks a
+ * server that cannot be reached returns an error and no workbook instead of exiting the process.
+ */
+func TestGenerateDiagnosticsUnreachableServer(t *testing.T) {
+	cfg := diagnostics.SQLServerConfig{
+		SQLServerHost:     "127.0.0.1",
+		SQLServerPort:     "1",
+		SQLServerDB:       "master",
+		SQLServerUser:     "diagnostics",
+		SQLServerPassword: "not-used",
+	}
+	queries := diagnostics.Queries{Queries: []diagnostics.Query{{Name: "Version", Query: "SELECT @@VERSION"}}}
+
+	f, summary, err := diagnostics.GenerateDiagnostics(context.Background(), cfg, queries, diagnostics.RunOptions{PingTimeout: 5 * time.Second})
+	if err == nil {
+		t.Fatalf("GenerateDiagnostics connected to a closed port")
+	}
+	if f != nil {
+		t.Errorf("GenerateDiagnostics returned a workbook with the error %v", err)
+	}
+	if len(summary.Queries) != 0 || summary.Findings != nil {
+		t.Errorf("GenerateDiagnostics returned the summary %+v with the error %v", summary, err)
+	}
+}
@@ -0,0 +1,52 @@
+package diagnostics
+
+import (
+	"context"             // For running the queries
+	"database/sql/driver" // For the values of the fake result
+	"strings"             // For checking the error of the failed query
+	"testing"             // For the test framework
+)
+
+/*
+ * TestGenerateDiagnosticsWorkbook runs a query that succeeds and one that fails on the fake driver and checks the
+ * workbook holds the result sheet and the report sheets, and the summary counts and describes both queries.
+ */
+func TestGenerateDiagnosticsWorkbook(t *testing.T) {
+	db, text := openFakeDB(t, fakeResultSet{
+		columns: []string{"wait_type", "wait_ms"},
+		types:   []string{"NVARCHAR", "BIGINT"},
+		rows:    [][]driver.Value{{"CXPACKET", int64(420)}, {"LCK_M_X", int64(12)}},
+	})
+	queries := Queries{Queries: []Query{
+		{Name: "Wait Stats", Query: text},
+		{Name: "Missing", Query: "SELECT missing"},
+	}}
+
+	f, summary, err := generateDiagnostics(context.Background(), db, queries, RunOptions{})
+	if err != nil {
+		t.Fatalf("generateDiagnostics: %v", err)
+	}
+	defer f.Close()
+
+	for _, sheet := range []string{executedQueriesSheetName, runSummarySheetName, tocSheetName, "1_Wait_Stats"} {
+		if idx, _ := f.GetSheetIndex(sheet); idx == -1 {
+			t.Errorf("the workbook has no %s sheet", sheet)
+		}
+	}
+	if value, _ := f.GetCellValue("1_Wait_Stats", "A5"); value != "CXPACKET" {
+		t.Errorf("first result cell = %q, want CXPACKET", value)
+	}
+
+	if summary.Succeeded != 1 || summary.FailedQueries != 1 || len(summary.Queries) != 2 {
+		t.Fatalf("summary = %+v, want one succeeded and one failed query", summary)
+	}
+	if got := summary.Queries[0]; got.Name != "Wait Stats" || got.Sheet != "1_Wait_Stats" || got.Status != statusSuccess || got.Rows != 2 {
+		t.Errorf("first query summary = %+v, want Wait Stats on 1_Wait_Stats with 2 rows", got)
+	}
+	if got := summary.Queries[1]; got.Status != statusFailed || !strings.Contains(got.Error, "no canned result") {
+		t.Errorf("second query summary = %+v, want it failed", got)
+	}
+	if summary.Findings == nil || len(summary.Findings.Outcomes) != 2 {
+		t.Errorf("summary findings = %+v, want the outcomes of both queries", summary.Findings)
+	}
+}
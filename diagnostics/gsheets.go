@@ -0,0 +1,435 @@
+package diagnostics
+
+import (
+	"bytes"           // For building HTTP request bodies
+	"crypto"          // For the SHA-256 hash identifier used when signing
+	"crypto/rand"     // For the RSA signature
+	"crypto/rsa"      // For signing the service account assertion
+	"crypto/sha256"   // For hashing the service account assertion
+	"crypto/x509"     // For parsing the service account private key
+	"database/sql"    // Database/sql package for column type information
+	"encoding/base64" // For encoding the service account assertion
+	"encoding/json"   // For parsing and encoding JSON data
+	"encoding/pem"    // For decoding the service account private key
+	"fmt"             // For formatted I/O operations
+	"io"              // For reading HTTP responses
+	"net/http"        // For calling the Google OAuth and Sheets APIs
+	"net/url"         // For encoding form values and path segments
+	"os"              // For reading the credentials file
+	"strings"         // For string manipulation
+	"time"            // For working with date and time
+)
+
+// Google Sheets API endpoints and scope
+const gsheetsAPI = "https://sheets.googleapis.com/v4/spreadsheets/"
+const gsheetsScope = "https://www.googleapis.com/auth/spreadsheets"
+
+// Maximum number of cells sent in a single appendCells request, keeps every request well under the API payload limits
+const gsheetsMaxCellsPerRequest = 10000
+
+// Time before its expiry an access token is replaced, so a request never carries a token expiring in flight
+const gsheetsTokenExpiryMargin = time.Minute
+
+/*
+ * googleServiceAccount holds the fields of a Google service account key file needed to request an access token.
+ */
+type googleServiceAccount struct {
+	ClientEmail string `json:"client_email"` // Service account email, the issuer of the assertion
+	PrivateKey  string `json:"private_key"`  // PEM encoded RSA private key
+	TokenURI    string `json:"token_uri"`    // OAuth token endpoint
+}
+
+/*
+ * googleSheetsWriter is the ResultWriter for `-format=gsheets`. It writes each result to its own tab
+ * of an existing Google Sheet, identified by `-gsheets-id`, using a service account credential.
+ *
+ * Fields:
+ * - client: The HTTP client used for all API calls.
+ * - apiURL: The Sheets API endpoint, gsheetsAPI.
+ * - spreadsheetID: The ID of the target Google Sheet.
+ * - credentialsFile: The service account key file, read again for every new access token.
+ * - token: The OAuth access token for the service account.
+ * - tokenExpiry: When `token` is replaced, shortly before it expires.
+ * - sheetIDs: The numeric sheet IDs of the existing tabs keyed by title.
+ * - currentID: The sheet ID of the tab receiving the current result.
+ * - columnTypes: The column types of the current result, typing its cells as typedCellValue does for Excel.
+ * - pending: Rows buffered for the next appendCells request.
+ * - pendingCells: The number of cells held in `pending`.
+ */
+type googleSheetsWriter struct {
+	client          *http.Client
+	apiURL          string
+	spreadsheetID   string
+	credentialsFile string
+	token           string
+	tokenExpiry     time.Time
+	sheetIDs        map[string]int64
+	currentID       int64
+	columnTypes     []*sql.ColumnType
+	pending         []map[string]interface{}
+	pendingCells    int
+}
+
+/*
+ * newGoogleSheetsWriter creates the Google Sheets writer, authenticating with the service account
+ * credential and loading the tabs already present in the target sheet.
+ *
+ * Parameters:
+ * - opts: A `RunOptions` struct, `GSheetsID` and `GSheetsCredentials` are required.
+ * - baseName: Unused, results are written to the existing Google Sheet.
+ *
+ * Returns:
+ * - ResultWriter: The writer, ready to receive results.
+ * - error: Returns an error if the options are incomplete, authentication fails or the sheet cannot be read.
+ *
+ * Notes:
+ * - The service account must have edit access to the Google Sheet, share the sheet with its client_email.
+ * - Access tokens last an hour, a new one is fetched before the current one expires or when the API rejects it,
+ *   so a long run keeps writing.
+ */
+func newGoogleSheetsWriter(opts RunOptions, baseName string) (ResultWriter, error) {
+	if opts.GSheetsID == "" {
+		return nil, fmt.Errorf("-gsheets-id is required for -format=gsheets")
+	}
+	if opts.GSheetsCredentials == "" {
+		return nil, fmt.Errorf("-gsheets-credentials or GOOGLE_APPLICATION_CREDENTIALS is required for -format=gsheets")
+	}
+
+	w := &googleSheetsWriter{
+		client:          &http.Client{Timeout: 60 * time.Second},
+		apiURL:          gsheetsAPI,
+		spreadsheetID:   opts.GSheetsID,
+		credentialsFile: opts.GSheetsCredentials,
+	}
+
+	if err := w.refreshAccessToken(); err != nil {
+		return nil, err
+	}
+
+	if err := w.loadSheetIDs(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+/*
+ * refreshAccessToken replaces the access token with a new one, valid until `tokenExpiry`.
+ */
+func (w *googleSheetsWriter) refreshAccessToken() error {
+	token, lifetime, err := w.fetchAccessToken(w.credentialsFile)
+	if err != nil {
+		return err
+	}
+	w.token = token
+	w.tokenExpiry = time.Now().Add(lifetime - gsheetsTokenExpiryMargin)
+	return nil
+}
+
+/*
+ * fetchAccessToken exchanges a signed service account assertion (RS256 JWT) for an OAuth access token, returned with
+ * its lifetime, an hour when the token endpoint does not tell.
+ */
+func (w *googleSheetsWriter) fetchAccessToken(credentialsFile string) (string, time.Duration, error) {
+	content, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read service account credentials: %v", err)
+	}
+
+	var account googleServiceAccount
+	if err := json.Unmarshal(content, &account); err != nil {
+		return "", 0, fmt.Errorf("failed to parse service account credentials: %v", err)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return "", 0, fmt.Errorf("service account private_key is not PEM encoded")
+	}
+	parsedKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to parse service account private key: %v", err)
+	}
+	privateKey, ok := parsedKey.(*rsa.PrivateKey)
+	if !ok {
+		return "", 0, fmt.Errorf("service account private key is not an RSA key")
+	}
+
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   account.ClientEmail,
+		"scope": gsheetsScope,
+		"aud":   account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to sign service account assertion: %v", err)
+	}
+	assertion := unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+
+	resp, err := w.client.PostForm(account.TokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to request access token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("failed to request access token: %s: %s", resp.Status, string(body))
+	}
+
+	var tokenResponse struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return "", 0, fmt.Errorf("failed to parse access token response: %v", err)
+	}
+	lifetime := time.Hour
+	if tokenResponse.ExpiresIn > 0 {
+		lifetime = time.Duration(tokenResponse.ExpiresIn) * time.Second
+	}
+	return tokenResponse.AccessToken, lifetime, nil
+}
+
+/*
+ * callAPI sends an authenticated request to the Sheets API and decodes the JSON response into `out` when it is not nil.
+ * The access token is replaced before it expires, and once when the API answers 401 Unauthorized, the request
+ * then being sent again with the new token.
+ */
+func (w *googleSheetsWriter) callAPI(method string, path string, payload interface{}, out interface{}) error {
+	var content []byte
+	if payload != nil {
+		var err error
+		if content, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+
+	if time.Now().After(w.tokenExpiry) {
+		logDebug("Google Sheets access token expired, fetching a new one")
+		if err := w.refreshAccessToken(); err != nil {
+			return err
+		}
+	}
+
+	status, respBody, err := w.sendRequest(method, path, content)
+	if err == nil && status == http.StatusUnauthorized {
+		logDebug("Google Sheets rejected the access token, fetching a new one")
+		if err := w.refreshAccessToken(); err != nil {
+			return err
+		}
+		status, respBody, err = w.sendRequest(method, path, content)
+	}
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("google sheets request failed: %d %s: %s", status, http.StatusText(status), string(respBody))
+	}
+	if out != nil {
+		return json.Unmarshal(respBody, out)
+	}
+	return nil
+}
+
+/*
+ * sendRequest sends one request to the Sheets API with the current access token, returning the status code and the
+ * body of the response.
+ */
+func (w *googleSheetsWriter) sendRequest(method string, path string, content []byte) (int, []byte, error) {
+	var body io.Reader
+	if content != nil {
+		body = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequest(method, w.apiURL+path, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+w.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("google sheets request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, respBody, nil
+}
+
+/*
+ * batchUpdate sends a list of spreadsheet requests in a single spreadsheets.batchUpdate call.
+ */
+func (w *googleSheetsWriter) batchUpdate(requests []map[string]interface{}, out interface{}) error {
+	return w.callAPI(http.MethodPost, url.PathEscape(w.spreadsheetID)+":batchUpdate", map[string]interface{}{"requests": requests}, out)
+}
+
+/*
+ * loadSheetIDs reads the titles and IDs of the tabs already present in the target sheet.
+ */
+func (w *googleSheetsWriter) loadSheetIDs() error {
+	var spreadsheet struct {
+		Sheets []struct {
+			Properties struct {
+				SheetID int64  `json:"sheetId"`
+				Title   string `json:"title"`
+			} `json:"properties"`
+		} `json:"sheets"`
+	}
+	if err := w.callAPI(http.MethodGet, url.PathEscape(w.spreadsheetID)+"?fields=sheets.properties", nil, &spreadsheet); err != nil {
+		return err
+	}
+
+	w.sheetIDs = make(map[string]int64)
+	for _, sheet := range spreadsheet.Sheets {
+		w.sheetIDs[sheet.Properties.Title] = sheet.Properties.SheetID
+	}
+	return nil
+}
+
+/*
+ * BeginResult creates the tab for the result, or clears and resizes it when it already exists
+ * from a previous run, then buffers the header row.
+ */
+func (w *googleSheetsWriter) BeginResult(name string, query Query, columns []string, columnTypes []*sql.ColumnType) error {
+	columnCount := len(columns)
+	if columnCount == 0 {
+		columnCount = 1
+	}
+	gridProperties := map[string]interface{}{"rowCount": 1, "columnCount": columnCount}
+
+	if sheetID, ok := w.sheetIDs[name]; ok {
+		err := w.batchUpdate([]map[string]interface{}{
+			{"updateSheetProperties": map[string]interface{}{
+				"properties": map[string]interface{}{"sheetId": sheetID, "gridProperties": gridProperties},
+				"fields":     "gridProperties(rowCount,columnCount)",
+			}},
+			{"updateCells": map[string]interface{}{
+				"range":  map[string]interface{}{"sheetId": sheetID},
+				"fields": "userEnteredValue",
+			}},
+		}, nil)
+		if err != nil {
+			return err
+		}
+		w.currentID = sheetID
+	} else {
+		var response struct {
+			Replies []struct {
+				AddSheet struct {
+					Properties struct {
+						SheetID int64 `json:"sheetId"`
+					} `json:"properties"`
+				} `json:"addSheet"`
+			} `json:"replies"`
+		}
+		err := w.batchUpdate([]map[string]interface{}{
+			{"addSheet": map[string]interface{}{
+				"properties": map[string]interface{}{"title": name, "gridProperties": gridProperties},
+			}},
+		}, &response)
+		if err != nil {
+			return err
+		}
+		if len(response.Replies) == 0 {
+			return fmt.Errorf("google sheets did not return the new sheet for %s", name)
+		}
+		w.currentID = response.Replies[0].AddSheet.Properties.SheetID
+		w.sheetIDs[name] = w.currentID
+	}
+
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = column
+	}
+	w.columnTypes = columnTypes
+	w.pending = nil
+	w.pendingCells = 0
+	return w.WriteRow(header)
+}
+
+/*
+ * WriteRow buffers a row of typed values and flushes the buffer once it reaches the per request cell limit.
+ */
+func (w *googleSheetsWriter) WriteRow(values []interface{}) error {
+	cells := make([]map[string]interface{}, len(values))
+	for i, v := range values {
+		var columnType *sql.ColumnType
+		if i < len(w.columnTypes) {
+			columnType = w.columnTypes[i]
+		}
+		cells[i] = map[string]interface{}{"userEnteredValue": gsheetsCellValue(v, columnType)}
+	}
+	w.pending = append(w.pending, map[string]interface{}{"values": cells})
+	w.pendingCells += len(values)
+
+	if w.pendingCells >= gsheetsMaxCellsPerRequest {
+		return w.flush()
+	}
+	return nil
+}
+
+/*
+ * gsheetsCellValue returns the userEnteredValue of a scanned value, typed as typedCellValue types the Excel cells: a
+ * numberValue for the integers, floats and the DECIMAL, NUMERIC and MONEY values of up to 15 digits, a boolValue for
+ * BIT values, and a stringValue holding the cleaned text of the others, dates included.
+ */
+func gsheetsCellValue(v interface{}, columnType *sql.ColumnType) map[string]interface{} {
+	value, _ := typedCellValue(v, columnType)
+	switch typed := value.(type) {
+	case bool:
+		return map[string]interface{}{"boolValue": typed}
+	case string:
+		return map[string]interface{}{"stringValue": typed}
+	}
+	if n, ok := numericValue(value); ok {
+		return map[string]interface{}{"numberValue": n}
+	}
+	return map[string]interface{}{"stringValue": cleanCellValue(v)}
+}
+
+/*
+ * flush appends the buffered rows to the current tab with a single appendCells request.
+ */
+func (w *googleSheetsWriter) flush() error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	err := w.batchUpdate([]map[string]interface{}{
+		{"appendCells": map[string]interface{}{
+			"sheetId": w.currentID,
+			"rows":    w.pending,
+			"fields":  "userEnteredValue",
+		}},
+	}, nil)
+	w.pending = nil
+	w.pendingCells = 0
+	return err
+}
+
+/*
+ * EndResult flushes the remaining buffered rows for the current result.
+ */
+func (w *googleSheetsWriter) EndResult() error {
+	return w.flush()
+}
+
+/*
+ * Close has nothing to release, every result is flushed by EndResult.
+ */
+func (w *googleSheetsWriter) Close() error {
+	logInfo("Results written to Google Sheet https://docs.google.com/spreadsheets/d/%s", strings.TrimSpace(w.spreadsheetID))
+	return nil
+}
@@ -0,0 +1,251 @@
+package diagnostics
+
+import (
+	"crypto/rand"         // For the test service account key
+	"crypto/rsa"          // For the test service account key
+	"crypto/x509"         // For encoding the test service account key
+	"database/sql/driver" // For the values of the fake result
+	"encoding/json"       // For the credentials and the API payloads
+	"encoding/pem"        // For encoding the test service account key
+	"fmt"                 // For numbering the access tokens
+	"net/http"            // For the fake Google endpoints
+	"net/http/httptest"   // For serving the fake Google endpoints
+	"os"                  // For writing the credentials file
+	"path/filepath"       // For the credentials file path
+	"strings"             // For matching the request paths
+	"sync"                // For the state of the fake Google endpoints
+	"testing"             // For the test framework
+	"time"                // For expiring the access token
+)
+
+/*
+ * fakeGoogleSheets serves the OAuth token endpoint and the Sheets API calls of googleSheetsWriter, recording the
+ * appendCells requests and the tokens used.
+ *
+ * Fields:
+ * - tokens: The number of access tokens handed out, the token n is "token-n".
+ * - revoked: Tokens answered with 401 Unauthorized.
+ * - appended: The rows of every appendCells request, in request order.
+ * - usedTokens: The token of every Sheets API call, in call order.
+ */
+type fakeGoogleSheets struct {
+	mu       sync.Mutex
+	tokens   int
+	revoked  map[string]bool
+	appended [][]struct {
+		Values []map[string]map[string]interface{}
+	}
+	usedTokens []string
+}
+
+func (g *fakeGoogleSheets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if r.URL.Path == "/token" {
+		g.tokens++
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": fmt.Sprintf("token-%d", g.tokens), "expires_in": 3600})
+		return
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	g.usedTokens = append(g.usedTokens, token)
+	if g.revoked[token] {
+		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	if r.Method == http.MethodGet {
+		w.Write([]byte(`{"sheets": []}`))
+		return
+	}
+
+	var update struct {
+		Requests []struct {
+			AppendCells *struct {
+				Rows []struct {
+					Values []map[string]map[string]interface{}
+				} `json:"rows"`
+			} `json:"appendCells"`
+		} `json:"requests"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, request := range update.Requests {
+		if request.AppendCells != nil {
+			g.appended = append(g.appended, request.AppendCells.Rows)
+		}
+	}
+	w.Write([]byte(`{"replies": [{"addSheet": {"properties": {"sheetId": 7}}}]}`))
+}
+
+/*
+ * newTestGoogleSheetsWriter returns a googleSheetsWriter calling a fakeGoogleSheets server, authenticated with a
+ * service account key generated for the test.
+ */
+func newTestGoogleSheetsWriter(t *testing.T) (*googleSheetsWriter, *fakeGoogleSheets) {
+	t.Helper()
+	fake := &fakeGoogleSheets{revoked: map[string]bool{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+	credentials, _ := json.Marshal(googleServiceAccount{
+		ClientEmail: "diagnostics@example.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    server.URL + "/token",
+	})
+	credentialsFile := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(credentialsFile, credentials, 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	w := &googleSheetsWriter{
+		client:          server.Client(),
+		apiURL:          server.URL + "/",
+		spreadsheetID:   "sheet-id",
+		credentialsFile: credentialsFile,
+	}
+	if err := w.refreshAccessToken(); err != nil {
+		t.Fatalf("refreshAccessToken: %v", err)
+	}
+	if err := w.loadSheetIDs(); err != nil {
+		t.Fatalf("loadSheetIDs: %v", err)
+	}
+	return w, fake
+}
+
+/*
+ * TestGoogleSheetsBatching writes 2000 rows of 10 columns and checks they are sent in appendCells requests of at
+ * most 10000 cells, the header included, with the remainder sent by EndResult.
+ */
+func TestGoogleSheetsBatching(t *testing.T) {
+	w, fake := newTestGoogleSheetsWriter(t)
+
+	columns := make([]string, 10)
+	row := make([]interface{}, 10)
+	for i := range columns {
+		columns[i] = fmt.Sprintf("c%d", i)
+		row[i] = int64(i)
+	}
+	if err := w.BeginResult("1_Wide", Query{Name: "Wide"}, columns, nil); err != nil {
+		t.Fatalf("BeginResult: %v", err)
+	}
+	for i := 0; i < 2000; i++ {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow %d: %v", i, err)
+		}
+	}
+	if err := w.EndResult(); err != nil {
+		t.Fatalf("EndResult: %v", err)
+	}
+
+	wantCells := []int{10000, 10000, 10}
+	if len(fake.appended) != len(wantCells) {
+		t.Fatalf("sent %d appendCells requests, want %d", len(fake.appended), len(wantCells))
+	}
+	for i, rows := range fake.appended {
+		cells := 0
+		for _, r := range rows {
+			cells += len(r.Values)
+		}
+		if cells != wantCells[i] {
+			t.Errorf("appendCells request %d holds %d cells, want %d", i+1, cells, wantCells[i])
+		}
+	}
+}
+
+/*
+ * TestGoogleSheetsTypedCells checks the numbers, the DECIMAL values of up to 15 digits and the BIT values are sent as
+ * numberValue and boolValue, the text, the dates, NULL and the longer DECIMAL values as stringValue.
+ */
+func TestGoogleSheetsTypedCells(t *testing.T) {
+	w, fake := newTestGoogleSheetsWriter(t)
+	rows := queryFakeRows(t, fakeResultSet{
+		columns: []string{"wait_ms", "ratio", "avg_wait", "is_user", "wait_type", "last_seen", "note", "total"},
+		types:   []string{"BIGINT", "FLOAT", "DECIMAL", "BIT", "NVARCHAR", "DATETIME", "NVARCHAR", "DECIMAL"},
+		scales:  map[int][2]int64{2: {10, 2}, 7: {38, 4}},
+	})
+	columns, _ := rows.Columns()
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("ColumnTypes: %v", err)
+	}
+
+	lastSeen := time.Date(2025, 11, 27, 10, 30, 0, 0, time.UTC)
+	values := []driver.Value{int64(420), 0.25, []byte("12.50"), true, "CXPACKET", lastSeen, nil, []byte("12345678901234567890.1234")}
+	row := make([]interface{}, len(values))
+	for i, v := range values {
+		row[i] = v
+	}
+	if err := w.BeginResult("1_Waits", Query{Name: "Waits"}, columns, columnTypes); err != nil {
+		t.Fatalf("BeginResult: %v", err)
+	}
+	if err := w.WriteRow(row); err != nil {
+		t.Fatalf("WriteRow: %v", err)
+	}
+	if err := w.EndResult(); err != nil {
+		t.Fatalf("EndResult: %v", err)
+	}
+
+	want := []map[string]interface{}{
+		{"numberValue": 420.0},
+		{"numberValue": 0.25},
+		{"numberValue": 12.5},
+		{"boolValue": true},
+		{"stringValue": "CXPACKET"},
+		{"stringValue": cleanCellValue(lastSeen)},
+		{"stringValue": "NULL"},
+		{"stringValue": "12345678901234567890.1234"},
+	}
+	if len(fake.appended) != 1 || len(fake.appended[0]) != 2 {
+		t.Fatalf("sent %v, want one appendCells request with the header and the row", fake.appended)
+	}
+	if header := fake.appended[0][0].Values[0]["userEnteredValue"]; header["stringValue"] != "wait_ms" {
+		t.Errorf("header cell = %v, want the column name as a stringValue", header)
+	}
+	cells := fake.appended[0][1].Values
+	for i, wantValue := range want {
+		got := cells[i]["userEnteredValue"]
+		if len(got) != 1 || fmt.Sprint(got) != fmt.Sprint(wantValue) {
+			t.Errorf("cell %s = %v, want %v", columns[i], got, wantValue)
+		}
+	}
+}
+
+/*
+ * TestGoogleSheetsTokenRefresh checks a new access token is fetched when the current one expires, and when the API
+ * rejects it with 401 Unauthorized, the rejected request being sent again with the new token.
+ */
+func TestGoogleSheetsTokenRefresh(t *testing.T) {
+	w, fake := newTestGoogleSheetsWriter(t)
+
+	// An expired token is replaced before the request is sent
+	w.tokenExpiry = time.Now().Add(-time.Second)
+	if err := w.BeginResult("1_Waits", Query{Name: "Waits"}, []string{"wait_type"}, nil); err != nil {
+		t.Fatalf("BeginResult with an expired token: %v", err)
+	}
+	if last := fake.usedTokens[len(fake.usedTokens)-1]; last != "token-2" {
+		t.Errorf("request sent with %s, want the new token-2", last)
+	}
+
+	// A rejected token is replaced and the request sent again
+	fake.revoked["token-2"] = true
+	if err := w.EndResult(); err != nil {
+		t.Fatalf("EndResult with a rejected token: %v", err)
+	}
+	if used := fake.usedTokens[len(fake.usedTokens)-2:]; used[0] != "token-2" || used[1] != "token-3" {
+		t.Errorf("requests sent with %v, want token-2 rejected then token-3", used)
+	}
+	if len(fake.appended) != 1 {
+		t.Errorf("sent %d appendCells requests, want the header sent once", len(fake.appended))
+	}
+}
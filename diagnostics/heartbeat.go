@@ -0,0 +1,44 @@
+package diagnostics
+
+import (
+	"context" // For stopping the heartbeat with the query
+	"time"    // For the heartbeat ticker
+)
+
+/*
+ * startHeartbeat logs "still running" every `-heartbeat` while a query runs, so a long query of an unattended
+ * or scheduled run is not mistaken for a hung program.
+ *
+ * Parameters:
+ * - ctx: The query context, the heartbeat stops when it is cancelled or times out.
+ * - opts: The run options, `Heartbeat` is the interval between the lines, 0 logs nothing.
+ * - name: The name of the query.
+ *
+ * Returns:
+ * - The function stopping the heartbeat, to call as soon as the query returns.
+ *
+ * Notes:
+ * - The heartbeat only logs, it never touches the query or its results.
+ */
+func startHeartbeat(ctx context.Context, opts RunOptions, name string) func() {
+	if opts.Heartbeat <= 0 {
+		return func() {}
+	}
+	started := time.Now()
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(opts.Heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				logInfo("Still running query %s (%ds elapsed)", name, int(time.Since(started).Seconds()))
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,151 @@
+package diagnostics
+
+import (
+	"context"      // For running the hooks on a dedicated connection
+	"database/sql" // Database/sql package for database operations
+	"fmt"          // For formatted I/O operations
+	"os"           // For reading the hook files
+	"regexp"       // For splitting batches on GO separators
+	"strings"      // For string manipulation
+)
+
+// Lines holding only GO separate batches in SQL files, as in SSMS and sqlcmd
+var batchSeparator = regexp.MustCompile(`(?im)^\s*GO\s*;?\s*$`)
+
+/*
+ * splitSQLBatches splits the content of a SQL file into batches on lines holding only GO,
+ * dropping empty batches.
+ */
+func splitSQLBatches(content string) []string {
+	var batches []string
+	for _, batch := range batchSeparator.Split(content, -1) {
+		if strings.TrimSpace(batch) != "" {
+			batches = append(batches, batch)
+		}
+	}
+	return batches
+}
+
+/*
+ * openHookConnection reserves a dedicated connection for the `-pre-sql` and `-post-sql` hooks and the `preRun`
+ * and `postRun` statements of the queries file.
+ *
+ * Parameters:
+ * - db: A pointer to the `sql.DB` object representing the database connection pool.
+ * - opts: A `RunOptions` struct with the hook files and `AllowWrites`.
+ * - queries: The queries of the run, with their `PreRun` and `PostRun` statements.
+ *
+ * Returns:
+ * - *sql.Conn: The dedicated connection, nil when no hook is configured.
+ * - error: Returns an error if hooks are configured without `-allow-writes` or the connection cannot be opened.
+ *
+ * Notes:
+ * - Hooks usually create or drop objects, so they are refused unless `-allow-writes` acknowledges that.
+ * - The same connection runs both hooks and stays open for the whole run, so global temporary tables (##name)
+ *   created by the pre hook live until the post hook. Local temporary tables (#name) are scoped to this
+ *   connection and are not visible to the diagnostic queries, which run on the pool.
+ */
+func openHookConnection(db *sql.DB, opts RunOptions, queries Queries) (*sql.Conn, error) {
+	if opts.PreSQL == "" && opts.PostSQL == "" && len(queries.PreRun) == 0 && len(queries.PostRun) == 0 {
+		return nil, nil
+	}
+	if !opts.AllowWrites {
+		return nil, fmt.Errorf("-pre-sql, -post-sql and the preRun and postRun statements of the queries file can change the database and require -allow-writes")
+	}
+	if opts.MaxOpenConns == 1 {
+		return nil, fmt.Errorf("the hooks hold a connection for the whole run, -max-open-conns 1 would leave none for the queries")
+	}
+	return db.Conn(context.Background())
+}
+
+/*
+ * runSQLHook executes every batch of a hook SQL file on the dedicated hook connection.
+ *
+ * Parameters:
+ * - conn: The dedicated hook connection.
+ * - filePath: The path of the SQL file.
+ * - phase: "pre_sql" or "post_sql", used in messages and to name the captured sheets.
+ * - report: The `excelReport` receiving the captured output, nil to discard any output.
+ *
+ * Returns:
+ * - error: Returns the first failing batch, later batches are not run.
+ *
+ * Functionality:
+ * 1. Reads the file and splits it into batches on GO separators.
+ * 2. Without capture, executes each batch with ExecContext.
+ * 3. With capture (`-capture-hook-output` and an Excel report), each result set of each batch is written
+ *    to its own sheet named "<phase>_<n>".
+ */
+func runSQLHook(conn *sql.Conn, filePath string, phase string, report *excelReport) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s file %s: %v", phase, filePath, err)
+	}
+
+	ctx := context.Background()
+	sheetNumber := 0
+	for i, batch := range splitSQLBatches(string(content)) {
+		logInfo("Executing %s batch %d from %s", phase, i+1, filePath)
+
+		if report == nil || !report.opts.CaptureHookOutput {
+			if _, err := conn.ExecContext(ctx, batch); err != nil {
+				return fmt.Errorf("%s batch %d failed: %v", phase, i+1, err)
+			}
+			continue
+		}
+
+		rows, err := conn.QueryContext(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("%s batch %d failed: %v", phase, i+1, err)
+		}
+		for {
+			columns, err := rows.Columns()
+			if err == nil && len(columns) > 0 {
+				columnTypes, _ := rows.ColumnTypes()
+				sheetNumber++
+				sheet := newResultSheet(report, fmt.Sprintf("%s_%d", phase, sheetNumber), Query{Name: phase}, columns, columnTypes, false)
+				if err := sheet.writeRows(rows, 1); err != nil {
+					rows.Close()
+					return fmt.Errorf("%s batch %d failed: %v", phase, i+1, err)
+				}
+				sheet.finish()
+			}
+			if !rows.NextResultSet() {
+				break
+			}
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return fmt.Errorf("%s batch %d failed: %v", phase, i+1, err)
+		}
+		rows.Close()
+	}
+	return nil
+}
+
+/*
+ * runStatementHooks executes the `preRun` or `postRun` statements of the queries file on the dedicated hook
+ * connection, in order and without creating any sheet.
+ *
+ * Parameters:
+ * - conn: The dedicated hook connection.
+ * - statements: The statements, each executed with ExecContext as one batch.
+ * - phase: "preRun" or "postRun", used in messages.
+ *
+ * Returns:
+ * - error: Returns the first failing statement, later statements are not run.
+ *
+ * Notes:
+ * - Session settings such as SET STATISTICS only apply to the hook connection, not to the pool the queries run on.
+ *   Server wide commands such as DBCC FREEPROCCACHE apply to every connection.
+ */
+func runStatementHooks(conn *sql.Conn, statements []string, phase string) error {
+	ctx := context.Background()
+	for i, statement := range statements {
+		logInfo("Executing %s statement %d: %s", phase, i+1, strings.TrimSpace(statement))
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("%s statement %d failed: %v", phase, i+1, err)
+		}
+	}
+	return nil
+}
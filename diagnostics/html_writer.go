@@ -0,0 +1,213 @@
+package diagnostics
+
+import (
+	"bufio"        // For buffering the file writes
+	"database/sql" // Database/sql package for column type information
+	"fmt"          // For formatted I/O operations
+	"html"         // For escaping the cell text
+	"os"           // For creating the output file
+	"time"         // For the generation time in the page header
+)
+
+// Head of the HTML report, the styles are inline so the file has no external dependency
+const htmlReportHead = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: Segoe UI, Helvetica, Arial, sans-serif; margin: 1.5em; color: #222; }
+nav ul { columns: 3; }
+section { margin-bottom: 2.5em; }
+h2 { border-bottom: 2px solid #4472C4; padding-bottom: .2em; }
+.description, .notes { margin: .3em 0; }
+.notes { color: #666; font-style: italic; }
+input.search { margin: .5em 0; padding: .3em; width: 20em; }
+.table-wrap { overflow-x: auto; max-height: 40em; }
+table { border-collapse: collapse; font-size: .85em; }
+th, td { border: 1px solid #ccc; padding: .25em .5em; text-align: left; vertical-align: top; white-space: pre-wrap; }
+th { background: #4472C4; color: #fff; cursor: pointer; position: sticky; top: 0; }
+th.asc::after { content: " \25B2"; }
+th.desc::after { content: " \25BC"; }
+tr:nth-child(even) td { background: #f3f6fb; }
+td.null { color: #999; }
+</style>
+</head>
+<body>
+<h1>%s</h1>
+<p>Generated %s</p>
+<nav><ul id="toc"></ul></nav>
+`
+
+// Script of the HTML report, sorting a table on a header click and filtering its rows with the search box
+const htmlReportScript = `<script>
+(function () {
+  var toc = document.getElementById("toc");
+  document.querySelectorAll("section").forEach(function (section) {
+    var item = document.createElement("li"), link = document.createElement("a");
+    link.href = "#" + section.id;
+    link.textContent = section.querySelector("h2").textContent;
+    item.appendChild(link);
+    toc.appendChild(item);
+  });
+  function cellValue(row, index) {
+    var cell = row.cells[index];
+    return cell.classList.contains("null") ? null : cell.textContent;
+  }
+  function compare(a, b) {
+    if (a === b) { return 0; }
+    if (a === null) { return 1; }
+    if (b === null) { return -1; }
+    var x = Number(a), y = Number(b);
+    if (a.trim() !== "" && b.trim() !== "" && !isNaN(x) && !isNaN(y)) { return x - y; }
+    return a.localeCompare(b);
+  }
+  document.querySelectorAll("table.report").forEach(function (table) {
+    var body = table.tBodies[0];
+    table.querySelectorAll("th").forEach(function (header, index) {
+      header.addEventListener("click", function () {
+        var ascending = !header.classList.contains("asc");
+        table.querySelectorAll("th").forEach(function (th) { th.classList.remove("asc", "desc"); });
+        header.classList.add(ascending ? "asc" : "desc");
+        var rows = Array.prototype.slice.call(body.rows);
+        rows.sort(function (r1, r2) {
+          var order = compare(cellValue(r1, index), cellValue(r2, index));
+          return ascending ? order : -order;
+        });
+        rows.forEach(function (row) { body.appendChild(row); });
+      });
+    });
+    var search = table.parentNode.previousElementSibling;
+    search.addEventListener("input", function () {
+      var text = search.value.toLowerCase();
+      Array.prototype.forEach.call(body.rows, function (row) {
+        row.style.display = row.textContent.toLowerCase().indexOf(text) === -1 ? "none" : "";
+      });
+    });
+  });
+})();
+</script>
+</body>
+</html>
+`
+
+/*
+ * htmlWriter is the ResultWriter for `-format=html`. Every result is a section of a single standalone
+ * "<baseName>.html" page, with the name, description and notes of its query above a table sortable by clicking
+ * a header and filtered by a search box. The rows are streamed to the file, a result is never held in memory.
+ *
+ * Fields:
+ * - fileName: The name of the output file.
+ * - file: The output file.
+ * - out: The buffered writer of the output file.
+ * - open: Whether the table of a result is open.
+ * - results: The number of results written so far.
+ */
+type htmlWriter struct {
+	fileName string
+	file     *os.File
+	out      *bufio.Writer
+	open     bool
+	results  int
+}
+
+/*
+ * newHTMLWriter creates the HTML writer and writes the head of the page.
+ *
+ * Parameters:
+ * - opts: Unused, the HTML format has no options.
+ * - baseName: The timestamped name of the output file, without its .html extension.
+ *
+ * Returns:
+ * - ResultWriter: The writer, ready to receive results.
+ * - error: An error if the output file cannot be created.
+ *
+ * Notes:
+ * - The styles and the sorting script are inline, the page opens offline and loads nothing from a CDN.
+ */
+func newHTMLWriter(opts RunOptions, baseName string) (ResultWriter, error) {
+	fileName := baseName + ".html"
+	file, err := os.Create(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %v", fileName, err)
+	}
+	w := &htmlWriter{fileName: fileName, file: file, out: bufio.NewWriter(file)}
+	title := "SQL Server Diagnostics"
+	_, err = fmt.Fprintf(w.out, htmlReportHead, title, title, time.Now().Format("2006-01-02 15:04:05"))
+	return w, err
+}
+
+/*
+ * BeginResult opens the section of a result with the name, description and notes of its query, the search box
+ * and the header row of its table.
+ */
+func (w *htmlWriter) BeginResult(name string, query Query, columns []string, columnTypes []*sql.ColumnType) error {
+	if err := w.EndResult(); err != nil {
+		return err
+	}
+	w.results++
+	w.open = true
+
+	fmt.Fprintf(w.out, "<section id=\"%s\">\n<h2>%s</h2>\n", html.EscapeString(name), html.EscapeString(name))
+	if query.Name != "" && query.Name != name {
+		fmt.Fprintf(w.out, "<p class=\"description\"><strong>%s</strong></p>\n", html.EscapeString(query.Name))
+	}
+	if query.Description != "" {
+		fmt.Fprintf(w.out, "<p class=\"description\">%s</p>\n", html.EscapeString(query.Description))
+	}
+	if query.Notes != "" {
+		fmt.Fprintf(w.out, "<p class=\"notes\">%s</p>\n", html.EscapeString(query.Notes))
+	}
+	w.out.WriteString("<input class=\"search\" type=\"search\" placeholder=\"Search\">\n<div class=\"table-wrap\">\n<table class=\"report\">\n<thead><tr>")
+	for _, column := range columns {
+		fmt.Fprintf(w.out, "<th>%s</th>", html.EscapeString(column))
+	}
+	_, err := w.out.WriteString("</tr></thead>\n<tbody>\n")
+	return err
+}
+
+/*
+ * WriteRow writes a row of the current table, NULL values are shown as a greyed NULL sorted last.
+ */
+func (w *htmlWriter) WriteRow(values []interface{}) error {
+	w.out.WriteString("<tr>")
+	for _, v := range values {
+		if text, ok := exportText(v); ok {
+			fmt.Fprintf(w.out, "<td>%s</td>", html.EscapeString(text))
+		} else {
+			w.out.WriteString("<td class=\"null\">NULL</td>")
+		}
+	}
+	_, err := w.out.WriteString("</tr>\n")
+	return err
+}
+
+/*
+ * EndResult closes the table and the section of the current result.
+ */
+func (w *htmlWriter) EndResult() error {
+	if !w.open {
+		return nil
+	}
+	w.open = false
+	_, err := w.out.WriteString("</tbody>\n</table>\n</div>\n</section>\n")
+	return err
+}
+
+/*
+ * Close completes a result left open by a failed query, writes the script and closes the page and the file.
+ */
+func (w *htmlWriter) Close() error {
+	err := w.EndResult()
+	if _, writeErr := w.out.WriteString(htmlReportScript); err == nil {
+		err = writeErr
+	}
+	if flushErr := w.out.Flush(); err == nil {
+		err = flushErr
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	logInfo("HTML file written: %s with %d result(s)", w.fileName, w.results)
+	return err
+}
@@ -0,0 +1,130 @@
+package diagnostics
+
+import (
+	"bufio"         // For buffering the file writes
+	"database/sql"  // Database/sql package for column type information
+	"encoding/json" // For encoding the results
+	"fmt"           // For formatted I/O operations
+	"os"            // For creating the output file
+)
+
+/*
+ * jsonWriter is the ResultWriter for `-format=json`. Every result is an object of a single "<baseName>.json"
+ * array, {"name", "query", "columns", "rows"}, each row an array of strings with null for NULL values.
+ * The rows are streamed to the file, a result is never held in memory.
+ *
+ * Fields:
+ * - fileName: The name of the output file.
+ * - file: The output file.
+ * - out: The buffered writer of the output file.
+ * - results: The number of results written so far.
+ * - rows: The number of rows of the current result written so far, -1 when no result is open.
+ */
+type jsonWriter struct {
+	fileName string
+	file     *os.File
+	out      *bufio.Writer
+	results  int
+	rows     int
+}
+
+/*
+ * newJSONWriter creates the JSON writer and opens the results array.
+ *
+ * Parameters:
+ * - opts: Unused, the JSON format has no options.
+ * - baseName: The timestamped name of the output file, without its .json extension.
+ *
+ * Returns:
+ * - ResultWriter: The writer, ready to receive results.
+ * - error: An error if the output file cannot be created.
+ */
+func newJSONWriter(opts RunOptions, baseName string) (ResultWriter, error) {
+	fileName := baseName + ".json"
+	file, err := os.Create(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %v", fileName, err)
+	}
+	w := &jsonWriter{fileName: fileName, file: file, out: bufio.NewWriter(file), rows: -1}
+	_, err = w.out.WriteString("[")
+	return w, err
+}
+
+/*
+ * BeginResult opens the object of a result with its sheet name, query name and column names.
+ */
+func (w *jsonWriter) BeginResult(name string, query Query, columns []string, columnTypes []*sql.ColumnType) error {
+	if err := w.EndResult(); err != nil {
+		return err
+	}
+
+	header, err := json.Marshal(struct {
+		Name    string   `json:"name"`
+		Query   string   `json:"query"`
+		Columns []string `json:"columns"`
+	}{name, query.Name, columns})
+	if err != nil {
+		return err
+	}
+
+	if w.results > 0 {
+		w.out.WriteString(",")
+	}
+	w.results++
+	w.rows = 0
+	// The header object is left open to append the rows array
+	_, err = fmt.Fprintf(w.out, "\n  %s,\"rows\":[", header[:len(header)-1])
+	return err
+}
+
+/*
+ * WriteRow appends a row to the rows array of the current result.
+ */
+func (w *jsonWriter) WriteRow(values []interface{}) error {
+	row := make([]*string, len(values))
+	for i, v := range values {
+		if text, ok := exportText(v); ok {
+			row[i] = &text
+		}
+	}
+	encoded, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	if w.rows > 0 {
+		w.out.WriteString(",")
+	}
+	w.rows++
+	_, err = fmt.Fprintf(w.out, "\n    %s", encoded)
+	return err
+}
+
+/*
+ * EndResult closes the rows array and the object of the current result.
+ */
+func (w *jsonWriter) EndResult() error {
+	if w.rows < 0 {
+		return nil
+	}
+	w.rows = -1
+	_, err := w.out.WriteString("]}")
+	return err
+}
+
+/*
+ * Close completes a result left open by a failed query, closes the results array and the file.
+ */
+func (w *jsonWriter) Close() error {
+	err := w.EndResult()
+	if _, writeErr := w.out.WriteString("\n]\n"); err == nil {
+		err = writeErr
+	}
+	if flushErr := w.out.Flush(); err == nil {
+		err = flushErr
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	logInfo("JSON file written: %s", w.fileName)
+	return err
+}
@@ -0,0 +1,48 @@
+package diagnostics
+
+import (
+	"database/sql" // Database/sql package for database operations
+	"time"         // For working with date and time
+)
+
+// Load signal of the `-load-guard`, tasks waiting for a CPU across the schedulers running user work
+const loadGuardQuery = `SELECT SUM(runnable_tasks_count) FROM sys.dm_os_schedulers WITH (NOLOCK) WHERE status = N'VISIBLE ONLINE'`
+
+// Time between two load checks while the server is above the threshold
+const loadGuardPollInterval = 10 * time.Second
+
+/*
+ * waitForServerLoad holds the next query back while the server is busy, for `-load-guard`.
+ *
+ * Parameters:
+ * - db: A pointer to the `sql.DB` object representing the database connection.
+ * - opts: A `RunOptions` struct with the `LoadGuardThreshold` and `LoadGuardMaxWait`.
+ *
+ * Functionality:
+ * 1. Reads the number of runnable tasks, tasks ready to run but waiting for a CPU, from sys.dm_os_schedulers.
+ * 2. While it is above the threshold, waits `loadGuardPollInterval` and checks again.
+ * 3. Proceeds once the load drops, or with a warning once `LoadGuardMaxWait` has passed.
+ *
+ * Notes:
+ * - The check is a single aggregate over a small DMV and adds no meaningful load itself.
+ * - A failing check, for example without VIEW SERVER STATE, is logged and the query proceeds.
+ */
+func waitForServerLoad(db *sql.DB, opts RunOptions) {
+	deadline := time.Now().Add(opts.LoadGuardMaxWait)
+	for {
+		var runnable sql.NullInt64
+		if err := db.QueryRow(loadGuardQuery).Scan(&runnable); err != nil {
+			logWarn("Load guard check failed, proceeding: %v", err)
+			return
+		}
+		if runnable.Int64 <= int64(opts.LoadGuardThreshold) {
+			return
+		}
+		if !time.Now().Add(loadGuardPollInterval).Before(deadline) {
+			logWarn("The server still has %d runnable tasks (threshold %d) after waiting %s, proceeding.", runnable.Int64, opts.LoadGuardThreshold, opts.LoadGuardMaxWait)
+			return
+		}
+		logInfo("Server busy with %d runnable tasks (threshold %d), waiting %s before the next query.", runnable.Int64, opts.LoadGuardThreshold, loadGuardPollInterval)
+		time.Sleep(loadGuardPollInterval)
+	}
+}
@@ -0,0 +1,98 @@
+package diagnostics
+
+import (
+	"fmt"     // For formatted I/O operations
+	"log"     // For writing the log messages
+	"strings" // For string manipulation
+)
+
+/*
+ * logLevel orders the severity of the log messages, the messages below the level set with `-log-level`
+ * are not written.
+ */
+type logLevel int
+
+const (
+	levelDebug logLevel = iota // Full SQL text and details only needed to troubleshoot a run
+	levelInfo                  // Progress of the run: queries started and finished, files written
+	levelWarn                  // Problems the run works around: ignored settings, retries, interruptions
+	levelError                 // Failures: queries, files or styles that could not be written
+)
+
+// Labels of the levels, as written before every message and accepted by `-log-level`
+var logLevelLabels = map[logLevel]string{
+	levelDebug: "DEBUG",
+	levelInfo:  "INFO",
+	levelWarn:  "WARN",
+	levelError: "ERROR",
+}
+
+// Level of the messages written, set from `-log-level`, `-quiet` or `-verbose` at startup
+var currentLogLevel = levelInfo
+
+/*
+ * resolveLogLevel returns the level selected on the command line.
+ *
+ * Parameters:
+ * - name: The `-log-level` value: debug, info, warn or error, case insensitively.
+ * - quiet: `-quiet`, which selects warn.
+ * - verbose: `-verbose`, which selects debug.
+ *
+ * Returns:
+ * - The selected level, or an error for an unknown level name or when `-quiet` and `-verbose` are both set.
+ *
+ * Notes:
+ * - `-quiet` and `-verbose` take precedence over `-log-level`.
+ */
+func resolveLogLevel(name string, quiet bool, verbose bool) (logLevel, error) {
+	switch {
+	case quiet && verbose:
+		return levelInfo, fmt.Errorf("-quiet and -verbose cannot be combined")
+	case quiet:
+		return levelWarn, nil
+	case verbose:
+		return levelDebug, nil
+	}
+	for level, label := range logLevelLabels {
+		if strings.EqualFold(strings.TrimSpace(name), label) {
+			return level, nil
+		}
+	}
+	return levelInfo, fmt.Errorf("unknown log level %q, use debug, info, warn or error", name)
+}
+
+/*
+ * logf writes a message through the standard logger, to stderr, prefixed with its level label, when the level
+ * is at or above `currentLogLevel`.
+ *
+ * Notes:
+ * - The results of the console format and the confirmation prompt of the risky queries are written to stdout
+ *   with fmt, whatever the level, so they can be piped apart from the log.
+ * - Fatal errors still go through log.Fatalf and are always written.
+ */
+func logf(level logLevel, format string, args ...interface{}) {
+	if level < currentLogLevel {
+		return
+	}
+	log.Printf(logLevelLabels[level]+" "+format, args...)
+}
+
+// logDebug writes a DEBUG message, such as the full SQL text of a query
+func logDebug(format string, args ...interface{}) {
+	logf(levelDebug, format, args...)
+}
+
+// logInfo writes an INFO message about the progress of the run
+func logInfo(format string, args ...interface{}) {
+	logf(levelInfo, format, args...)
+}
+
+// logWarn writes a WARN message about a problem the run works around
+func logWarn(format string, args ...interface{}) {
+	logf(levelWarn, format, args...)
+}
+
+// logError writes an ERROR message about a failure
+func logError(format string, args ...interface{}) {
+	logf(levelError, format, args...)
+}
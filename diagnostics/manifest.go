@@ -0,0 +1,127 @@
+package diagnostics
+
+import (
+	"encoding/json" // For encoding the manifest
+	"fmt"           // For formatted I/O operations
+	"os"            // For writing the manifest file
+	"path/filepath" // For the output file name recorded
+	"runtime/debug" // For the module version of the binary
+	"strings"       // For string manipulation
+	"time"          // For the start and end times of the run
+)
+
+// Version of the tool recorded in the run manifest, set at build time with -ldflags "-X malcolmpereira/getSQLServerDiagnostics/diagnostics.toolVersion=1.2.3"
+var toolVersion = "dev"
+
+/*
+ * runManifest is the machine readable record of a run written next to its workbook, so a pipeline can trace
+ * the runs without opening the workbook.
+ *
+ * Fields:
+ * - ToolVersion: The version of the tool, see `manifestToolVersion`.
+ * - Started: When the run started.
+ * - Finished: When the workbook was saved.
+ * - Server: The host the run connected to, with its instance name and port when set.
+ * - Database: The database the run connected to, empty for the login's default database.
+ * - QueriesFile: The queries file or `-queries-dir` folder of the run.
+ * - Output: The file name of the workbook, relative to the manifest.
+ * - Queries: The outcome of every query of the queries file, in query order.
+ */
+type runManifest struct {
+	ToolVersion string          `json:"toolVersion"`
+	Started     time.Time       `json:"started"`
+	Finished    time.Time       `json:"finished"`
+	Server      string          `json:"server"`
+	Database    string          `json:"database"`
+	QueriesFile string          `json:"queriesFile"`
+	Output      string          `json:"output"`
+	Queries     []manifestQuery `json:"queries"`
+}
+
+/*
+ * manifestQuery is the outcome of one query in the run manifest, the run_summary row of the query.
+ */
+type manifestQuery struct {
+	Name       string `json:"name"`
+	Sheet      string `json:"sheet"`
+	Status     string `json:"status"`
+	Rows       int    `json:"rows"`
+	DurationMs int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+	Truncated  bool   `json:"truncated,omitempty"`
+}
+
+/*
+ * manifestToolVersion returns the version recorded in the manifest: the `toolVersion` set at build time, else
+ * the module version or VCS revision of the binary, else "dev".
+ */
+func manifestToolVersion() string {
+	if toolVersion != "dev" {
+		return toolVersion
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return toolVersion
+	}
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return toolVersion + "+" + setting.Value
+		}
+	}
+	return toolVersion
+}
+
+/*
+ * writeRunManifest writes the "<workbook>.manifest.json" manifest of a run next to its workbook.
+ *
+ * Parameters:
+ * - excelFileName: The file name of the saved workbook.
+ * - queriesFile: The queries file or folder of the run.
+ * - queries: The queries of the run, in query order.
+ * - findings: The findings holding the outcome of every query run.
+ * - opts: The run options, `Server` is the server and database recorded.
+ * - started: When the run started.
+ *
+ * Returns:
+ * - The file name of the manifest, or an error if it cannot be written.
+ *
+ * Notes:
+ * - Only the host and database are recorded, never the user or password of the connection.
+ * - Queries without an outcome are Skipped, as on the run_summary sheet.
+ */
+func writeRunManifest(excelFileName string, queriesFile string, queries Queries, findings *ReportFindings, opts RunOptions, started time.Time) (string, error) {
+	manifest := runManifest{
+		ToolVersion: manifestToolVersion(),
+		Started:     started,
+		Finished:    time.Now(),
+		Server:      opts.Server.Host,
+		Database:    opts.Server.Database,
+		QueriesFile: queriesFile,
+		Output:      filepath.Base(excelFileName),
+		Queries:     make([]manifestQuery, 0, len(queries.Queries)),
+	}
+	for i, query := range queries.Queries {
+		entry := manifestQuery{Name: query.Name, Sheet: createSheetName(i+1, query.Name), Status: statusSkipped}
+		if outcome := findings.outcomeOf(entry.Sheet); outcome != nil {
+			entry.Status = outcome.Status
+			entry.Rows = outcome.Rows
+			entry.DurationMs = outcome.Duration.Milliseconds()
+			entry.Error = outcome.Error
+			entry.Truncated = outcome.Truncated
+		}
+		manifest.Queries = append(manifest.Queries, entry)
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode the run manifest: %v", err)
+	}
+	fileName := strings.TrimSuffix(excelFileName, ".xlsx") + ".manifest.json"
+	if err := os.WriteFile(fileName, append(content, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("failed to write the run manifest: %v", err)
+	}
+	return fileName, nil
+}
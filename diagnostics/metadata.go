@@ -0,0 +1,279 @@
+package diagnostics
+
+import (
+	"context"      // For recognizing query deadlines
+	"database/sql" // Database/sql package for database operations
+	"errors"       // For unwrapping query errors
+	"fmt"          // For formatted I/O operations
+	"strings"      // For string manipulation
+	"time"         // For working with date and time
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+// Name of the sheet listing the queries of the run
+const executedQueriesSheetName = "executed_queries"
+
+// Statuses of a query on the executed_queries landing page
+const (
+	statusSuccess = "Success" // The query ran and its result was written
+	statusFailed  = "Failed"  // The query failed
+	statusTimeout = "Timeout" // The query failed on a timeout
+	statusSkipped = "Skipped" // The query was not run, left out by -only or -tag or the run stopped before it
+)
+
+// Name of the sheet holding the QuerySource of the queries file in the `-metadata-only` catalog
+const aboutSheetName = "about"
+
+/*
+ * writeExecutedQueriesSheet writes the query metadata to the "executed_queries" sheet, renaming the
+ * default "Sheet1" of a new workbook so it is the first sheet.
+ *
+ * Parameters:
+ * - f: A pointer to the excelize.File object representing the Excel file.
+ * - queries: The queries of the run.
+ * - opts: The run options, the catalog only lists the queries selected by `-only` and `-tag`.
+ * - catalog: Also write the description and tags of each query, used by `-metadata-only`. Otherwise the status, row
+ *   count and duration headers are written, their values are filled by writeQueryOutcomes once the queries ran.
+ *
+ * Notes:
+ * - A run lists every query, the ones left out show as Skipped, as writeQueryOutcomes fills row Sr.No + 1. The
+ *   catalog leaves them out, the queries listed keep their Sr.No of the queries file.
+ */
+func writeExecutedQueriesSheet(f *excelize.File, queries Queries, opts RunOptions, catalog bool) {
+	f.SetSheetName("Sheet1", executedQueriesSheetName)
+
+	// Write headers for executed_queries sheet
+	headers := []string{"Sr.No", "Query", "Query Notes", "Name"}
+	if catalog {
+		headers = append(headers, "Description", "Tags")
+	} else {
+		headers = append(headers, "Status", "Rows", "Duration (ms)")
+	}
+	for colIndex, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(colIndex+1, 1)
+		f.SetCellValue(executedQueriesSheetName, cell, header)
+	}
+
+	// Write query metadata to executed_queries sheet
+	rowNum := 1
+	for i, query := range queries.Queries {
+		if catalog && !selectedQuery(opts, query) {
+			continue
+		}
+		rowNum++ // Start from row 2 (after header)
+		f.SetCellValue(executedQueriesSheetName, fmt.Sprintf("A%d", rowNum), i+1)
+		f.SetCellValue(executedQueriesSheetName, fmt.Sprintf("B%d", rowNum), query.Query)
+		f.SetCellValue(executedQueriesSheetName, fmt.Sprintf("C%d", rowNum), query.Notes)
+		f.SetCellValue(executedQueriesSheetName, fmt.Sprintf("D%d", rowNum), query.Name)
+		if catalog {
+			f.SetCellValue(executedQueriesSheetName, fmt.Sprintf("E%d", rowNum), query.Description)
+			f.SetCellValue(executedQueriesSheetName, fmt.Sprintf("F%d", rowNum), strings.Join(query.Tags, ", "))
+		}
+	}
+}
+
+/*
+ * queryOutcome is what running one query produced, shown on the executed_queries landing page and the overview sheet.
+ *
+ * Fields:
+ * - Sheet: The result sheet of the query.
+ * - Query: The query.
+ * - Status: statusSuccess, statusFailed or statusTimeout once the query ran.
+ * - Rows: The number of rows written to the result sheet.
+ * - Duration: How long the query took, from execution to the last row written.
+ * - Summary: The value of the query's `summaryColumn` in the first row, empty when not configured or not found.
+ * - Error: The error of a failed query, empty when it succeeded.
+ * - Messages: The informational messages SQL Server sent while the query ran, PRINT and RAISERROR output.
+ * - Truncated: Whether a result sheet of the query was cut by `-max-rows`.
+ * - Alerts: The number of rows meeting the query's `alert` rule.
+ */
+type queryOutcome struct {
+	Sheet     string
+	Query     Query
+	Status    string
+	Rows      int
+	Duration  time.Duration
+	Summary   string
+	Error     string
+	Messages  []string
+	Truncated bool
+	Alerts    int
+}
+
+/*
+ * addOutcome starts the outcome of a query, before the query is executed.
+ */
+func (findings *ReportFindings) addOutcome(query Query, sheetName string) *queryOutcome {
+	outcome := &queryOutcome{Sheet: sheetName, Query: query}
+	findings.Outcomes = append(findings.Outcomes, outcome)
+	return outcome
+}
+
+/*
+ * outcomeOf returns the outcome of a result sheet, nil when the query of that sheet did not run.
+ */
+func (findings *ReportFindings) outcomeOf(sheetName string) *queryOutcome {
+	for _, outcome := range findings.Outcomes {
+		if outcome.Sheet == sheetName {
+			return outcome
+		}
+	}
+	return nil
+}
+
+/*
+ * add counts a written row, taking the summary value from the first row.
+ *
+ * Parameters:
+ * - columns: The column names of the result, matched case insensitively against `summaryColumn` and its label.
+ * - values: The scanned values of the row, each a *interface{}.
+ */
+func (outcome *queryOutcome) add(columns []string, values []interface{}) {
+	outcome.Rows++
+	if outcome.Rows > 1 || outcome.Query.SummaryColumn == "" {
+		return
+	}
+	labels := applyColumnLabels(columns, outcome.Query)
+	for i, column := range columns {
+		if strings.EqualFold(column, outcome.Query.SummaryColumn) || strings.EqualFold(labels[i], outcome.Query.SummaryColumn) {
+			outcome.Summary = cleanCellValue(*(values[i].(*interface{})))
+			return
+		}
+	}
+}
+
+/*
+ * finish records the duration and status of the query, a failure caused by a deadline or a network timeout
+ * is reported as statusTimeout.
+ */
+func (outcome *queryOutcome) finish(duration time.Duration, err error) {
+	outcome.Duration = duration
+	switch {
+	case err == nil:
+		outcome.Status = statusSuccess
+	case errors.Is(err, context.DeadlineExceeded) || strings.Contains(strings.ToLower(err.Error()), "timeout"):
+		outcome.Status, outcome.Error = statusTimeout, err.Error()
+	default:
+		outcome.Status, outcome.Error = statusFailed, err.Error()
+	}
+}
+
+// Fill and font colors of each status on the landing page
+var statusColors = map[string][2]string{
+	statusSuccess: {"C6EFCE", "006100"},
+	statusFailed:  {"FFC7CE", "9C0006"},
+	statusTimeout: {"FFEB9C", "9C5700"},
+	statusSkipped: {"D9D9D9", "595959"},
+}
+
+/*
+ * writeQueryOutcomes completes the executed_queries sheet as the landing page of the report once the queries ran.
+ *
+ * Parameters:
+ * - report: The `excelReport` whose findings hold the outcome of every query run.
+ * - queries: The queries of the run.
+ *
+ * Functionality:
+ * 1. Links the name of every query to its result sheet, when the sheet exists.
+ * 2. Writes the status, color coded with the cached styles, the row count and the duration of every query.
+ *    Queries without an outcome, those left out by `-only` or after the failure that stopped a
+ *    `-stop-on-first-error` run, are Skipped.
+ */
+func writeQueryOutcomes(report *excelReport, queries Queries) {
+	f := report.f
+	for i, query := range queries.Queries {
+		rowNum := i + 2 // Start from row 2 (after header)
+		sheetName := createSheetName(i+1, query.Name)
+
+		status := statusSkipped
+		if outcome := report.findings.outcomeOf(sheetName); outcome != nil {
+			status = outcome.Status
+			f.SetCellValue(executedQueriesSheetName, fmt.Sprintf("F%d", rowNum), outcome.Rows)
+			f.SetCellValue(executedQueriesSheetName, fmt.Sprintf("G%d", rowNum), outcome.Duration.Milliseconds())
+		}
+		statusCell := fmt.Sprintf("E%d", rowNum)
+		f.SetCellValue(executedQueriesSheetName, statusCell, status)
+
+		colors := statusColors[status]
+		styleID, err := report.cellStyle("status_"+status, &excelize.Style{
+			Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{colors[0]}},
+			Font: &excelize.Font{Color: colors[1], Bold: true},
+		})
+		if err != nil {
+			logError("Failed to create the %s status style: %v", status, err)
+		} else {
+			f.SetCellStyle(executedQueriesSheetName, statusCell, statusCell, styleID)
+		}
+
+		if idx, _ := f.GetSheetIndex(sheetName); idx != -1 {
+			f.SetCellHyperLink(executedQueriesSheetName, fmt.Sprintf("D%d", rowNum), fmt.Sprintf("'%s'!A1", sheetName), "Location")
+		}
+	}
+}
+
+/*
+ * writeAboutSheet writes the QuerySource of the queries file as field and value rows in the "about" sheet.
+ */
+func writeAboutSheet(f *excelize.File, source QuerySource) {
+	f.NewSheet(aboutSheetName)
+	f.SetCellValue(aboutSheetName, "A1", "Field")
+	f.SetCellValue(aboutSheetName, "B1", "Value")
+
+	fields := [][2]string{
+		{"Name", source.Name},
+		{"SQL Server Version", source.SQLServerVersion},
+		{"Author", source.Author},
+		{"Last Modified", source.LastModified},
+		{"Source", source.Source},
+		{"URL", source.URL},
+		{"Comments", source.Comments},
+		{"Copyright", source.CopyRight},
+	}
+	for i, field := range fields {
+		f.SetCellValue(aboutSheetName, fmt.Sprintf("A%d", i+2), field[0])
+		f.SetCellValue(aboutSheetName, fmt.Sprintf("B%d", i+2), field[1])
+	}
+}
+
+/*
+ * writeQueryCatalog writes the catalog of the queries file to an Excel file for `-metadata-only` and `-schema-only`,
+ * without running any query.
+ *
+ * Parameters:
+ * - queries: The queries read from the queries file.
+ * - db: The database connection the result columns are described on for `-schema-only`, nil for `-metadata-only`
+ *   alone, which does not connect to SQL Server.
+ * - opts: The run options, the queries selected by `Only` and `Tags` are cataloged, described with the `Params`.
+ * - template: The `-output` template, or the `OUTPUT_PATH` of the configuration for `-schema-only`.
+ * - target: The server and database substituted in the template, empty for `-metadata-only`.
+ *
+ * Functionality:
+ * 1. Writes the "executed_queries" sheet with the name, description and tags of each selected query added.
+ * 2. Writes the QuerySource of the file to the "about" sheet.
+ * 3. With a database connection, writes the result columns of every selected query to the "schemas" sheet, see
+ *    `writeSchemasSheet`.
+ * 4. Saves the workbook as "sql_queries_catalog_<timestamp>.xlsx", in the directory or with the name of the
+ *    template, see `catalogBaseName`.
+ *
+ * Returns:
+ * - The name of the saved workbook, or an error if it cannot be saved.
+ */
+func writeQueryCatalog(queries Queries, db *sql.DB, opts RunOptions, template string, target runTarget) (string, error) {
+	f := excelize.NewFile()
+	writeExecutedQueriesSheet(f, queries, opts, true)
+	writeAboutSheet(f, queries.QuerySource)
+	if db != nil {
+		writeSchemasSheet(f, db, queries, opts)
+	}
+
+	baseName, err := catalogBaseName(template, target, time.Now())
+	if err != nil {
+		return "", err
+	}
+	excelFileName := baseName + ".xlsx"
+	if err := saveWorkbook(f, excelFileName); err != nil {
+		return "", fmt.Errorf("error saving Excel file %s: %v", excelFileName, err)
+	}
+	return excelFileName, nil
+}
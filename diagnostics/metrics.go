@@ -0,0 +1,134 @@
+package diagnostics
+
+import (
+	"fmt"           // For formatted I/O operations
+	"os"            // For writing the metrics file
+	"path/filepath" // For naming the metrics file of each server
+	"strings"       // For string manipulation
+	"time"          // For the run timestamp and durations
+)
+
+/*
+ * metricsFileFor returns the `-metrics-file` of one server of a `-config-dir` run, the server label inserted before
+ * the extension, such as "diag_prod1.prom", so the servers do not overwrite each other's metrics.
+ */
+func metricsFileFor(metricsFile string, label string) string {
+	if metricsFile == "" {
+		return ""
+	}
+	ext := filepath.Ext(metricsFile)
+	return strings.TrimSuffix(metricsFile, ext) + "_" + label + ext
+}
+
+/*
+ * writeMetricsFile writes the `-metrics-file` of a run, in the Prometheus text format read by the node_exporter
+ * textfile collector.
+ *
+ * Parameters:
+ * - fileName: The .prom file written, replaced on every run.
+ * - findings: The findings of the run, nil when the run failed before any query.
+ * - runErr: The error of the run, the configuration, queries file or connection failure.
+ * - started: When the run started.
+ *
+ * Returns:
+ * - An error if the file cannot be written, the previous file is kept then.
+ *
+ * Functionality:
+ * 1. Writes one `diag_query_duration_seconds`, `diag_query_rows` and `diag_query_success` gauge per query run, from
+ *    the outcomes feeding the run_summary sheet, labelled with the server, the database, the query name and its
+ *    sheet, which keeps two queries of the same name apart.
+ * 2. Writes `diag_run_success`, 1 when the run exits with `exitSuccess`, `diag_run_failed_queries`,
+ *    `diag_run_duration_seconds` and `diag_last_run_timestamp`, the Unix time the run ended.
+ *
+ * Notes:
+ * - The file is written to a temporary file renamed over it, so the collector never reads half a file.
+ * - Every format records the outcome of every query run, so the query gauges do not depend on `-format`.
+ * - A run aborted by a fatal error, such as a failing -pre-sql hook, writes no metrics, alert on
+ *   `diag_last_run_timestamp` getting old to catch it.
+ */
+func writeMetricsFile(fileName string, findings *ReportFindings, runErr error, started time.Time) error {
+	var content strings.Builder
+	gauge := func(name string, help string) {
+		fmt.Fprintf(&content, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+
+	var server []string
+	if findings != nil {
+		server = []string{"server", findings.Server.Host, "database", findings.Server.Database}
+	}
+
+	if findings != nil && len(findings.Outcomes) > 0 {
+		gauge("diag_query_duration_seconds", "Duration of the diagnostic query in the last run.")
+		for _, outcome := range findings.Outcomes {
+			fmt.Fprintf(&content, "diag_query_duration_seconds%s %g\n", queryMetricLabels(server, outcome), outcome.Duration.Seconds())
+		}
+		gauge("diag_query_rows", "Rows returned by the diagnostic query in the last run.")
+		for _, outcome := range findings.Outcomes {
+			fmt.Fprintf(&content, "diag_query_rows%s %d\n", queryMetricLabels(server, outcome), outcome.Rows)
+		}
+		gauge("diag_query_success", "1 when the diagnostic query succeeded in the last run, 0 otherwise.")
+		for _, outcome := range findings.Outcomes {
+			fmt.Fprintf(&content, "diag_query_success%s %d\n", queryMetricLabels(server, outcome), boolMetric(outcome.Status == statusSuccess))
+		}
+	}
+
+	failedQueries := 0
+	if findings != nil {
+		failedQueries = findings.FailedQueries
+	}
+	labels := metricLabels(server...)
+	gauge("diag_run_success", "1 when the last run reached the server and every query succeeded, 0 otherwise.")
+	fmt.Fprintf(&content, "diag_run_success%s %d\n", labels, boolMetric(runExitCode(findings, runErr) == exitSuccess))
+	gauge("diag_run_failed_queries", "Queries that failed or timed out in the last run.")
+	fmt.Fprintf(&content, "diag_run_failed_queries%s %d\n", labels, failedQueries)
+	gauge("diag_run_duration_seconds", "Duration of the last run.")
+	fmt.Fprintf(&content, "diag_run_duration_seconds%s %g\n", labels, time.Since(started).Seconds())
+	gauge("diag_last_run_timestamp", "Unix time the last run ended.")
+	fmt.Fprintf(&content, "diag_last_run_timestamp%s %d\n", labels, time.Now().Unix())
+
+	tempFileName := fileName + ".tmp"
+	if err := os.WriteFile(tempFileName, []byte(content.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write the metrics file %s: %v", fileName, err)
+	}
+	if err := os.Rename(tempFileName, fileName); err != nil {
+		os.Remove(tempFileName)
+		return fmt.Errorf("failed to write the metrics file %s: %v", fileName, err)
+	}
+	return nil
+}
+
+/*
+ * queryMetricLabels returns the labels of the gauges of one query, the server labels followed by its name and sheet.
+ */
+func queryMetricLabels(server []string, outcome *queryOutcome) string {
+	return metricLabels(append(append([]string{}, server...), "query", outcome.Query.Name, "sheet", outcome.Sheet)...)
+}
+
+/*
+ * metricLabels formats label name and value pairs as a Prometheus label set, such as {query="WaitStats"}, escaping
+ * the backslashes, double quotes and line breaks of the values. Empty values are left out.
+ */
+func metricLabels(pairs ...string) string {
+	var labels []string
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] == "" {
+			continue
+		}
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, pairs[i], escaper.Replace(pairs[i+1])))
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+/*
+ * boolMetric returns 1 for true and 0 for false.
+ */
+func boolMetric(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
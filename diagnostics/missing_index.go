@@ -0,0 +1,278 @@
+package diagnostics
+
+import (
+	"fmt"     // For formatted I/O operations
+	"regexp"  // For building index names
+	"sort"    // For ordering recommendations by impact
+	"strings" // For string manipulation
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+// Name of the sheet holding the consolidated missing index recommendations
+const recommendationsSheetName = "recommendations"
+
+/*
+ * MissingIndexRecommendation is a single missing index suggestion taken from a row of a missing index DMV result.
+ *
+ * Fields:
+ * - Table: The fully qualified table, the `statement` column of sys.dm_db_missing_index_details.
+ * - EqualityColumns: Columns used in equality predicates.
+ * - InequalityColumns: Columns used in inequality predicates.
+ * - IncludedColumns: Columns to include in the index.
+ * - Impact: The index advantage, user seeks * avg total user cost * avg user impact %.
+ * - Sources: The sheets the recommendation was found in.
+ */
+type MissingIndexRecommendation struct {
+	Table             string   // Fully qualified table
+	EqualityColumns   string   // Equality predicate columns
+	InequalityColumns string   // Inequality predicate columns
+	IncludedColumns   string   // Included columns
+	Impact            float64  // Index advantage used for prioritizing
+	Sources           []string // Sheets the recommendation came from
+}
+
+/*
+ * missingIndexColumns holds the positions of the standard missing index DMV columns within a result.
+ * A position of -1 means the result does not have the column.
+ */
+type missingIndexColumns struct {
+	table        int
+	equality     int
+	inequality   int
+	included     int
+	advantage    int
+	userSeeks    int
+	avgTotalCost int
+	avgImpact    int
+}
+
+/*
+ * normalizeColumnName lowercases a column name and drops everything but letters and digits, so
+ * "Index Advantage", "index_advantage" and "[index_advantage]" all compare equal.
+ */
+func normalizeColumnName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+/*
+ * findMissingIndexColumns recognizes the missing index DMV columns in a result.
+ *
+ * Parameters:
+ * - columns: The column names returned by the query.
+ *
+ * Returns:
+ * - The column positions, or nil when the result does not carry a table and at least one of the
+ *   equality or inequality columns, in which case it is not a missing index result.
+ *
+ * Notes:
+ * - The table is recognized as `statement` or `Database.Schema.Table`, the impact as `index_advantage`
+ *   or computed from `user_seeks`, `avg_total_user_cost` and `avg_user_impact` when those are present.
+ */
+func findMissingIndexColumns(columns []string) *missingIndexColumns {
+	m := &missingIndexColumns{table: -1, equality: -1, inequality: -1, included: -1, advantage: -1, userSeeks: -1, avgTotalCost: -1, avgImpact: -1}
+	for i, column := range columns {
+		switch normalizeColumnName(column) {
+		case "statement", "databaseschematable":
+			m.table = i
+		case "equalitycolumns":
+			m.equality = i
+		case "inequalitycolumns":
+			m.inequality = i
+		case "includedcolumns":
+			m.included = i
+		case "indexadvantage":
+			m.advantage = i
+		case "userseeks":
+			m.userSeeks = i
+		case "avgtotalusercost":
+			m.avgTotalCost = i
+		case "avguserimpact":
+			m.avgImpact = i
+		}
+	}
+	if m.table == -1 || (m.equality == -1 && m.inequality == -1) {
+		return nil
+	}
+	return m
+}
+
+/*
+ * recommendation builds a MissingIndexRecommendation from a scanned row, returning false when the row
+ * has no table or no key columns.
+ */
+func (m *missingIndexColumns) recommendation(values []interface{}, sheetName string) (MissingIndexRecommendation, bool) {
+	text := func(index int) string {
+		if index == -1 {
+			return ""
+		}
+		v := *(values[index].(*interface{}))
+		if v == nil {
+			return ""
+		}
+		return strings.TrimSpace(cleanCellValue(v))
+	}
+	number := func(index int) float64 {
+		if index == -1 {
+			return 0
+		}
+		n, _ := numericValue(*(values[index].(*interface{})))
+		return n
+	}
+
+	recommendation := MissingIndexRecommendation{
+		Table:             text(m.table),
+		EqualityColumns:   text(m.equality),
+		InequalityColumns: text(m.inequality),
+		IncludedColumns:   text(m.included),
+		Sources:           []string{sheetName},
+	}
+	if m.advantage != -1 {
+		recommendation.Impact = number(m.advantage)
+	} else {
+		recommendation.Impact = number(m.userSeeks) * number(m.avgTotalCost) * number(m.avgImpact) * 0.01
+	}
+
+	if recommendation.Table == "" || (recommendation.EqualityColumns == "" && recommendation.InequalityColumns == "") {
+		return recommendation, false
+	}
+	return recommendation, true
+}
+
+/*
+ * consolidateMissingIndexes merges recommendations for the same table and columns, found by several
+ * queries, keeping the highest impact, and orders them by impact descending.
+ */
+func consolidateMissingIndexes(recommendations []MissingIndexRecommendation) []MissingIndexRecommendation {
+	var consolidated []MissingIndexRecommendation
+	positions := make(map[string]int)
+
+	for _, r := range recommendations {
+		key := strings.ToLower(strings.Join([]string{r.Table, r.EqualityColumns, r.InequalityColumns, r.IncludedColumns}, "|"))
+		if pos, ok := positions[key]; ok {
+			existing := &consolidated[pos]
+			if r.Impact > existing.Impact {
+				existing.Impact = r.Impact
+			}
+			for _, source := range r.Sources {
+				if !containsString(existing.Sources, source) {
+					existing.Sources = append(existing.Sources, source)
+				}
+			}
+			continue
+		}
+		positions[key] = len(consolidated)
+		consolidated = append(consolidated, r)
+	}
+
+	sort.SliceStable(consolidated, func(i, j int) bool {
+		return consolidated[i].Impact > consolidated[j].Impact
+	})
+	return consolidated
+}
+
+/*
+ * containsString reports whether the slice holds the value.
+ */
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+ * createIndexStatement generates the CREATE INDEX statement for a recommendation. Key columns are the
+ * equality columns followed by the inequality columns, as recommended for missing index suggestions.
+ *
+ * Example:
+ * Input: Table = "[db].[dbo].[Orders]", EqualityColumns = "[CustomerID]", IncludedColumns = "[OrderDate]"
+ * Output: "CREATE NONCLUSTERED INDEX [IX_Orders_CustomerID] ON [db].[dbo].[Orders] ([CustomerID]) INCLUDE ([OrderDate]);"
+ */
+func createIndexStatement(r MissingIndexRecommendation) string {
+	var keyColumns []string
+	for _, columns := range []string{r.EqualityColumns, r.InequalityColumns} {
+		if columns != "" {
+			keyColumns = append(keyColumns, columns)
+		}
+	}
+	keys := strings.Join(keyColumns, ", ")
+
+	statement := fmt.Sprintf("CREATE NONCLUSTERED INDEX [%s] ON %s (%s)", missingIndexName(r.Table, keys), r.Table, keys)
+	if r.IncludedColumns != "" {
+		statement += fmt.Sprintf(" INCLUDE (%s)", r.IncludedColumns)
+	}
+	return statement + ";"
+}
+
+/*
+ * missingIndexName builds an index name from the table name and key columns, "IX_<table>_<col>_<col>",
+ * limited to the 128 characters SQL Server allows for identifiers.
+ */
+func missingIndexName(table string, keys string) string {
+	re := regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+	parts := strings.Split(table, ".")
+	tableName := re.ReplaceAllString(parts[len(parts)-1], "")
+
+	var columnNames []string
+	for _, column := range strings.Split(keys, ",") {
+		if name := re.ReplaceAllString(column, ""); name != "" {
+			columnNames = append(columnNames, name)
+		}
+	}
+
+	name := "IX_" + tableName + "_" + strings.Join(columnNames, "_")
+	if len(name) > 128 {
+		name = name[:128]
+	}
+	return name
+}
+
+/*
+ * writeRecommendationsSheet writes the consolidated missing index recommendations for `-explain-missing-index`.
+ *
+ * Parameters:
+ * - f: A pointer to the excelize.File object representing the Excel file.
+ * - recommendations: The recommendations collected across all executed queries.
+ *
+ * Functionality:
+ * 1. Merges duplicate recommendations and orders them by impact, highest first.
+ * 2. Writes one row per recommendation with its priority, impact, table, columns, source sheets
+ *    and the generated CREATE INDEX statement.
+ *
+ * Notes:
+ * - When no result carried the missing index DMV columns, the sheet only holds a note saying so.
+ * - The statements are suggestions, review them against the existing indexes before creating any.
+ */
+func writeRecommendationsSheet(f *excelize.File, recommendations []MissingIndexRecommendation) {
+	f.NewSheet(recommendationsSheetName)
+
+	if len(recommendations) == 0 {
+		logInfo("No missing index results found, the %s sheet is empty", recommendationsSheetName)
+		f.SetCellValue(recommendationsSheetName, "A1", "No results with the missing index columns (statement, equality_columns, inequality_columns) were found.")
+		return
+	}
+
+	headers := []string{"Priority", "Impact", "Table", "Equality Columns", "Inequality Columns", "Included Columns", "Source Sheets", "Create Index Statement"}
+	for colIndex, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(colIndex+1, 1)
+		f.SetCellValue(recommendationsSheetName, cell, header)
+	}
+
+	for i, r := range consolidateMissingIndexes(recommendations) {
+		row := []interface{}{i + 1, r.Impact, r.Table, r.EqualityColumns, r.InequalityColumns, r.IncludedColumns, strings.Join(r.Sources, ", "), createIndexStatement(r)}
+		for colIndex, value := range row {
+			cell, _ := excelize.CoordinatesToCellName(colIndex+1, i+2)
+			f.SetCellValue(recommendationsSheetName, cell, value)
+		}
+	}
+}
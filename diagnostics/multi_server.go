@@ -0,0 +1,114 @@
+package diagnostics
+
+import (
+	"context"       // For stopping an interrupted run
+	"fmt"           // For formatted I/O operations
+	"os"            // For listing the configuration folder
+	"path/filepath" // For building the configuration file paths
+	"sort"          // For running the servers in name order
+	"strings"       // For string manipulation
+)
+
+// Extensions of the configuration files of a `-config-dir` folder, the plaintext and the `-encrypt-config` files
+var serverConfigExtensions = []string{".properties", ".properties.enc"}
+
+/*
+ * serverConfigFiles lists the configuration files of a `-config-dir` folder, in name order.
+ *
+ * Returns:
+ * - The paths of the files, or an error if the folder cannot be read or holds no configuration file.
+ */
+func serverConfigFiles(configDir string) ([]string, error) {
+	entries, err := os.ReadDir(configDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", configDir, err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		for _, extension := range serverConfigExtensions {
+			if strings.HasSuffix(strings.ToLower(entry.Name()), extension) {
+				files = append(files, filepath.Join(configDir, entry.Name()))
+				break
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s holds no %s file", configDir, strings.Join(serverConfigExtensions, " or "))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+/*
+ * serverLabel returns the name a server's output files are prefixed with, the configuration file name without
+ * its extensions, reduced to letters, digits and underscores as for sheet names.
+ */
+func serverLabel(configFile string) string {
+	name := filepath.Base(configFile)
+	for _, extension := range serverConfigExtensions {
+		if strings.HasSuffix(strings.ToLower(name), extension) {
+			name = name[:len(name)-len(extension)]
+			break
+		}
+	}
+	return sheetNameDroppedCharacters.ReplaceAllString(strings.ReplaceAll(name, " ", "_"), "")
+}
+
+/*
+ * runServers runs the queries against every server configured in a `-config-dir` folder, one after the other.
+ *
+ * Parameters:
+ * - ctx: The context of the run, the servers not started yet are skipped once it is cancelled.
+ * - configDir: The folder holding one properties file per server.
+ * - sqlQueries: The path to the queries file, run against every server.
+ * - opts: The run options, the same for every server.
+ *
+ * Returns:
+ * - The exit code of the run, the highest `runExitCode` of the servers, or an error if the folder holds no
+ *   configuration file.
+ *
+ * Functionality:
+ * 1. Runs `executeSQLQueries` with each configuration file, on its own connection pool closed once done.
+ * 2. Prefixes the output files of each server with its label, so every server gets its own
+ *    "sql_diagnostics_<label>_<timestamp>" files, and its own `-metrics-file` suffixed with the label.
+ * 3. Continues with the next server when one cannot be configured or reached, and prints a summary of the
+ *    failed servers at the end. A server whose queries failed is counted as succeeded, its exit code is kept.
+ */
+func runServers(ctx context.Context, configDir string, sqlQueries string, opts RunOptions) (int, error) {
+	files, err := serverConfigFiles(configDir)
+	if err != nil {
+		return 0, err
+	}
+
+	var failures []string
+	exitCode := exitSuccess
+	for i, file := range files {
+		if ctx.Err() != nil {
+			logWarn("Interrupted, the remaining %d server(s) were not run.", len(files)-i)
+			break
+		}
+		label := serverLabel(file)
+		logInfo("=== Server %d of %d: %s (%s) ===", i+1, len(files), label, file)
+
+		serverOpts := opts
+		serverOpts.FilePrefix = label + "_"
+		serverOpts.MetricsFile = metricsFileFor(opts.MetricsFile, label)
+		pool := &connectionPool{}
+		findings, err := executeSQLQueries(ctx, file, sqlQueries, serverOpts, pool)
+		pool.close()
+		exitCode = max(exitCode, runExitCode(findings, err))
+		if err != nil {
+			logError("Server %s failed: %v", label, err)
+			failures = append(failures, fmt.Sprintf("%s: %v", label, err))
+		}
+	}
+
+	logInfo("Ran the queries against %d server(s), %d succeeded and %d failed.", len(files), len(files)-len(failures), len(failures))
+	for _, failure := range failures {
+		logInfo("  %s", failure)
+	}
+	return exitCode, nil
+}
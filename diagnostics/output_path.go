@@ -0,0 +1,104 @@
+package diagnostics
+
+import (
+	"fmt"           // For formatted I/O operations
+	"os"            // For creating the output directory
+	"path/filepath" // For splitting and joining the output path
+	"regexp"        // For reducing the substituted values to file name characters
+	"strings"       // For string manipulation
+	"time"          // For the timestamp of the output file names
+)
+
+// Timestamp of the output file names, as in sql_diagnostics_DDMMYYYY_HHMMSS.xlsx
+const outputTimestampFormat = "02012006_150405"
+
+// Characters of a server or database name that are not kept in a file name
+var outputNameDroppedCharacters = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+/*
+ * outputBaseName returns the path of the output files of a run without their extension, and creates its
+ * directory when missing.
+ *
+ * Parameters:
+ * - template: The `-output` flag or the `OUTPUT_PATH` property, a directory or a file name template. Empty keeps
+ *   the default "sql_diagnostics_<timestamp>" name in the working directory.
+ * - target: The server and database of the run, substituted for `{server}` and `{db}`.
+ * - prefix: The prefix of the default file name, the server label of a `-config-dir` run.
+ * - now: The start time of the run, substituted for `{timestamp}`.
+ *
+ * Returns:
+ * - The output path without extension, each format adds its own, or an error if the directory cannot be created.
+ *
+ * Notes:
+ * - A template ending with a path separator, or naming an existing directory, writes the default file name there.
+ * - Otherwise its last element is the file name, a trailing .xlsx is dropped so the other formats share the name.
+ * - The `prefix` is put before a template file name without `{server}`, so the servers of a `-config-dir` run do
+ *   not overwrite each other's files.
+ */
+func outputBaseName(template string, target runTarget, prefix string, now time.Time) (string, error) {
+	return resolveOutputBaseName(template, target, prefix, "sql_diagnostics_"+prefix+now.Format(outputTimestampFormat), now)
+}
+
+/*
+ * catalogBaseName returns the path of the `-metadata-only` and `-schema-only` catalog without its extension,
+ * resolved from the `-output` template as the output of a run is, see `outputBaseName`, with
+ * "sql_queries_catalog_<timestamp>" as the default file name.
+ */
+func catalogBaseName(template string, target runTarget, now time.Time) (string, error) {
+	return resolveOutputBaseName(template, target, "", "sql_queries_catalog_"+now.Format(outputTimestampFormat), now)
+}
+
+/*
+ * resolveOutputBaseName resolves an `-output` template to a path without extension, the work of `outputBaseName`
+ * and `catalogBaseName`, `defaultName` being the file name used without a template or in a template directory.
+ */
+func resolveOutputBaseName(template string, target runTarget, prefix string, defaultName string, now time.Time) (string, error) {
+	timestamp := now.Format(outputTimestampFormat)
+
+	template = strings.TrimSpace(template)
+	if template == "" {
+		return defaultName, nil
+	}
+
+	database := target.Database
+	if database == "" {
+		database = "default"
+	}
+	substituted := strings.NewReplacer(
+		"{server}", outputNameDroppedCharacters.ReplaceAllString(target.Host, "_"),
+		"{db}", outputNameDroppedCharacters.ReplaceAllString(database, "_"),
+		"{timestamp}", timestamp,
+	).Replace(template)
+
+	isDir := strings.HasSuffix(substituted, "/") || strings.HasSuffix(substituted, `\`)
+	if info, err := os.Stat(substituted); err == nil && info.IsDir() {
+		isDir = true
+	}
+
+	dir, name := substituted, defaultName
+	if !isDir {
+		dir, name = filepath.Dir(substituted), filepath.Base(substituted)
+		if strings.EqualFold(filepath.Ext(name), ".xlsx") {
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+		}
+		if !strings.Contains(template, "{server}") {
+			name = prefix + name
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create the output directory %s: %v", dir, err)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+/*
+ * outputTemplate returns the output directory or file name template of a run, the `-output` flag or else the
+ * `OUTPUT_PATH` property of the server's configuration.
+ */
+func outputTemplate(opts RunOptions, sqlConfig SQLServerConfig) string {
+	if opts.Output != "" {
+		return opts.Output
+	}
+	return sqlConfig.OutputPath
+}
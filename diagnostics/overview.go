@@ -0,0 +1,60 @@
+package diagnostics
+
+import (
+	"fmt" // For formatted I/O operations
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+// Name of the sheet holding the one row per query digest of `-overview`
+const overviewSheetName = "overview"
+
+/*
+ * writeOverviewSheet writes the "overview" sheet, one row per query with its description, notes and a one line
+ * result highlight.
+ *
+ * Parameters:
+ * - f: A pointer to the excelize.File object representing the Excel file.
+ * - outcomes: The outcomes of the queries run, in query order.
+ *
+ * Notes:
+ * - The highlight is the `summaryColumn` value of the first row, or the row count when the query has no
+ *   summary column, the column is missing or the result is empty.
+ * - Each query name links to its result sheet.
+ */
+func writeOverviewSheet(f *excelize.File, outcomes []*queryOutcome) {
+	if idx, _ := f.GetSheetIndex(overviewSheetName); idx == -1 {
+		f.NewSheet(overviewSheetName)
+	}
+
+	headers := []string{"Sr.No", "Name", "Description", "Notes", "Highlight", "Rows", "Status"}
+	for colIndex, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(colIndex+1, 1)
+		f.SetCellValue(overviewSheetName, cell, header)
+	}
+
+	for i, entry := range outcomes {
+		rowNum := i + 2 // Start from row 2 (after header)
+		f.SetCellValue(overviewSheetName, fmt.Sprintf("A%d", rowNum), i+1)
+		f.SetCellValue(overviewSheetName, fmt.Sprintf("B%d", rowNum), entry.Query.Name)
+		f.SetCellValue(overviewSheetName, fmt.Sprintf("C%d", rowNum), entry.Query.Description)
+		f.SetCellValue(overviewSheetName, fmt.Sprintf("D%d", rowNum), entry.Query.Notes)
+		f.SetCellValue(overviewSheetName, fmt.Sprintf("F%d", rowNum), entry.Rows)
+
+		if entry.Error != "" {
+			f.SetCellValue(overviewSheetName, fmt.Sprintf("G%d", rowNum), entry.Status+": "+entry.Error)
+			continue
+		}
+		f.SetCellValue(overviewSheetName, fmt.Sprintf("G%d", rowNum), entry.Status)
+
+		if entry.Summary != "" {
+			f.SetCellValue(overviewSheetName, fmt.Sprintf("E%d", rowNum), fmt.Sprintf("%s: %s", entry.Query.SummaryColumn, entry.Summary))
+		} else {
+			f.SetCellValue(overviewSheetName, fmt.Sprintf("E%d", rowNum), fmt.Sprintf("%d row(s)", entry.Rows))
+		}
+
+		if idx, _ := f.GetSheetIndex(entry.Sheet); idx != -1 {
+			f.SetCellHyperLink(overviewSheetName, fmt.Sprintf("B%d", rowNum), fmt.Sprintf("'%s'!A1", entry.Sheet), "Location")
+		}
+	}
+}
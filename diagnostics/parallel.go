@@ -0,0 +1,293 @@
+package diagnostics
+
+import (
+	"context"      // For stopping the workers once the run stops
+	"database/sql" // Database/sql package for database operations
+	"fmt"          // For formatted I/O operations
+	"sync"         // For the worker pool
+	"time"         // For measuring the query durations
+)
+
+/*
+ * resultRows is what the Excel sheets read the result sets of a query from, *sql.Rows while the query runs,
+ * or the rows prefetched in memory by a `-parallel` worker.
+ */
+type resultRows interface {
+	rowSource
+	Columns() ([]string, error)
+	ColumnTypes() ([]*sql.ColumnType, error)
+	NextResultSet() bool
+}
+
+/*
+ * prefetchedResultSet is one result set read into memory, its rows in the order the server returned them.
+ */
+type prefetchedResultSet struct {
+	columns     []string
+	columnTypes []*sql.ColumnType
+	rows        *bufferedRows
+}
+
+/*
+ * prefetchedRows holds every result set of a query run by a `-parallel` worker and hands them out like *sql.Rows.
+ *
+ * Fields:
+ * - sets: The result sets of the batch.
+ * - set: The index of the current result set.
+ */
+type prefetchedRows struct {
+	sets []prefetchedResultSet
+	set  int
+}
+
+/*
+ * prefetchRows reads every result set of `rows` into memory, at most `limit` rows of each when it is above 0,
+ * see `prefetchRowLimit`.
+ *
+ * Notes:
+ * - The error that ended the reading of a result set is returned by Err once that result set is read, no
+ *   further result set is read after it.
+ */
+func prefetchRows(rows *sql.Rows, limit int) (*prefetchedRows, error) {
+	prefetched := &prefetchedRows{}
+	for {
+		columns, err := rows.Columns()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get columns: %v", err)
+		}
+		columnTypes, err := rows.ColumnTypes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get column types: %v", err)
+		}
+
+		buffered := readRows(rows, len(columns), limit)
+		prefetched.sets = append(prefetched.sets, prefetchedResultSet{columns: columns, columnTypes: columnTypes, rows: buffered})
+
+		if buffered.err != nil || !rows.NextResultSet() {
+			return prefetched, nil
+		}
+	}
+}
+
+// Columns returns the column names of the current result set
+func (p *prefetchedRows) Columns() ([]string, error) {
+	return p.sets[p.set].columns, nil
+}
+
+// ColumnTypes returns the column types of the current result set
+func (p *prefetchedRows) ColumnTypes() ([]*sql.ColumnType, error) {
+	return p.sets[p.set].columnTypes, nil
+}
+
+// Next moves to the next row of the current result set
+func (p *prefetchedRows) Next() bool {
+	return p.sets[p.set].rows.Next()
+}
+
+// Scan copies the current row into the destinations
+func (p *prefetchedRows) Scan(dest ...interface{}) error {
+	return p.sets[p.set].rows.Scan(dest...)
+}
+
+// Err returns the error that ended the reading of the current result set
+func (p *prefetchedRows) Err() error {
+	return p.sets[p.set].rows.Err()
+}
+
+// NextResultSet moves to the next result set
+func (p *prefetchedRows) NextResultSet() bool {
+	if p.set+1 >= len(p.sets) {
+		return false
+	}
+	p.set++
+	return true
+}
+
+/*
+ * prefetchedQuery is what a `-parallel` worker produced for one query.
+ *
+ * Fields:
+ * - rows: The result sets of the query, nil when it failed.
+ * - err: The error of the query, nil when it succeeded.
+ * - duration: How long the query took, from execution to its last row read.
+ * - timing: The server times for `-statistics-time`, nil when not enabled.
+ * - messages: The informational messages SQL Server sent while the query ran, nil when it did not run.
+ * - free: Frees the `-max-buffered-results` slot the query holds, nil without a limit, see `releaseBuffer`.
+ */
+type prefetchedQuery struct {
+	rows     *prefetchedRows
+	err      error
+	duration time.Duration
+	timing   *queryTiming
+	messages *queryMessages
+	free     func()
+}
+
+/*
+ * releaseBuffer frees the `-max-buffered-results` slot of the query once the caller wrote its results, letting
+ * a waiting worker run the next query.
+ */
+func (p prefetchedQuery) releaseBuffer() {
+	if p.free != nil {
+		p.free()
+	}
+}
+
+/*
+ * prefetchQueries runs the selected queries on `opts.Parallel` workers while the caller writes their sheets in
+ * the original order.
+ *
+ * Parameters:
+ * - ctx: The context of the run, the workers stop when it is cancelled.
+ * - db: The database connection, shared by the workers through its connection pool.
+ * - queries: The queries of the run.
+ * - opts: The run options, every query runs with its own `-query-timeout`, and `-load-guard` is checked before each.
+ * - kept: The queries whose results are kept from the `-resume-workbook`, keyed by sheet name, they are not run.
+ *
+ * Returns:
+ * - One channel per query receiving its prefetchedQuery, nil for the queries left out by `-only` or `-tag` or kept.
+ * - The function stopping the workers, queries not started yet are then not run and running ones are cancelled.
+ *
+ * Notes:
+ * - Only the execution runs concurrently, the results are held in memory until the caller writes them, as
+ *   the Excel file cannot be written from several goroutines. `opts.MaxBufferedResults` bounds how many, see
+ *   `runAhead`, the caller calls `releaseBuffer` once it wrote a result.
+ * - A failing query only fails its own prefetchedQuery, the workers continue with the next queries.
+ */
+func prefetchQueries(parent context.Context, db *sql.DB, queries Queries, opts RunOptions, kept map[string]keptQuery) ([]chan prefetchedQuery, context.CancelFunc) {
+	var selected []int
+	for i, query := range queries.Queries {
+		if _, ok := kept[createSheetName(i+1, query.Name)]; !ok && selectedQuery(opts, query) {
+			selected = append(selected, i)
+		}
+	}
+	return runAhead(parent, len(queries.Queries), selected, opts.Parallel, opts.MaxBufferedResults, func(ctx context.Context, i int) prefetchedQuery {
+		return prefetchQuery(ctx, db, queries.Queries[i], opts)
+	})
+}
+
+/*
+ * runAhead is the worker pool of `prefetchQueries`, running `run` for every index of `selected` on `workers`
+ * goroutines, in the order of `selected`.
+ *
+ * Parameters:
+ * - parent: The context of the run, the workers stop when it is cancelled.
+ * - count: The number of queries, the length of the returned slice.
+ * - selected: The indexes of the queries to run, in increasing order.
+ * - workers: The number of goroutines running queries at once.
+ * - maxBuffered: The largest number of results taken and not released yet, running ones included, 0 for no limit.
+ * - run: Runs the query of an index.
+ *
+ * Returns:
+ * - One channel per query receiving its prefetchedQuery, nil for the indexes not selected.
+ * - The function stopping the workers and waiting for them to return.
+ *
+ * Notes:
+ * - With `maxBuffered` a worker takes a slot before it takes the next index, and the slot is freed by the
+ *   prefetchedQuery's `releaseBuffer`. As the indexes are taken in order, the result the caller waits for is always
+ *   running or done, the limit cannot deadlock the in order writing.
+ * - Once the context is cancelled every index left gets the context error, so a caller waiting for one returns.
+ */
+func runAhead(parent context.Context, count int, selected []int, workers int, maxBuffered int, run func(ctx context.Context, i int) prefetchedQuery) ([]chan prefetchedQuery, context.CancelFunc) {
+	ctx, stop := context.WithCancel(parent)
+	results := make([]chan prefetchedQuery, count)
+	jobs := make(chan int, len(selected))
+	for _, i := range selected {
+		results[i] = make(chan prefetchedQuery, 1)
+		jobs <- i
+	}
+	close(jobs)
+
+	var slots chan struct{}
+	if maxBuffered > 0 {
+		slots = make(chan struct{}, maxBuffered)
+	}
+	takeSlot := func() bool {
+		if slots == nil {
+			return true
+		}
+		select {
+		case slots <- struct{}{}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	freeSlot := func() { <-slots }
+
+	var pool sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		pool.Add(1)
+		go func() {
+			defer pool.Done()
+			for {
+				held := takeSlot()
+				i, ok := <-jobs
+				if !ok {
+					if held && slots != nil {
+						freeSlot()
+					}
+					return
+				}
+				if !held || ctx.Err() != nil {
+					if held && slots != nil {
+						freeSlot()
+					}
+					results[i] <- prefetchedQuery{err: ctx.Err()}
+					continue
+				}
+				fetched := run(ctx, i)
+				if slots != nil {
+					fetched.free = sync.OnceFunc(freeSlot)
+				}
+				results[i] <- fetched
+			}
+		}()
+	}
+	return results, func() {
+		stop()
+		pool.Wait()
+	}
+}
+
+/*
+ * prefetchQuery runs one query for a `-parallel` worker and reads all its result sets.
+ */
+func prefetchQuery(parent context.Context, db *sql.DB, query Query, opts RunOptions) prefetchedQuery {
+	if opts.LoadGuard {
+		waitForServerLoad(db, opts)
+	}
+
+	messageCtx, messages := withQueryMessages(parent)
+	ctx, cancel, timing := queryContext(messageCtx, opts, query.Name)
+	defer cancel()
+	started := time.Now()
+
+	rows, release, err := runQueryRows(ctx, db, opts, query, excelQueryText(ctx, opts, query))
+	if err != nil {
+		return prefetchedQuery{err: queryTimeoutError(ctx, opts, fmt.Errorf("failed to execute query: %v", err)), duration: time.Since(started), timing: timing, messages: messages}
+	}
+	defer release()
+
+	prefetched, err := prefetchRows(rows, prefetchRowLimit(opts, query))
+	if err == nil && len(prefetched.sets) > 0 {
+		err = prefetched.sets[len(prefetched.sets)-1].rows.err
+	}
+	return prefetchedQuery{rows: prefetched, err: queryTimeoutError(ctx, opts, err), duration: time.Since(started), timing: timing, messages: messages}
+}
+
+/*
+ * writePrefetchedQuery writes the results a `-parallel` worker fetched for a query to its Excel sheets.
+ *
+ * Returns:
+ * - How long the query took, from execution to its last row written.
+ * - The error of the query or of writing its results, nil when both succeeded.
+ */
+func writePrefetchedQuery(fetched prefetchedQuery, query Query, report *excelReport, sheetName string) (time.Duration, error) {
+	if fetched.err != nil {
+		return fetched.duration, fetched.err
+	}
+	started := time.Now()
+	err := writeQueryResults(fetched.rows, query, report, sheetName)
+	return fetched.duration + time.Since(started), err
+}
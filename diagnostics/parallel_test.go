@@ -0,0 +1,122 @@
+package diagnostics
+
+import (
+	"context"     // For cancelling the worker pool
+	"errors"      // For checking the error of the cancelled queries
+	"sync/atomic" // For counting the results held at once
+	"testing"     // For the test framework
+	"time"        // For slowing down the queries and the writer
+)
+
+/*
+ * TestRunAheadBoundsBufferedResults runs more queries than workers with a writer slower than the queries, and
+ * checks the results taken and not released never exceed -max-buffered-results, while every result still arrives
+ * in order.
+ */
+func TestRunAheadBoundsBufferedResults(t *testing.T) {
+	tests := []struct {
+		name        string
+		workers     int
+		maxBuffered int
+		wantMax     int32
+	}{
+		{name: "limit below the workers", workers: 4, maxBuffered: 2, wantMax: 2},
+		{name: "limit above the workers", workers: 2, maxBuffered: 5, wantMax: 5},
+		{name: "single slot", workers: 3, maxBuffered: 1, wantMax: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const count = 20
+			selected := make([]int, count)
+			for i := range selected {
+				selected[i] = i
+			}
+
+			var held, peak atomic.Int32
+			results, stop := runAhead(context.Background(), count, selected, tt.workers, tt.maxBuffered, func(ctx context.Context, i int) prefetchedQuery {
+				current := held.Add(1)
+				for {
+					old := peak.Load()
+					if current <= old || peak.CompareAndSwap(old, current) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				return prefetchedQuery{duration: time.Duration(i)}
+			})
+			defer stop()
+
+			for i := 0; i < count; i++ {
+				fetched := <-results[i]
+				if fetched.duration != time.Duration(i) {
+					t.Fatalf("result %d arrived for query %d", fetched.duration, i)
+				}
+				time.Sleep(2 * time.Millisecond)
+				held.Add(-1)
+				fetched.releaseBuffer()
+			}
+			if got := peak.Load(); got > tt.wantMax {
+				t.Errorf("peak buffered results = %d, want at most %d", got, tt.wantMax)
+			}
+		})
+	}
+}
+
+/*
+ * TestRunAheadWithoutLimit checks the workers run ahead of a writer that has not read anything yet when there is
+ * no limit, the behaviour before -max-buffered-results.
+ */
+func TestRunAheadWithoutLimit(t *testing.T) {
+	const count = 8
+	selected := []int{0, 1, 2, 3, 4, 5, 6, 7}
+	var ran atomic.Int32
+	results, stop := runAhead(context.Background(), count, selected, 2, 0, func(ctx context.Context, i int) prefetchedQuery {
+		ran.Add(1)
+		return prefetchedQuery{}
+	})
+	defer stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for ran.Load() < count && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := ran.Load(); got != count {
+		t.Fatalf("ran %d queries ahead of the writer, want %d", got, count)
+	}
+	for i := range results {
+		if fetched := <-results[i]; fetched.free != nil {
+			t.Errorf("query %d holds a buffer slot without a limit", i)
+		}
+	}
+}
+
+/*
+ * TestRunAheadCancelled stops the pool while the workers wait for a slot, and checks every selected query not
+ * run still gets the context error, so the writer waiting for it does not hang.
+ */
+func TestRunAheadCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	selected := []int{0, 2, 3, 5}
+	results, stop := runAhead(ctx, 6, selected, 2, 1, func(ctx context.Context, i int) prefetchedQuery {
+		return prefetchedQuery{}
+	})
+	defer stop()
+
+	if results[1] != nil || results[4] != nil {
+		t.Fatalf("queries not selected got a channel")
+	}
+	first := <-results[0]
+	cancel()
+	first.releaseBuffer()
+
+	for _, i := range selected[1:] {
+		select {
+		case fetched := <-results[i]:
+			if fetched.err != nil && !errors.Is(fetched.err, context.Canceled) {
+				t.Errorf("query %d failed with %v, want the context error", i, fetched.err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("query %d never got a result after the cancellation", i)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+//go:build parquet
+
+package diagnostics
+
+import (
+	"database/sql" // Database/sql package for column type information
+	"fmt"          // For formatted I/O operations
+	"math/big"     // For converting decimal text to unscaled integers
+	"os"           // For creating the Parquet files
+	"strings"      // For string manipulation
+	"time"         // For working with date and time
+
+	"github.com/parquet-go/parquet-go" // For writing Apache Parquet files
+)
+
+/*
+ * The Parquet writer is only built with `-tags parquet`, keeping the Parquet library out of the default binary:
+ *
+ *   go build -tags parquet
+ */
+func init() {
+	resultWriterFactories["parquet"] = newParquetWriter
+}
+
+// Kinds of Parquet columns a SQL Server column type is mapped to
+const (
+	parquetString    = iota // UTF8 string, the fallback for every other type
+	parquetInt64            // INT(64) for the integer types
+	parquetDouble           // DOUBLE for FLOAT and REAL
+	parquetBoolean          // BOOLEAN for BIT
+	parquetDecimal          // DECIMAL backed by INT64 for DECIMAL and NUMERIC up to 18 digits
+	parquetTimestamp        // TIMESTAMP in microseconds for the date and time types
+	parquetBytes            // BYTE_ARRAY for the binary types
+)
+
+/*
+ * parquetColumn is a result column with the Parquet field it is written to.
+ */
+type parquetColumn struct {
+	field string // Unique field name in the Parquet schema
+	kind  int    // One of the parquet* kinds
+	scale int    // Decimal scale for parquetDecimal
+}
+
+/*
+ * parquetWriter is the ResultWriter for `-format=parquet`. Every result is written to its own
+ * "<baseName>_<name>.parquet" file with a schema inferred from the driver column types.
+ *
+ * Fields:
+ * - baseName: The timestamped prefix of the output files.
+ * - file: The file of the current result.
+ * - writer: The Parquet writer of the current result.
+ * - columns: The Parquet columns of the current result.
+ * - fileNames: The files written so far, listed once the writer is closed.
+ */
+type parquetWriter struct {
+	baseName  string
+	file      *os.File
+	writer    *parquet.Writer
+	columns   []parquetColumn
+	fileNames []string
+}
+
+/*
+ * newParquetWriter creates the Parquet writer.
+ *
+ * Parameters:
+ * - opts: Unused, the Parquet format has no options.
+ * - baseName: The timestamped prefix of the output files.
+ *
+ * Returns:
+ * - ResultWriter: The writer, ready to receive results.
+ * - error: Always nil.
+ */
+func newParquetWriter(opts RunOptions, baseName string) (ResultWriter, error) {
+	return &parquetWriter{baseName: baseName}, nil
+}
+
+/*
+ * parquetColumnKind maps a SQL Server column type to the Parquet column kind preserving its native type.
+ *
+ * Notes:
+ * - DECIMAL and NUMERIC above 18 digits and MONEY do not fit the INT64 decimal and are written as strings.
+ * - Columns without a type, such as the executed_queries result, are written as strings.
+ */
+func parquetColumnKind(columnType *sql.ColumnType) (int, int) {
+	if columnType == nil {
+		return parquetString, 0
+	}
+	switch columnType.DatabaseTypeName() {
+	case "BIGINT", "INT", "SMALLINT", "TINYINT":
+		return parquetInt64, 0
+	case "FLOAT", "REAL":
+		return parquetDouble, 0
+	case "BIT":
+		return parquetBoolean, 0
+	case "DECIMAL", "NUMERIC":
+		if precision, scale, ok := columnType.DecimalSize(); ok && precision <= 18 {
+			return parquetDecimal, int(scale)
+		}
+		return parquetString, 0
+	case "DATETIME", "DATETIME2", "SMALLDATETIME", "DATETIMEOFFSET", "DATE":
+		return parquetTimestamp, 0
+	case "BINARY", "VARBINARY", "IMAGE", "TIMESTAMP":
+		return parquetBytes, 0
+	}
+	return parquetString, 0
+}
+
+/*
+ * parquetFieldName makes a column name usable as a unique Parquet field, naming empty columns
+ * "column_<n>" and suffixing repeated names with "_<n>".
+ */
+func parquetFieldName(column string, index int, used map[string]bool) string {
+	name := strings.TrimSpace(column)
+	if name == "" {
+		name = fmt.Sprintf("column_%d", index+1)
+	}
+	field := name
+	for n := 2; used[field]; n++ {
+		field = fmt.Sprintf("%s_%d", name, n)
+	}
+	used[field] = true
+	return field
+}
+
+/*
+ * BeginResult creates the Parquet file of a result with one optional field per column, so NULL values are kept.
+ *
+ * Notes:
+ * - Parquet groups order their fields by name, readers select the columns by name rather than position.
+ * - A result left open by a failed query is completed first, so its file still has a valid footer.
+ */
+func (w *parquetWriter) BeginResult(name string, query Query, columns []string, columnTypes []*sql.ColumnType) error {
+	if err := w.EndResult(); err != nil {
+		return err
+	}
+
+	group := parquet.Group{}
+	used := make(map[string]bool, len(columns))
+	w.columns = make([]parquetColumn, len(columns))
+
+	for i, column := range columns {
+		var columnType *sql.ColumnType
+		if i < len(columnTypes) {
+			columnType = columnTypes[i]
+		}
+		kind, scale := parquetColumnKind(columnType)
+		if valueMappedColumn(column, query) {
+			kind, scale = parquetString, 0
+		}
+
+		var node parquet.Node
+		switch kind {
+		case parquetInt64:
+			node = parquet.Int(64)
+		case parquetDouble:
+			node = parquet.Leaf(parquet.DoubleType)
+		case parquetBoolean:
+			node = parquet.Leaf(parquet.BooleanType)
+		case parquetDecimal:
+			precision, _, _ := columnType.DecimalSize()
+			node = parquet.Decimal(scale, int(precision), parquet.Int64Type)
+		case parquetTimestamp:
+			node = parquet.Timestamp(parquet.Microsecond)
+		case parquetBytes:
+			node = parquet.Leaf(parquet.ByteArrayType)
+		default:
+			node = parquet.String()
+		}
+
+		w.columns[i] = parquetColumn{field: parquetFieldName(column, i, used), kind: kind, scale: scale}
+		group[w.columns[i].field] = parquet.Optional(node)
+	}
+
+	fileName := fmt.Sprintf("%s_%s.parquet", w.baseName, name)
+	file, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", fileName, err)
+	}
+	w.file = file
+	w.writer = parquet.NewWriter(file, parquet.NewSchema(name, group))
+	w.fileNames = append(w.fileNames, fileName)
+	return nil
+}
+
+/*
+ * parquetValue converts a scanned value to the Go value expected by the column's Parquet type.
+ */
+func parquetValue(column parquetColumn, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch column.kind {
+	case parquetInt64:
+		if n, ok := v.(int64); ok {
+			return n, nil
+		}
+	case parquetDouble:
+		if n, ok := v.(float64); ok {
+			return n, nil
+		}
+	case parquetBoolean:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case parquetDecimal:
+		// The driver returns DECIMAL and NUMERIC as their text, stored as the unscaled integer
+		if text, ok := v.([]byte); ok {
+			r, ok := new(big.Rat).SetString(string(text))
+			if !ok {
+				return nil, fmt.Errorf("column %s: invalid decimal %q", column.field, text)
+			}
+			r.Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(column.scale)), nil)))
+			return new(big.Int).Quo(r.Num(), r.Denom()).Int64(), nil
+		}
+	case parquetTimestamp:
+		if t, ok := v.(time.Time); ok {
+			return t, nil
+		}
+	case parquetBytes:
+		if b, ok := v.([]byte); ok {
+			return b, nil
+		}
+	default:
+		switch value := v.(type) {
+		case string:
+			return value, nil
+		case []byte:
+			return string(value), nil
+		}
+		return fmt.Sprint(v), nil
+	}
+	return nil, fmt.Errorf("column %s: unexpected %T value", column.field, v)
+}
+
+/*
+ * WriteRow writes a row to the current Parquet file.
+ */
+func (w *parquetWriter) WriteRow(values []interface{}) error {
+	row := make(map[string]interface{}, len(values))
+	for i, v := range values {
+		value, err := parquetValue(w.columns[i], v)
+		if err != nil {
+			return err
+		}
+		row[w.columns[i].field] = value
+	}
+	return w.writer.Write(row)
+}
+
+/*
+ * EndResult completes the Parquet file of the current result, writing its footer.
+ */
+func (w *parquetWriter) EndResult() error {
+	if w.writer == nil {
+		return nil
+	}
+	err := w.writer.Close()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.writer, w.file = nil, nil
+	return err
+}
+
+/*
+ * Close completes a result left open by a failed query and lists the files written.
+ */
+func (w *parquetWriter) Close() error {
+	err := w.EndResult()
+	for _, fileName := range w.fileNames {
+		logInfo("Parquet file written: %s", fileName)
+	}
+	return err
+}
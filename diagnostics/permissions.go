@@ -0,0 +1,130 @@
+package diagnostics
+
+import (
+	"database/sql" // Database/sql package for database operations
+	"fmt"          // For formatted I/O operations
+	"strings"      // For string manipulation
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+// Name of the sheet listing the permissions checked by `-check-permissions`
+const permissionsSheetName = "permissions"
+
+/*
+ * permissionCheck is a permission the diagnostic queries rely on, with the expression testing it for the login.
+ */
+type permissionCheck struct {
+	Permission string // Name shown in the permissions sheet
+	Expression string // Returns 1 when held, 0 when missing and NULL when not applicable to the server
+	NeededFor  string // What fails without the permission
+}
+
+// Permissions needed by the diagnostic queries, tested with HAS_PERMS_BY_NAME for the current login and database
+var requiredPermissions = []permissionCheck{
+	{"VIEW SERVER STATE", "HAS_PERMS_BY_NAME(NULL, NULL, 'VIEW SERVER STATE')", "Server wide DMVs (sys.dm_os_*, sys.dm_exec_*), most of the diagnostic queries"},
+	{"VIEW SERVER PERFORMANCE STATE", "HAS_PERMS_BY_NAME(NULL, NULL, 'VIEW SERVER PERFORMANCE STATE')", "Performance DMVs on SQL Server 2022 and later, NULL on earlier versions"},
+	{"VIEW DATABASE STATE", "HAS_PERMS_BY_NAME(DB_NAME(), 'DATABASE', 'VIEW DATABASE STATE')", "Database scoped DMVs (index usage, missing indexes, file stats)"},
+	{"VIEW ANY DEFINITION", "HAS_PERMS_BY_NAME(NULL, NULL, 'VIEW ANY DEFINITION')", "Object definitions and metadata of objects the login does not own"},
+	{"SELECT on database (db_datareader)", "HAS_PERMS_BY_NAME(DB_NAME(), 'DATABASE', 'SELECT')", "Queries reading user tables"},
+	{"VIEW ANY DATABASE", "HAS_PERMS_BY_NAME(NULL, NULL, 'VIEW ANY DATABASE')", "Listing every database in sys.databases"},
+}
+
+/*
+ * permissionResult is the outcome of a permissionCheck.
+ */
+type permissionResult struct {
+	permissionCheck
+	Status string // "present", "missing", "not applicable" or "check failed: <error>"
+}
+
+/*
+ * checkPermissions tests the permissions the diagnostic suite needs for the connected login.
+ *
+ * Parameters:
+ * - db: A pointer to the `sql.DB` object representing the database connection.
+ *
+ * Returns:
+ * - The result of every check in `requiredPermissions`.
+ * - The names of the missing permissions, empty when all are present or not applicable.
+ *
+ * Notes:
+ * - A check that cannot run, for example on an edition that does not know the permission, is reported and
+ *   not counted as missing.
+ */
+func checkPermissions(db *sql.DB) ([]permissionResult, []string) {
+	var results []permissionResult
+	var missing []string
+	for _, check := range requiredPermissions {
+		result := permissionResult{permissionCheck: check}
+		var held sql.NullInt64
+		if err := db.QueryRow("SELECT " + check.Expression).Scan(&held); err != nil {
+			result.Status = fmt.Sprintf("check failed: %v", err)
+		} else if !held.Valid {
+			result.Status = "not applicable"
+		} else if held.Int64 == 1 {
+			result.Status = "present"
+		} else {
+			result.Status = "missing"
+			missing = append(missing, check.Permission)
+		}
+		results = append(results, result)
+	}
+	return results, missing
+}
+
+/*
+ * writePermissionsSheet writes the permission checks to the "permissions" sheet.
+ */
+func writePermissionsSheet(f *excelize.File, results []permissionResult) {
+	f.NewSheet(permissionsSheetName)
+
+	headers := []string{"Permission", "Status", "Needed For"}
+	for colIndex, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(colIndex+1, 1)
+		f.SetCellValue(permissionsSheetName, cell, header)
+	}
+
+	for i, result := range results {
+		row := []string{result.Permission, result.Status, result.NeededFor}
+		for colIndex, value := range row {
+			cell, _ := excelize.CoordinatesToCellName(colIndex+1, i+2)
+			f.SetCellValue(permissionsSheetName, cell, value)
+		}
+	}
+}
+
+/*
+ * preflightPermissions runs the permission checks for `-check-permissions` before any query.
+ *
+ * Parameters:
+ * - db: A pointer to the `sql.DB` object representing the database connection.
+ * - f: The Excel file receiving the "permissions" sheet, nil for the other output formats.
+ * - opts: The run options, `RequirePermissions` aborts the run when a permission is missing.
+ *
+ * Returns:
+ * - An error with `RequirePermissions` when a permission is missing, nil otherwise.
+ *
+ * Functionality:
+ * 1. Checks every permission in `requiredPermissions` and writes the results to the "permissions" sheet.
+ * 2. Warns about the missing permissions, the queries depending on them will fail.
+ * 3. With `RequirePermissions`, returns an error when a permission is missing instead of warning, the caller runs
+ *    no query then.
+ */
+func preflightPermissions(db *sql.DB, f *excelize.File, opts RunOptions) error {
+	results, missing := checkPermissions(db)
+	if f != nil {
+		writePermissionsSheet(f, results)
+	}
+
+	if len(missing) == 0 {
+		logInfo("Permission check passed, the login holds every permission the diagnostic queries need.")
+		return nil
+	}
+
+	if opts.RequirePermissions {
+		return fmt.Errorf("the login is missing permissions required by -require-permissions: %s", strings.Join(missing, ", "))
+	}
+	logWarn("The login is missing %s, the queries depending on them will fail.", strings.Join(missing, ", "))
+	return nil
+}
@@ -0,0 +1,268 @@
+package diagnostics
+
+import (
+	"encoding/xml" // For reading the showplan XML
+	"fmt"          // For formatted I/O operations
+	"io"           // For detecting the end of the showplan XML
+	"math"         // For rounding the skew ratio
+	"sort"         // For ordering the operators by skew
+	"strconv"      // For parsing the showplan attributes
+	"strings"      // For string manipulation
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+// Name of the sheet holding the estimated and actual rows of every plan operator for `-plan-analysis`
+const planAnalysisSheetName = "plan_analysis"
+
+// Column of the result sets SQL Server returns with SET STATISTICS XML ON
+const showplanColumn = "Microsoft SQL Server 2005 XML Showplan"
+
+// Ratio between estimated and actual rows from which an operator is highlighted as a large estimation skew
+const planSkewThreshold = 10
+
+/*
+ * planOperator is one operator (RelOp) of a captured execution plan.
+ *
+ * Fields:
+ * - Sheet: The result sheet of the query the plan belongs to.
+ * - Statement: The 1 based number of the plan within the query's batch.
+ * - NodeID: The operator's NodeId in the plan.
+ * - PhysicalOp: The physical operator, for example "Index Seek".
+ * - LogicalOp: The logical operator, for example "Inner Join".
+ * - EstimatedRows: The rows estimated per execution.
+ * - ActualRows: The rows returned across all threads and executions.
+ * - Executions: The executions across all threads.
+ * - HasActual: Whether the plan carried runtime counters, false for an estimated plan.
+ */
+type planOperator struct {
+	Sheet         string
+	Statement     int
+	NodeID        int
+	PhysicalOp    string
+	LogicalOp     string
+	EstimatedRows float64
+	ActualRows    int64
+	Executions    int64
+	HasActual     bool
+}
+
+/*
+ * estimatedTotal returns the rows estimated across all executions, as the estimate is per execution.
+ */
+func (op planOperator) estimatedTotal() float64 {
+	if op.Executions > 1 {
+		return op.EstimatedRows * float64(op.Executions)
+	}
+	return op.EstimatedRows
+}
+
+/*
+ * skew returns how many times the estimate and the actual rows differ, at least 1, both floored to one row
+ * so an empty result does not divide by zero. Zero when the plan has no actual rows.
+ */
+func (op planOperator) skew() float64 {
+	if !op.HasActual {
+		return 0
+	}
+	estimated := math.Max(op.estimatedTotal(), 1)
+	actual := math.Max(float64(op.ActualRows), 1)
+	return math.Max(estimated, actual) / math.Min(estimated, actual)
+}
+
+/*
+ * planQueryText prefixes the query with SET STATISTICS XML ON for `-plan-analysis`, so SQL Server returns the
+ * actual plan of every statement as an extra result set.
+ */
+func planQueryText(opts RunOptions, query string) string {
+	if !opts.PlanAnalysis {
+		return query
+	}
+	return "SET STATISTICS XML ON;\n" + query
+}
+
+/*
+ * isShowplanResult reports whether the columns are those of a result set returned by SET STATISTICS XML.
+ */
+func isShowplanResult(columns []string) bool {
+	return len(columns) == 1 && columns[0] == showplanColumn
+}
+
+/*
+ * addPlans reads the plans of the current showplan result set and adds their operators to the findings.
+ * A plan that cannot be read is logged and skipped.
+ */
+func (findings *ReportFindings) addPlans(sheetName string, rows resultRows) {
+	for rows.Next() {
+		var plan string
+		if err := rows.Scan(&plan); err != nil {
+			logError("Failed to read the plan of %s: %v", sheetName, err)
+			continue
+		}
+		if findings.planStatements == nil {
+			findings.planStatements = make(map[string]int)
+		}
+		findings.planStatements[sheetName]++
+		operators, err := parseShowplan(plan, sheetName, findings.planStatements[sheetName])
+		if err != nil {
+			logError("Failed to parse the plan of %s: %v", sheetName, err)
+			continue
+		}
+		findings.PlanOperators = append(findings.PlanOperators, operators...)
+	}
+}
+
+/*
+ * capturePlans reads the showplan result sets at the current position of `rows`, up to the first other result set.
+ *
+ * Returns:
+ * - false when the batch has no result set after the plans.
+ */
+func capturePlans(findings *ReportFindings, sheetName string, rows resultRows) bool {
+	for {
+		columns, err := rows.Columns()
+		if err != nil || !isShowplanResult(columns) {
+			return true
+		}
+		findings.addPlans(sheetName, rows)
+		if !rows.NextResultSet() {
+			return false
+		}
+	}
+}
+
+/*
+ * parseShowplan extracts the operators of a showplan XML with their estimated and actual rows.
+ *
+ * Parameters:
+ * - plan: The showplan XML.
+ * - sheetName: The result sheet of the query the plan belongs to.
+ * - statement: The number of the plan within the query's batch.
+ *
+ * Returns:
+ * - The operators in plan order, or an error if the XML cannot be read.
+ *
+ * Notes:
+ * - The runtime counters of an operator are summed across its threads, an estimated plan has none and its
+ *   operators are returned without actual rows.
+ */
+func parseShowplan(plan string, sheetName string, statement int) ([]planOperator, error) {
+	var operators []planOperator
+	var open []int // Indexes of the RelOp elements being read, innermost last
+
+	decoder := xml.NewDecoder(strings.NewReader(plan))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return operators, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "RelOp":
+				op := planOperator{Sheet: sheetName, Statement: statement}
+				for _, attr := range element.Attr {
+					switch attr.Name.Local {
+					case "NodeId":
+						op.NodeID, _ = strconv.Atoi(attr.Value)
+					case "PhysicalOp":
+						op.PhysicalOp = attr.Value
+					case "LogicalOp":
+						op.LogicalOp = attr.Value
+					case "EstimateRows":
+						op.EstimatedRows, _ = strconv.ParseFloat(attr.Value, 64)
+					}
+				}
+				operators = append(operators, op)
+				open = append(open, len(operators)-1)
+			case "RunTimeCountersPerThread":
+				if len(open) == 0 {
+					continue
+				}
+				op := &operators[open[len(open)-1]]
+				op.HasActual = true
+				for _, attr := range element.Attr {
+					switch attr.Name.Local {
+					case "ActualRows":
+						n, _ := strconv.ParseInt(attr.Value, 10, 64)
+						op.ActualRows += n
+					case "ActualExecutions":
+						n, _ := strconv.ParseInt(attr.Value, 10, 64)
+						op.Executions += n
+					}
+				}
+			}
+		case xml.EndElement:
+			if element.Name.Local == "RelOp" && len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		}
+	}
+}
+
+/*
+ * writePlanAnalysisSheet writes the "plan_analysis" sheet, one row per plan operator with its estimated and
+ * actual rows, largest estimation skews first.
+ *
+ * Parameters:
+ * - report: The `excelReport` holding the Excel file and the cached styles.
+ * - operators: The operators of every plan captured during the run.
+ *
+ * Notes:
+ * - Operators whose estimate and actual rows differ `planSkewThreshold` times or more are highlighted.
+ * - Operators of estimated plans have no actual rows nor skew and are listed last.
+ */
+func writePlanAnalysisSheet(report *excelReport, operators []planOperator) {
+	f := report.f
+	f.NewSheet(planAnalysisSheetName)
+
+	headers := []string{"Sheet", "Statement", "Node Id", "Physical Op", "Logical Op", "Estimated Rows", "Executions", "Actual Rows", "Skew", "Direction"}
+	for colIndex, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(colIndex+1, 1)
+		f.SetCellValue(planAnalysisSheetName, cell, header)
+	}
+
+	sort.SliceStable(operators, func(i, j int) bool {
+		return operators[i].skew() > operators[j].skew()
+	})
+
+	styleID, err := report.cellStyle("plan_skew", &excelize.Style{
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}},
+		Font: &excelize.Font{Color: "9C0006"},
+	})
+	if err != nil {
+		logError("Failed to create the plan skew style: %v", err)
+	}
+
+	for i, op := range operators {
+		rowNum := i + 2 // Start from row 2 (after header)
+		f.SetCellValue(planAnalysisSheetName, fmt.Sprintf("A%d", rowNum), op.Sheet)
+		f.SetCellValue(planAnalysisSheetName, fmt.Sprintf("B%d", rowNum), op.Statement)
+		f.SetCellValue(planAnalysisSheetName, fmt.Sprintf("C%d", rowNum), op.NodeID)
+		f.SetCellValue(planAnalysisSheetName, fmt.Sprintf("D%d", rowNum), op.PhysicalOp)
+		f.SetCellValue(planAnalysisSheetName, fmt.Sprintf("E%d", rowNum), op.LogicalOp)
+		f.SetCellValue(planAnalysisSheetName, fmt.Sprintf("F%d", rowNum), op.estimatedTotal())
+		if !op.HasActual {
+			f.SetCellValue(planAnalysisSheetName, fmt.Sprintf("J%d", rowNum), "estimated plan only")
+			continue
+		}
+
+		skew := op.skew()
+		direction := "overestimated"
+		if float64(op.ActualRows) > op.estimatedTotal() {
+			direction = "underestimated"
+		}
+		f.SetCellValue(planAnalysisSheetName, fmt.Sprintf("G%d", rowNum), op.Executions)
+		f.SetCellValue(planAnalysisSheetName, fmt.Sprintf("H%d", rowNum), op.ActualRows)
+		f.SetCellValue(planAnalysisSheetName, fmt.Sprintf("I%d", rowNum), math.Round(skew*10)/10)
+		f.SetCellValue(planAnalysisSheetName, fmt.Sprintf("J%d", rowNum), direction)
+
+		if skew >= planSkewThreshold && err == nil {
+			f.SetCellStyle(planAnalysisSheetName, fmt.Sprintf("A%d", rowNum), fmt.Sprintf("J%d", rowNum), styleID)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package diagnostics
+
+import (
+	"fmt"           // For formatted I/O operations
+	"os"            // For reading the .sql files
+	"path/filepath" // For the file names and extensions
+	"strings"       // For string manipulation
+)
+
+/*
+ * readQueriesDir reads the queries from a folder of .sql files, one query per file, for `-queries-dir`.
+ *
+ * Parameters:
+ * - dir: The folder holding the .sql files.
+ *
+ * Returns:
+ * - Queries: The queries of the folder, in file name order, described by a querysource naming the folder.
+ * - error: An error if the folder cannot be read, holds no .sql file or a file cannot be read.
+ *
+ * Functionality:
+ * 1. Reads every `*.sql` file of the folder, sub folders are not read. The files run in file name order, a numeric
+ *    prefix such as 01_waits.sql orders them.
+ * 2. The SQL of a query is the content of its file as is, no escaping is needed.
+ * 3. The name of a query is its file name without the extension. The leading comment tags fill the other fields,
+ *    `-- description: ...` or `-- @description ...`, and likewise notes and tags, see `parseCommentTags`.
+ *    A `-- @name` tag replaces the file name.
+ *
+ * Notes:
+ * - The queries go through the same pipeline as those of a queries file, only the per query JSON settings such
+ *   as columnLabels or alert and the preRun and postRun statements cannot be expressed in a .sql file.
+ */
+func readQueriesDir(dir string) (Queries, error) {
+	queries := Queries{
+		QuerySource: QuerySource{Name: filepath.Base(filepath.Clean(dir)), Source: dir},
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return queries, fmt.Errorf("failed to read queries folder: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".sql") {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return queries, fmt.Errorf("failed to read query file: %v", err)
+		}
+		query := Query{Query: string(content)}
+		applyCommentTags(&query)
+		if query.Name == "" {
+			query.Name = strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		}
+		queries.Queries = append(queries.Queries, query)
+	}
+	if len(queries.Queries) == 0 {
+		return queries, fmt.Errorf("the queries folder %s holds no .sql file", dir)
+	}
+	return queries, nil
+}
@@ -0,0 +1,128 @@
+package diagnostics
+
+import (
+	"fmt"     // For formatted I/O operations
+	"strings" // For string manipulation
+)
+
+/*
+ * checkOnlyNames checks that every name given to `-only` matches a query of the queries file.
+ *
+ * Parameters:
+ * - queries: The queries of the run.
+ * - names: The query names from `-only`, matched case insensitively against Query.Name as written in the file,
+ *   not against the sanitized sheet name.
+ *
+ * Returns:
+ * - An error listing the names matching no query, nil when all of them match or no name is given.
+ */
+func checkOnlyNames(queries Queries, names []string) error {
+	var unknown []string
+	for _, name := range names {
+		found := false
+		for _, query := range queries.Queries {
+			if strings.EqualFold(strings.TrimSpace(query.Name), name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("no query named %s in the queries file", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+/*
+ * checkSelectedTags warns about the tags given to `-tag` that no query of the queries file carries.
+ *
+ * Parameters:
+ * - queries: The queries of the run, with the tags of their `tags` field or their "-- @tags" comment.
+ * - tags: The tags from `-tag`, matched case insensitively.
+ *
+ * Returns:
+ * - The tags matching no query, each already logged as a warning.
+ *
+ * Notes:
+ * - An unknown tag only warns, unlike an unknown `-only` name, as a tag shared by several queries files may
+ *   not be used in all of them. The caller still fails the run when no query is selected at all.
+ */
+func checkSelectedTags(queries Queries, tags []string) []string {
+	var unknown []string
+	for _, tag := range tags {
+		found := false
+		for _, query := range queries.Queries {
+			if hasTag(query, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			logWarn("No query is tagged %s, -tag %s selects nothing.", tag, tag)
+			unknown = append(unknown, tag)
+		}
+	}
+	return unknown
+}
+
+/*
+ * selectedQuery reports whether a query runs, every query runs unless `-only` or `-tag` select a subset of them.
+ * A query runs when `-only` names it and it carries one of the `-tag` tags, a flag left empty selects every query,
+ * so both flags together run the intersection. The queries left out are not run and show as Skipped on the
+ * executed_queries sheet.
+ */
+func selectedQuery(opts RunOptions, query Query) bool {
+	return namedByOnly(opts.Only, query) && taggedBy(opts.Tags, query)
+}
+
+// namedByOnly reports whether `-only` names the query, true when no name is given
+func namedByOnly(names []string, query Query) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, name := range names {
+		if strings.EqualFold(strings.TrimSpace(query.Name), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// taggedBy reports whether the query carries one of the `-tag` tags, true when no tag is given
+func taggedBy(tags []string, query Query) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		if hasTag(query, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+ * selectedCount returns the number of queries selected by `-only` and `-tag`.
+ */
+func selectedCount(opts RunOptions, queries Queries) int {
+	count := 0
+	for _, query := range queries.Queries {
+		if selectedQuery(opts, query) {
+			count++
+		}
+	}
+	return count
+}
+
+// hasTag reports whether the query carries the tag, compared case insensitively
+func hasTag(query Query, tag string) bool {
+	for _, queryTag := range query.Tags {
+		if strings.EqualFold(strings.TrimSpace(queryTag), tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package diagnostics
+
+import (
+	"strings" // For checking the error messages
+	"testing" // For the test framework
+)
+
+// filterTestQueries is the queries file the filter tests select from
+var filterTestQueries = Queries{Queries: []Query{
+	{Name: "Wait Stats", Tags: []string{"waits", "perf"}},
+	{Name: " CPU Utilization ", Tags: []string{"Perf"}},
+	{Name: "Database Sizes", Tags: []string{"storage"}},
+	{Name: "Missing Indexes"},
+}}
+
+/*
+ * TestCheckOnlyNames checks that -only names match the query names case insensitively, and that the names
+ * matching no query are all listed in the error.
+ */
+func TestCheckOnlyNames(t *testing.T) {
+	tests := []struct {
+		name        string
+		only        []string
+		wantUnknown []string
+	}{
+		{name: "no names", only: nil},
+		{name: "exact names", only: []string{"Wait Stats", "Database Sizes"}},
+		{name: "case insensitive", only: []string{"wait stats", "MISSING INDEXES"}},
+		{name: "name padded in the file", only: []string{"cpu utilization"}},
+		{name: "sanitized sheet name does not match", only: []string{"1_Wait_Stats"}, wantUnknown: []string{"1_Wait_Stats"}},
+		{name: "several unknown", only: []string{"Wait Stats", "Wait Statz", "Blocking"}, wantUnknown: []string{"Wait Statz", "Blocking"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkOnlyNames(filterTestQueries, tt.only)
+			if len(tt.wantUnknown) == 0 {
+				if err != nil {
+					t.Fatalf("checkOnlyNames(%v) = %v, want no error", tt.only, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("checkOnlyNames(%v) = nil, want an error naming %v", tt.only, tt.wantUnknown)
+			}
+			if want := strings.Join(tt.wantUnknown, ", "); !strings.Contains(err.Error(), want) {
+				t.Errorf("checkOnlyNames(%v) = %q, want it to list %q", tt.only, err, want)
+			}
+		})
+	}
+}
+
+/*
+ * TestSelectedQuery checks the queries selected by -only and -tag alone and together, where a query must be
+ * named and tagged.
+ */
+func TestSelectedQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		only []string
+		tags []string
+		want []string
+	}{
+		{name: "no filter", want: []string{"Wait Stats", " CPU Utilization ", "Database Sizes", "Missing Indexes"}},
+		{name: "only", only: []string{"database sizes", "missing indexes"}, want: []string{"Database Sizes", "Missing Indexes"}},
+		{name: "tag", tags: []string{"perf"}, want: []string{"Wait Stats", " CPU Utilization "}},
+		{name: "several tags", tags: []string{"waits", "storage"}, want: []string{"Wait Stats", "Database Sizes"}},
+		{name: "only and tag intersect", only: []string{"Wait Stats", "Database Sizes"}, tags: []string{"perf"}, want: []string{"Wait Stats"}},
+		{name: "only and tag without overlap", only: []string{"Missing Indexes"}, tags: []string{"perf"}, want: nil},
+		{name: "unknown tag", tags: []string{"blocking"}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := RunOptions{Only: tt.only, Tags: tt.tags}
+			var got []string
+			for _, query := range filterTestQueries.Queries {
+				if selectedQuery(opts, query) {
+					got = append(got, query.Name)
+				}
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("selected %q, want %q", got, tt.want)
+			}
+			if count := selectedCount(opts, filterTestQueries); count != len(tt.want) {
+				t.Errorf("selectedCount = %d, want %d", count, len(tt.want))
+			}
+		})
+	}
+}
+
+/*
+ * TestCheckSelectedTags checks the -tag tags no query carries are returned, matched case insensitively.
+ */
+func TestCheckSelectedTags(t *testing.T) {
+	unknown := checkSelectedTags(filterTestQueries, []string{"PERF", "blocking", "storage", "io"})
+	if strings.Join(unknown, ",") != "blocking,io" {
+		t.Errorf("checkSelectedTags = %v, want [blocking io]", unknown)
+	}
+}
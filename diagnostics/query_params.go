@@ -0,0 +1,204 @@
+package diagnostics
+
+import (
+	"bytes"         // For decoding the parameters file
+	"database/sql"  // Database/sql package for the named parameters
+	"encoding/json" // For reading the -params file
+	"fmt"           // For formatted I/O operations
+	"math"          // For recognizing whole numbers
+	"os"            // For reading the -params file
+	"regexp"        // For finding the parameters referenced by a query
+	"strings"       // For string manipulation
+)
+
+// An @name reference in the SQL, not a @@ system function
+var parameterReference = regexp.MustCompile(`(?:^|[^@\w])@([A-Za-z_][\w$#]*)`)
+
+/*
+ * paramValues holds parameter values keyed by lower case name, without the leading @.
+ */
+type paramValues map[string]interface{}
+
+/*
+ * paramFlags collects the repeated `-param key=value` flags.
+ */
+type paramFlags map[string]interface{}
+
+// String returns the parameters as key=value pairs
+func (p paramFlags) String() string {
+	pairs := make([]string, 0, len(p))
+	for key, value := range p {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", key, value))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set adds one key=value parameter, the value is passed as text
+func (p paramFlags) Set(value string) error {
+	key, text, ok := strings.Cut(value, "=")
+	key = strings.TrimPrefix(strings.TrimSpace(key), "@")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	p[key] = text
+	return nil
+}
+
+/*
+ * readParamsFile reads the `-params` JSON file, an object of parameter names and their string, number or
+ * boolean values.
+ *
+ * Parameters:
+ * - path: The path to the JSON file, empty when no file is given.
+ *
+ * Returns:
+ * - The parameters, nil when `path` is empty.
+ * - An error if the file cannot be read or parsed, or a value is not a string, number or boolean.
+ */
+func readParamsFile(path string) (map[string]interface{}, error) {
+	if path == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parameters file: %v", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var params map[string]interface{}
+	if err := decoder.Decode(&params); err != nil {
+		return nil, fmt.Errorf("failed to parse parameters file %s: %v", path, err)
+	}
+	for key, value := range params {
+		switch value.(type) {
+		case string, json.Number, bool:
+		default:
+			return nil, fmt.Errorf("parameter %s in %s must be a string, number or boolean", key, path)
+		}
+	}
+	return params, nil
+}
+
+/*
+ * mergeParams combines the parameters of the `-params` file and the `-param` flags, a flag overriding the
+ * file's value of the same name. Names are matched case insensitively.
+ */
+func mergeParams(file map[string]interface{}, flags paramFlags) paramValues {
+	params := make(paramValues, len(file)+len(flags))
+	for _, source := range []map[string]interface{}{file, flags} {
+		for key, value := range source {
+			params[strings.ToLower(strings.TrimPrefix(key, "@"))] = value
+		}
+	}
+	return params
+}
+
+/*
+ * paramValue converts a parameter value to the type it is bound with: whole numbers as bigint, other numbers
+ * as float, booleans as bit and the rest as nvarchar.
+ */
+func paramValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return paramValue(f)
+		}
+		return v.String()
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+		return v
+	case int64, bool, string:
+		return v
+	case int:
+		return int64(v)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+/*
+ * queryParams resolves the parameters a query references to their values.
+ *
+ * Parameters:
+ * - query: The query, its `params` give the default values.
+ * - params: The parameters of the run from `-params` and `-param`, overriding the query's defaults.
+ *
+ * Returns:
+ * - The names referenced as @name in the SQL that have a value, in order of first reference, with their values.
+ *
+ * Notes:
+ * - Only referenced parameters are bound, a query without any runs as a plain batch as before.
+ * - A name with a value must not also be DECLAREd in the query, SQL Server rejects the duplicate declaration.
+ */
+func queryParams(query Query, params paramValues) ([]string, []interface{}) {
+	values := make(map[string]interface{}, len(query.Params)+len(params))
+	for key, value := range query.Params {
+		values[strings.ToLower(strings.TrimPrefix(key, "@"))] = value
+	}
+	for key, value := range params {
+		values[key] = value
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	var names []string
+	var resolved []interface{}
+	seen := make(map[string]bool)
+	for _, match := range parameterReference.FindAllStringSubmatch(query.Query, -1) {
+		name := match[1]
+		key := strings.ToLower(name)
+		value, ok := values[key]
+		if !ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, name)
+		resolved = append(resolved, paramValue(value))
+	}
+	return names, resolved
+}
+
+/*
+ * queryArgs returns the named arguments binding the parameters a query references, passed to QueryContext.
+ *
+ * Notes:
+ * - The values are bound server side through sp_executesql, never spliced into the SQL text, so a value cannot
+ *   change the statement and needs no quoting or escaping.
+ */
+func queryArgs(query Query, params paramValues) []interface{} {
+	names, values := queryParams(query, params)
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = sql.Named(name, values[i])
+	}
+	return args
+}
+
+/*
+ * paramDeclarations returns the declarations of the parameters a query references, such as
+ * "@database_name nvarchar(4000), @days bigint", typed as the driver binds them, for describing the query
+ * without running it. Empty when the query references none.
+ */
+func paramDeclarations(query Query, params paramValues) string {
+	names, values := queryParams(query, params)
+	declarations := make([]string, len(names))
+	for i, name := range names {
+		sqlType := "nvarchar(4000)"
+		switch values[i].(type) {
+		case int64:
+			sqlType = "bigint"
+		case float64:
+			sqlType = "float"
+		case bool:
+			sqlType = "bit"
+		}
+		declarations[i] = "@" + name + " " + sqlType
+	}
+	return strings.Join(declarations, ", ")
+}
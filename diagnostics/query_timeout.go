@@ -0,0 +1,45 @@
+package diagnostics
+
+import (
+	"context" // For cancelling the queries running past the timeout
+	"fmt"     // For formatted I/O operations
+)
+
+/*
+ * queryContext returns the context a query runs with: collecting the server times for `-statistics-time`,
+ * see timedQueryContext, and cancelled once `-query-timeout` elapses. A `-heartbeat` is logged until it is released.
+ *
+ * Parameters:
+ * - parent: The context of the run, cancelled when the run is interrupted.
+ * - opts: The run options.
+ * - name: The name of the query, logged by the heartbeat.
+ *
+ * Returns:
+ * - The query context.
+ * - The function releasing the context and stopping the heartbeat, to call once the query's rows are written.
+ * - The timing collected for `-statistics-time`, nil when not enabled.
+ */
+func queryContext(parent context.Context, opts RunOptions, name string) (context.Context, context.CancelFunc, *queryTiming) {
+	ctx, timing := timedQueryContext(parent, opts)
+	if opts.QueryTimeout <= 0 {
+		return ctx, startHeartbeat(ctx, opts, name), timing
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.QueryTimeout)
+	stopHeartbeat := startHeartbeat(ctx, opts, name)
+	return ctx, func() {
+		stopHeartbeat()
+		cancel()
+	}, timing
+}
+
+/*
+ * queryTimeoutError replaces the error of a query cancelled by `-query-timeout`, which the driver reports in
+ * various ways, with one naming the timeout and wrapping context.DeadlineExceeded, so the query is reported
+ * as a Timeout. Other errors are returned unchanged.
+ */
+func queryTimeoutError(ctx context.Context, opts RunOptions, err error) error {
+	if err == nil || ctx.Err() != context.DeadlineExceeded {
+		return err
+	}
+	return fmt.Errorf("timed out after %s (-query-timeout): %w", opts.QueryTimeout, context.DeadlineExceeded)
+}
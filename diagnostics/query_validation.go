@@ -0,0 +1,64 @@
+package diagnostics
+
+import (
+	"fmt"     // For formatted I/O operations
+	"strings" // For string manipulation
+)
+
+/*
+ * validateQueries checks every query of the queries file for the fields a run needs, before connecting.
+ *
+ * Parameters:
+ * - q: The queries read from the queries file, with their comment tags applied.
+ *
+ * Returns:
+ * - Every problem found, in query order, empty when the file is valid.
+ *
+ * Functionality:
+ * 1. Reports queries without a name, which would get a sheet named by their Sr.No only.
+ * 2. Reports queries without SQL, which would run nothing and leave an empty sheet.
+ * 3. Reports names used by more than one query, case insensitively as sheet names and `-only` compare them.
+ * 4. Reports empty `preRun` and `postRun` statements, listed before the problems of the queries.
+ * 5. Reports invalid `alert` rules, including an alert on a column with a value map, see `validateAlertRule`.
+ *
+ * Notes:
+ * - Every query is checked, so a single run lists all the problems instead of stopping at the first.
+ */
+func validateQueries(q Queries) []error {
+	var problems []error
+	names := make(map[string]int, len(q.Queries))
+
+	for i, statement := range q.PreRun {
+		if strings.TrimSpace(statement) == "" {
+			problems = append(problems, fmt.Errorf("preRun statement %d is empty", i+1))
+		}
+	}
+	for i, statement := range q.PostRun {
+		if strings.TrimSpace(statement) == "" {
+			problems = append(problems, fmt.Errorf("postRun statement %d is empty", i+1))
+		}
+	}
+
+	for i, query := range q.Queries {
+		index := i + 1
+		name := strings.TrimSpace(query.Name)
+		if name == "" {
+			problems = append(problems, fmt.Errorf("query %d has no name", index))
+		}
+		if strings.TrimSpace(query.Query) == "" {
+			problems = append(problems, fmt.Errorf("query %d %q has no query", index, name))
+		}
+		if err := validateAlertRule(query); err != nil {
+			problems = append(problems, fmt.Errorf("query %d %q: %v", index, name, err))
+		}
+		if name == "" {
+			continue
+		}
+		if previous, ok := names[strings.ToLower(name)]; ok {
+			problems = append(problems, fmt.Errorf("query %d %q has the same name as query %d", index, name, previous))
+		} else {
+			names[strings.ToLower(name)] = index
+		}
+	}
+	return problems
+}
@@ -0,0 +1,60 @@
+package diagnostics
+
+import (
+	"context"      // For running the query with its context
+	"database/sql" // Database/sql package for database operations
+	"fmt"          // For formatted I/O operations
+)
+
+/*
+ * queryRows runs the SQL of a query and returns its rows, inside a transaction rolled back once the rows are
+ * read with `-read-only`.
+ *
+ * Parameters:
+ * - ctx: The query context.
+ * - db: The database connection pool, or the dedicated connection of the query, see `runQueryRows`.
+ * - opts: The run options, `ReadOnly` wraps the query in the transaction.
+ * - text: The SQL sent to the server.
+ * - args: The parameters bound to the query.
+ *
+ * Returns:
+ * - The rows of the query.
+ * - The function closing the rows and rolling the transaction back, to call once the rows are read.
+ * - An error if the transaction cannot be started or the query fails.
+ *
+ * Notes:
+ * - SQL Server has no read-only transaction and the driver rejects sql.TxOptions{ReadOnly: true}, so the guard is
+ *   a transaction that is never committed: an INSERT, UPDATE, DELETE or DDL of a query is undone by the rollback
+ *   and never persists, even when the server accepted it.
+ * - Queries that manage their own transactions (BEGIN TRAN, COMMIT) or run statements not allowed in a user
+ *   transaction, such as BACKUP, RECONFIGURE or some DBCC commands, fail with -read-only. Temp tables and table
+ *   variables keep working, they are dropped with the rollback. Run the incompatible queries in a separate run
+ *   without -read-only, selecting them with -only or -tag.
+ * - The transaction holds the locks a write took until the rows are read, the diagnostic DMV queries take none.
+ */
+func queryRows(ctx context.Context, db queryRunner, opts RunOptions, text string, args ...interface{}) (*sql.Rows, func(), error) {
+	if !opts.ReadOnly {
+		rows, err := db.QueryContext(ctx, text, args...)
+		if err != nil {
+			return nil, func() {}, err
+		}
+		return rows, func() { rows.Close() }, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, func() {}, fmt.Errorf("failed to start the -read-only transaction: %v", err)
+	}
+	rows, err := tx.QueryContext(ctx, text, args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, func() {}, err
+	}
+	return rows, func() {
+		rows.Close()
+		// A cancelled context already rolled the transaction back, ErrTxDone is expected then
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			logWarn("Failed to roll back the -read-only transaction: %v", err)
+		}
+	}, nil
+}
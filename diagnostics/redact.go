@@ -0,0 +1,128 @@
+package diagnostics
+
+import (
+	"crypto/sha256" // For hashing the redacted values
+	"encoding/hex"  // For encoding the hashed values
+	"fmt"           // For formatted I/O operations
+	"path"          // For matching the column name patterns
+	"strings"       // For string manipulation
+
+	"github.com/magiconair/properties" // For reading the redaction properties
+)
+
+// Values of the REDACT_MODE property
+const (
+	redactModeRedact = "redact" // Replace every value with redactedValue
+	redactModeHash   = "hash"   // Replace every value with a short hash, equal values keep equal hashes
+)
+
+// Text replacing the values of the redacted columns with REDACT_MODE=redact
+const redactedValue = "REDACTED"
+
+/*
+ * columnRedaction holds the columns whose values are hidden from the output, read from the configuration.
+ * The zero value redacts nothing.
+ *
+ * Fields:
+ * - Patterns: The lower case column name patterns of `REDACT_COLUMNS`, such as "login_name" or "*host*".
+ * - Hash: Replace the values with a hash instead of redactedValue, `REDACT_MODE=hash`.
+ */
+type columnRedaction struct {
+	Patterns []string
+	Hash     bool
+}
+
+/*
+ * readRedactionProperties reads the redaction properties of a configuration file into `sqlServerConfig`.
+ *
+ * Parameters:
+ * - sqlProperties: The loaded properties file.
+ * - sqlServerConfig: The configuration being read, updated in place.
+ *
+ * Returns:
+ * - An error for an invalid pattern in `REDACT_COLUMNS` or an unknown `REDACT_MODE` value.
+ *
+ * Functionality:
+ * 1. `REDACT_COLUMNS` is a comma separated list of column name patterns, matched case insensitively against the
+ *    column names the queries return, with the `*` and `?` wildcards, such as "login_name,client_net_address,*host*".
+ * 2. `REDACT_MODE` is redact, the default, to write "REDACTED", or hash to write "hash:" followed by the first 12 hex
+ *    digits of the SHA-256 of the value, so the rows of one login can still be told apart and grouped.
+ *
+ * Notes:
+ * - The hash is not salted, a value guessable from a short list, such as an IP address of a known subnet, can be
+ *   recovered from it. Use redact for workbooks shared outside the team.
+ * - The patterns match the column names returned by the server, not the `columnLabels` of the queries.
+ */
+func readRedactionProperties(sqlProperties *properties.Properties, sqlServerConfig *SQLServerConfig) error {
+	sqlServerConfig.Redaction = columnRedaction{}
+	for _, pattern := range strings.Split(sqlProperties.GetString("REDACT_COLUMNS", ""), ",") {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("REDACT_COLUMNS has the invalid pattern %q: %v", pattern, err)
+		}
+		sqlServerConfig.Redaction.Patterns = append(sqlServerConfig.Redaction.Patterns, pattern)
+	}
+
+	mode := strings.ToLower(strings.TrimSpace(sqlProperties.GetString("REDACT_MODE", redactModeRedact)))
+	switch mode {
+	case redactModeRedact:
+	case redactModeHash:
+		sqlServerConfig.Redaction.Hash = true
+	default:
+		return fmt.Errorf("REDACT_MODE has the unknown value %q, expected %s or %s", mode, redactModeRedact, redactModeHash)
+	}
+	return nil
+}
+
+/*
+ * columns returns which of the result columns are redacted, nil when none is.
+ */
+func (r columnRedaction) columns(columns []string) []bool {
+	var redacted []bool
+	for i, column := range columns {
+		for _, pattern := range r.Patterns {
+			if matched, _ := path.Match(pattern, strings.ToLower(column)); matched {
+				if redacted == nil {
+					redacted = make([]bool, len(columns))
+				}
+				redacted[i] = true
+				break
+			}
+		}
+	}
+	return redacted
+}
+
+/*
+ * apply replaces the scanned values of the redacted columns of a row in place, so no later step of the run, the
+ * sheet, the other formats, the snapshot or the overview, sees the original value. NULL stays NULL.
+ */
+func (r columnRedaction) apply(values []interface{}, redacted []bool) {
+	for i, isRedacted := range redacted {
+		if !isRedacted || i >= len(values) {
+			continue
+		}
+		value := values[i].(*interface{})
+		if *value != nil {
+			*value = r.redact(*value)
+		}
+	}
+}
+
+/*
+ * redact returns the text replacing a value of a redacted column.
+ */
+func (r columnRedaction) redact(v interface{}) string {
+	if !r.Hash {
+		return redactedValue
+	}
+	text := fmt.Sprint(v)
+	if b, ok := v.([]byte); ok {
+		text = string(b)
+	}
+	sum := sha256.Sum256([]byte(text))
+	return "hash:" + hex.EncodeToString(sum[:])[:12]
+}
@@ -0,0 +1,100 @@
+package diagnostics
+
+import (
+	"context"             // For running the query on the fake driver
+	"database/sql/driver" // For the values of the fake result
+	"fmt"                 // For comparing the rows
+	"testing"             // For the test framework
+
+	"github.com/xuri/excelize/v2" // For reading back the result sheets
+)
+
+// The result sets of a batch such as "SELECT 1 AS a; SELECT 2 AS b, 3 AS c"
+var twoResultSets = []fakeResultSet{
+	{columns: []string{"a"}, types: []string{"INT"}, rows: [][]driver.Value{{int64(1)}}},
+	{columns: []string{"b", "c"}, types: []string{"INT", "INT"}, rows: [][]driver.Value{{int64(2), int64(3)}}},
+}
+
+/*
+ * TestWriteQueryResultsResultSets checks every result set of a batch gets its own Excel sheet, the second one suffixed
+ * "_2", each with its own headers and values, and only the first one is counted in the outcome.
+ */
+func TestWriteQueryResultsResultSets(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+	report := newExcelReport(f, RunOptions{})
+	query := Query{Name: "Batch"}
+	outcome := report.findings.addOutcome(query, "1_Batch")
+
+	if err := writeQueryResults(queryFakeRows(t, twoResultSets...), query, report, "1_Batch"); err != nil {
+		t.Fatalf("writeQueryResults: %v", err)
+	}
+
+	tests := []struct {
+		sheet      string
+		wantHeader []string
+		wantRow    []string
+	}{
+		{sheet: "1_Batch", wantHeader: []string{"a"}, wantRow: []string{"1"}},
+		{sheet: "1_Batch_2", wantHeader: []string{"b", "c"}, wantRow: []string{"2", "3"}},
+	}
+	for _, tt := range tests {
+		rows, err := f.GetRows(tt.sheet)
+		if err != nil {
+			t.Errorf("sheet %s: %v", tt.sheet, err)
+			continue
+		}
+		if len(rows) != resultFirstDataRow {
+			t.Errorf("sheet %s has %d rows, want the header and one data row", tt.sheet, len(rows))
+			continue
+		}
+		if header := rows[resultHeaderRow-1]; fmt.Sprint(header) != fmt.Sprint(tt.wantHeader) {
+			t.Errorf("sheet %s headers = %q, want %q", tt.sheet, header, tt.wantHeader)
+		}
+		if row := rows[resultFirstDataRow-1]; fmt.Sprint(row) != fmt.Sprint(tt.wantRow) {
+			t.Errorf("sheet %s row = %q, want %q", tt.sheet, row, tt.wantRow)
+		}
+	}
+	if outcome.Rows != 1 {
+		t.Errorf("outcome counts %d rows, want the row of the first result set", outcome.Rows)
+	}
+}
+
+/*
+ * TestExecuteQueryToWriterResultSets checks the non Excel formats get every result set of a batch as a result of its
+ * own, named as the Excel sheets, and only the first one is counted in the outcome.
+ */
+func TestExecuteQueryToWriterResultSets(t *testing.T) {
+	db, text := openFakeDB(t, twoResultSets...)
+	query := Query{Name: "Batch", Query: text}
+	findings := &ReportFindings{}
+	outcome := findings.addOutcome(query, "1_Batch")
+	writer := &recordingWriter{}
+
+	if err := executeQueryToWriter(context.Background(), db, query, RunOptions{}, writer, "1_Batch", findings, outcome); err != nil {
+		t.Fatalf("executeQueryToWriter: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		wantHeader []string
+		wantRow    []interface{}
+	}{
+		{name: "1_Batch", wantHeader: []string{"a"}, wantRow: []interface{}{int64(1)}},
+		{name: "1_Batch_2", wantHeader: []string{"b", "c"}, wantRow: []interface{}{int64(2), int64(3)}},
+	}
+	if len(writer.headers) != len(tests) {
+		t.Errorf("wrote the results %v, want %d", writer.headers, len(tests))
+	}
+	for _, tt := range tests {
+		if header := writer.headers[tt.name]; fmt.Sprint(header) != fmt.Sprint(tt.wantHeader) {
+			t.Errorf("result %s headers = %q, want %q", tt.name, header, tt.wantHeader)
+		}
+		if rows := writer.rows[tt.name]; len(rows) != 1 || fmt.Sprint(rows[0]) != fmt.Sprint(tt.wantRow) {
+			t.Errorf("result %s rows = %v, want [%v]", tt.name, rows, tt.wantRow)
+		}
+	}
+	if outcome.Rows != 1 {
+		t.Errorf("outcome counts %d rows, want the row of the first result set", outcome.Rows)
+	}
+}
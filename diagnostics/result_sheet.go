@@ -0,0 +1,483 @@
+package diagnostics
+
+import (
+	"database/sql" // Database/sql package for database operations
+	"fmt"          // For formatted I/O operations
+	"strings"      // For string manipulation
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+// Rows of a result sheet: the full query name and its description head the sheet above the table, whose header
+// row follows a blank row, since a sheet name is cut to 31 characters
+const (
+	resultTitleRow       = 1 // Full name of the query
+	resultDescriptionRow = 2 // Description of the query
+	resultHeaderRow      = 4 // Column headers of the result
+	resultFirstDataRow   = 5 // First row of the result
+)
+
+/*
+ * resultSheet streams the rows of one or more result sets into a single Excel sheet, applying the
+ * per cell options (strict scanning, statistics, missing index collection) as each row is written.
+ *
+ * Fields:
+ * - report: The `excelReport` the sheet belongs to.
+ * - f: The Excel file the sheet belongs to.
+ * - name: The sheet name.
+ * - query: The `Query` the rows come from.
+ * - opts: The run options.
+ * - findings: Collects the data issues and missing index rows of the sheet.
+ * - columns: The column names of the result, the first `-max-columns` ones when the result is wider.
+ * - columnTypes: The driver column types of the result.
+ * - resultColumns: The number of columns of the result, more than len(columns) when truncated by `-max-columns`.
+ * - withResultSet: Whether a leading "result_set" column numbers the result set of each row.
+ * - rowIndex: The next row to write.
+ * - stats: The numeric column statistics for `-summarize`.
+ * - missingIndex: The missing index DMV column positions, nil when not a missing index result.
+ * - valueMaps: The query's value maps resolved per column, nil when the query has none.
+ * - formatHints: The query's format hints resolved per column, nil when the query has none.
+ * - rankedColumn: The index of the query's `highlightTop` column, -1 when nothing is highlighted.
+ * - alertColumn: The index of the query's `alert` column, -1 when nothing is flagged.
+ * - redacted: Which columns have their values redacted, see `columnRedaction`, nil when none is.
+ * - resultSetRows: The first and last sheet row written for each result set, used for the outline groups.
+ * - snapshot: Receives the text of every written row for the changes sheet, nil when not needed.
+ * - outcome: Counts the rows and takes the summary value of the query's outcome, nil for sheets outside the queries.
+ * - mirror: Receives the rows of the first result set for the other `-format` formats, nil when not mirrored.
+ * - widths: The character count of the widest value written to each sheet column, for `-max-col-width`.
+ * - truncated: Whether rows were left out by `-max-rows`, noted below the last row.
+ */
+type resultSheet struct {
+	report        *excelReport
+	f             *excelize.File
+	name          string
+	query         Query
+	opts          RunOptions
+	findings      *ReportFindings
+	columns       []string
+	columnTypes   []*sql.ColumnType
+	resultColumns int
+	withResultSet bool
+	rowIndex      int
+	stats         []columnStats
+	missingIndex  *missingIndexColumns
+	valueMaps     []map[string]string
+	formatHints   []string
+	rankedColumn  int
+	alertColumn   int
+	redacted      []bool
+	resultSetRows [][2]int
+	snapshot      *resultSnapshot
+	outcome       *queryOutcome
+	mirror        ResultWriter
+	widths        []int
+	truncated     bool
+}
+
+/*
+ * newResultSheet creates the sheet and writes its header row.
+ *
+ * Parameters:
+ * - report: The `excelReport` holding the Excel file, run options and findings.
+ * - name: The sheet name.
+ * - query: The `Query` whose column labels are applied to the header.
+ * - columns: The column names of the result, in the order the query returns them.
+ * - columnTypes: The driver column types of the result.
+ * - withResultSet: Prefix every row with a "result_set" column holding its result set number.
+ *
+ * Returns:
+ * - The resultSheet, ready to receive rows from `resultFirstDataRow`.
+ *
+ * Notes:
+ * - The full query name, in bold, and its description are written in A1 and A2 above the header row, the sheet name
+ *   being cut to 31 characters. They are not counted in the fitted column widths, the text overflows into the
+ *   empty cells to its right.
+ * - The columns named by `columnsToFront` are moved to the left, the sheet keeps the columns in that display order.
+ * - A result wider than `-max-columns` keeps its first columns in display order, a note after the last header
+ *   tells how many columns were dropped.
+ */
+func newResultSheet(report *excelReport, name string, query Query, columns []string, columnTypes []*sql.ColumnType, withResultSet bool) *resultSheet {
+	f, opts := report.f, report.opts
+
+	order, missing := columnOrder(columns, query, frontColumnList(query, opts))
+	if len(missing) > 0 {
+		logWarn("Query %s: columns to front not found in the result are ignored: %s", query.Name, strings.Join(missing, ", "))
+	}
+	columns = reorderColumns(columns, order)
+	columnTypes = reorderColumns(columnTypes, order)
+
+	resultColumns := len(columns)
+	if kept := keptColumns(opts, resultColumns); kept < resultColumns {
+		logWarn("Query %s: the result has %d columns, only the first %d are written to %s (-max-columns)", query.Name, resultColumns, kept, name)
+		columns, columnTypes = columns[:kept], columnTypes[:kept]
+	}
+
+	s := &resultSheet{
+		report:        report,
+		f:             f,
+		name:          name,
+		query:         query,
+		opts:          opts,
+		findings:      report.findings,
+		columns:       columns,
+		columnTypes:   columnTypes,
+		resultColumns: resultColumns,
+		withResultSet: withResultSet,
+		rowIndex:      resultFirstDataRow, // Start after the title block and the headers
+		valueMaps:     columnValueMaps(columns, query),
+		formatHints:   columnFormatHints(columns, query),
+		rankedColumn:  rankedColumn(columns, columnTypes, query),
+		alertColumn:   alertColumn(columns, columnTypes, query),
+		redacted:      opts.Redaction.columns(columns),
+	}
+
+	// Create new sheet
+	f.NewSheet(name)
+
+	// Write the full query name and description above the table
+	f.SetCellValue(name, fmt.Sprintf("A%d", resultTitleRow), query.Name)
+	if styleID, err := report.cellStyle("result_title", &excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}}); err == nil {
+		f.SetCellStyle(name, fmt.Sprintf("A%d", resultTitleRow), fmt.Sprintf("A%d", resultTitleRow), styleID)
+	}
+	if description := strings.TrimSpace(query.Description); description != "" {
+		f.SetCellValue(name, fmt.Sprintf("A%d", resultDescriptionRow), description)
+	}
+
+	// Write headers to the header row, using the friendly column labels where the query defines them
+	headers := applyColumnLabels(columns, query)
+	if withResultSet {
+		headers = append([]string{"result_set"}, headers...)
+	}
+	for colIndex, colName := range headers {
+		cell, _ := excelize.CoordinatesToCellName(colIndex+1, resultHeaderRow)
+		f.SetCellValue(name, cell, colName)
+		s.measure(colIndex+1, colName)
+	}
+	if len(columns) < resultColumns {
+		cell, _ := excelize.CoordinatesToCellName(len(headers)+1, resultHeaderRow)
+		f.SetCellValue(name, cell, truncationNote(len(columns), resultColumns))
+	}
+
+	// Missing index recommendations are only collected from results carrying the missing index DMV columns
+	if opts.ExplainMissingIndex {
+		s.missingIndex = findMissingIndexColumns(columns)
+	}
+
+	// Statistics for the numeric columns are accumulated while the rows stream
+	if opts.Summarize {
+		s.stats = make([]columnStats, len(headers))
+	}
+
+	return s
+}
+
+/*
+ * resultTableRows returns the rows of a result sheet read with GetRows from its header row on, leaving out the
+ * title block. A sheet of a workbook written before the title block, its header on the first row, is returned whole.
+ *
+ * Notes:
+ * - The title block is recognized by its single cell title and description rows followed by a blank row.
+ */
+func resultTableRows(rows [][]string) [][]string {
+	if len(rows) >= resultHeaderRow && len(rows[resultTitleRow-1]) <= 1 && len(rows[resultDescriptionRow-1]) <= 1 &&
+		len(rows[resultHeaderRow-2]) == 0 && len(rows[resultHeaderRow-1]) > 0 {
+		return rows[resultHeaderRow-1:]
+	}
+	return rows
+}
+
+/*
+ * mirrorTo sends the first result set written to the sheet to the writer of the other `-format` formats,
+ * with the same name, column labels and mapped values as the `-format` writers produce on their own.
+ * The further result sets of a query are mirrored from their own sheets.
+ *
+ * Notes:
+ * - The writer is a multiWriter, a failing format is dropped there and never stops the Excel sheet.
+ */
+func (s *resultSheet) mirrorTo(writer ResultWriter) {
+	if err := writer.BeginResult(s.name, s.query, applyColumnLabels(s.columns, s.query), s.columnTypes); err == nil {
+		s.mirror = writer
+	}
+}
+
+/*
+ * firstColumn returns the sheet column number of the first result column, shifted right by one
+ * when the "result_set" column is written.
+ */
+func (s *resultSheet) firstColumn() int {
+	if s.withResultSet {
+		return 2
+	}
+	return 1
+}
+
+/*
+ * writeRows writes every row of the current result set of `rows` to the sheet.
+ *
+ * Parameters:
+ * - rows: The query rows, positioned on the result set to write, its columns are scanned in the sheet's display order.
+ * - resultSet: The 1 based number of the result set, written to the "result_set" column when enabled.
+ *
+ * Returns:
+ * - error: Returns an error if row iteration fails, nil otherwise.
+ *
+ * Notes:
+ * - Rows that fail to scan are logged and skipped, and recorded as data issues with strict scanning.
+ * - Reading stops once the sheet holds `-max-rows` rows and another one was read, the remaining rows of the
+ *   result set are discarded by the driver when the next result set is read or the rows are closed.
+ */
+func (s *resultSheet) writeRows(rows resultRows, resultSet int) error {
+	// Create a slice of interface{}'s to hold each column value, every column is scanned even when only the
+	// first -max-columns ones are written
+	scanned := make([]interface{}, s.resultColumns)
+	for i := range scanned {
+		scanned[i] = new(interface{})
+	}
+	values := scanned[:len(s.columns)]
+
+	// Scan each column straight into its display position
+	resultColumns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to get columns: %v", err)
+	}
+	order, _ := columnOrder(resultColumns, s.query, frontColumnList(s.query, s.opts))
+	targets := scanTargets(scanned, order)
+
+	// A query with sortRows has its result set buffered and sorted before the first row is written
+	var source rowSource = rows
+	if len(s.query.SortRows) > 0 {
+		source = sortedRows(rows, resultColumns, s.query)
+	}
+
+	first := s.firstColumn()
+	startRow := s.rowIndex
+
+	// Write data rows, up to -max-rows rows per sheet
+	for source.Next() {
+		if rowLimitReached(s.opts, s.rowIndex-resultFirstDataRow) {
+			if !s.truncated {
+				logWarn("Query %s: only the first %d rows are written to %s (-max-rows)", s.query.Name, s.opts.MaxRows, s.name)
+			}
+			s.truncated = true
+			if s.outcome != nil {
+				s.outcome.Truncated = true
+			}
+			break
+		}
+
+		err := source.Scan(targets...)
+		if err != nil {
+			logError("Failed to scan row: %v", err)
+			if s.opts.StrictScan {
+				cell, _ := excelize.CoordinatesToCellName(first, s.rowIndex)
+				s.findings.DataIssues = append(s.findings.DataIssues, DataIssue{Sheet: s.name, Cell: cell, Column: "*", Issue: fmt.Sprintf("row scan failed and was skipped: %v", err)})
+			}
+			continue
+		}
+
+		// The redacted values replace the scanned ones, nothing below sees the original value
+		s.opts.Redaction.apply(values, s.redacted)
+
+		if s.withResultSet {
+			cell, _ := excelize.CoordinatesToCellName(1, s.rowIndex)
+			s.f.SetCellValue(s.name, cell, resultSet)
+			s.measure(1, resultSet)
+		}
+
+		// Write each cell value
+		for colIndex, val := range values {
+			cell, _ := excelize.CoordinatesToCellName(colIndex+first, s.rowIndex)
+			v := *(val.(*interface{}))
+
+			if s.opts.StrictScan && (s.redacted == nil || !s.redacted[colIndex]) {
+				if issue := checkScannedValue(v, s.columnTypes[colIndex]); issue != "" {
+					s.findings.DataIssues = append(s.findings.DataIssues, DataIssue{Sheet: s.name, Cell: cell, Column: s.columns[colIndex], Issue: issue})
+				}
+			}
+
+			if s.opts.Summarize {
+				s.stats[colIndex+first-1].add(v)
+			}
+
+			if colIndex == s.alertColumn && s.outcome != nil && s.query.Alert.matches(v) {
+				s.outcome.Alerts++
+			}
+
+			// Mapped values replace the raw value in the sheet only, the checks above still see the raw value
+			if s.valueMaps != nil {
+				if display, ok := mapCellValue(s.valueMaps[colIndex], v); ok {
+					s.f.SetCellValue(s.name, cell, display)
+					s.measure(colIndex+first, display)
+					continue
+				}
+			}
+
+			// Hinted columns are written as numbers so their number format applies, the ranked and alert columns so
+			// Excel can compare them
+			if (s.formatHints != nil && s.formatHints[colIndex] != "") || colIndex == s.rankedColumn || colIndex == s.alertColumn {
+				if n, ok := s.setNumericCellValue(cell, v, s.columnTypes[colIndex]); ok {
+					s.measure(colIndex+first, n)
+					continue
+				}
+			}
+
+			s.measure(colIndex+first, s.setTypedCellValue(cell, v, s.columnTypes[colIndex]))
+		}
+		if s.snapshot != nil {
+			s.snapshot.add(values)
+		}
+		if s.outcome != nil {
+			s.outcome.add(s.columns, values)
+		}
+		if s.mirror != nil {
+			s.mirrorRow(values)
+		}
+		if s.missingIndex != nil {
+			if recommendation, ok := s.missingIndex.recommendation(values, s.name); ok {
+				s.findings.MissingIndexes = append(s.findings.MissingIndexes, recommendation)
+			}
+		}
+		s.rowIndex++
+	}
+
+	// Only the first result set of a sheet is mirrored, a further result set stacked onto it is not
+	if s.mirror != nil {
+		s.mirror.EndResult()
+		s.mirror = nil
+	}
+
+	if s.rowIndex > startRow {
+		s.resultSetRows = append(s.resultSetRows, [2]int{startRow, s.rowIndex - 1})
+	}
+
+	// Check for errors during row iteration
+	if err := source.Err(); err != nil {
+		return fmt.Errorf("error occurred during row iteration: %v", err)
+	}
+	return nil
+}
+
+/*
+ * mirrorRow writes a scanned row to the mirror writer, replacing the mapped values with their display text.
+ */
+func (s *resultSheet) mirrorRow(values []interface{}) {
+	row := make([]interface{}, len(values))
+	for i, val := range values {
+		row[i] = *(val.(*interface{}))
+		if s.valueMaps != nil {
+			if display, ok := mapCellValue(s.valueMaps[i], row[i]); ok {
+				row[i] = display
+			}
+		}
+	}
+	if err := s.mirror.WriteRow(row); err != nil {
+		s.mirror = nil
+	}
+}
+
+/*
+ * finish completes the sheet once all its rows are written, fitting the column widths for `-max-col-width`,
+ * applying the query's format hints, grouping
+ * the result sets for `-outline-groups`, shading the data range for `-banded-rows`, flagging the `alert` cells, noting the rows left out by
+ * `-max-rows` and writing the statistics sheet for `-summarize`, computed over the rows written.
+ */
+func (s *resultSheet) finish() {
+	s.fitColumnWidths()
+	if s.formatHints != nil {
+		applyFormatHints(s.report, s.name, s.formatHints, s.firstColumn(), s.rowIndex-1)
+	}
+	if s.opts.OutlineGroups && s.withResultSet {
+		s.outlineResultSets()
+	}
+	if s.opts.BandedRows {
+		s.bandRows()
+	}
+	s.highlightTopRows()
+	s.highlightAlerts()
+	if s.truncated {
+		cell, _ := excelize.CoordinatesToCellName(1, s.rowIndex)
+		s.f.SetCellValue(s.name, cell, rowLimitNote(s.opts.MaxRows))
+		s.rowIndex++
+	}
+	if s.opts.Summarize {
+		headers := applyColumnLabels(s.columns, s.query)
+		if s.withResultSet {
+			headers = append([]string{"result_set"}, headers...)
+		}
+		writeStatsSheet(s.f, s.name, headers, s.stats)
+	}
+}
+
+/*
+ * outlineResultSets groups the rows of every result set stacked on a combined sheet with Excel outline levels,
+ * so each result set can be collapsed to its first row.
+ *
+ * Notes:
+ * - Only sheets holding several result sets, the sheets of queries with `aggregateResultSets`, are grouped.
+ * - The first row of each result set stays visible as the group heading, the summary row sits above the details.
+ */
+func (s *resultSheet) outlineResultSets() {
+	if len(s.resultSetRows) < 2 {
+		return
+	}
+	s.f.SetSheetProps(s.name, &excelize.SheetPropsOptions{OutlineSummaryBelow: boolPtr(false)})
+	for _, rows := range s.resultSetRows {
+		for row := rows[0] + 1; row <= rows[1]; row++ {
+			if err := s.f.SetRowOutlineLevel(s.name, row, 1); err != nil {
+				logError("Failed to group row %d of sheet %s: %v", row, s.name, err)
+				return
+			}
+		}
+	}
+}
+
+/*
+ * bandRows shades every other row of the data range with a single MOD(ROW(),2) conditional format.
+ *
+ * Notes:
+ * - The rule is evaluated per row by Excel, so the banding stays correct after sorting or filtering.
+ * - The range stops at the last data row.
+ */
+func (s *resultSheet) bandRows() {
+	if s.rowIndex <= resultFirstDataRow {
+		return
+	}
+	styleID, err := s.report.conditionalStyle("banded_rows", &excelize.Style{
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"F2F2F2"}},
+	})
+	if err != nil {
+		logError("Failed to create the banded rows style: %v", err)
+		return
+	}
+	lastColumn := len(s.columns) + s.firstColumn() - 1
+	lastCell, _ := excelize.CoordinatesToCellName(lastColumn, s.rowIndex-1)
+	err = s.f.SetConditionalFormat(s.name, fmt.Sprintf("A%d:%s", resultFirstDataRow, lastCell), []excelize.ConditionalFormatOptions{
+		{Type: "formula", Criteria: "MOD(ROW(),2)=0", Format: &styleID},
+	})
+	if err != nil {
+		logError("Failed to band the rows of sheet %s: %v", s.name, err)
+	}
+}
+
+/*
+ * sameSchema reports whether the columns and types of a result set match those of the sheet,
+ * in which case the result set can be stacked below the sheet's existing rows.
+ */
+func (s *resultSheet) sameSchema(columns []string, columnTypes []*sql.ColumnType) bool {
+	order, _ := columnOrder(columns, s.query, frontColumnList(s.query, s.opts))
+	columns = reorderColumns(columns, order)
+	columnTypes = reorderColumns(columnTypes, order)
+	if len(columns) != s.resultColumns || len(columnTypes) != s.resultColumns {
+		return false
+	}
+	columns, columnTypes = columns[:len(s.columns)], columnTypes[:len(s.columns)]
+	for i := range columns {
+		if !strings.EqualFold(columns[i], s.columns[i]) {
+			return false
+		}
+		if columnTypes[i].DatabaseTypeName() != s.columnTypes[i].DatabaseTypeName() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package diagnostics
+
+import (
+	"strconv" // For reading the numbers of the run_summary sheet
+	"time"    // For the durations of the kept queries
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+/*
+ * keptQuery is a query whose result sheet is kept from the `-resume-workbook` workbook instead of being run again.
+ *
+ * Fields:
+ * - Rows: The rows of the sheet, from the run_summary sheet of the workbook when it has one.
+ * - Duration: The duration recorded on the run_summary sheet, 0 when the workbook has none.
+ */
+type keptQuery struct {
+	Rows     int
+	Duration time.Duration
+}
+
+/*
+ * resumeWorkbook prepares the workbook of an earlier run for `-resume-workbook`: it finds the queries whose result
+ * is already in it and removes the sheets of the others, which are run again.
+ *
+ * Parameters:
+ * - f: The workbook of the earlier run, opened with excelize.OpenFile.
+ * - queries: The queries of the run, in query order.
+ *
+ * Returns:
+ * - The kept queries keyed by result sheet name.
+ *
+ * Functionality:
+ * 1. When the workbook has a run_summary sheet, the run completed: only the queries with a Success status and
+ *    a result sheet are kept, the failed, timed out and skipped ones run again.
+ * 2. Otherwise the run died before its end: every query whose result sheet exists with at least its header row is
+ *    kept. The sheets are saved whole by -save-every, after their query finished.
+ * 3. The result sheets of the queries run again, those of their further result sets and their `-summarize`
+ *    statistics sheets are deleted so no rows of the earlier attempt are left.
+ *
+ * Notes:
+ * - Queries are matched on their sheet name, the Sr.No and name, so the queries file must not be reordered.
+ */
+func resumeWorkbook(f *excelize.File, queries Queries) map[string]keptQuery {
+	summary := map[string][]string{}
+	summaryRows, err := f.GetRows(runSummarySheetName)
+	completed := err == nil && len(summaryRows) > 0
+	for _, row := range summaryRows {
+		if len(row) > 2 {
+			summary[row[2]] = row
+		}
+	}
+
+	kept := make(map[string]keptQuery)
+	for i, query := range queries.Queries {
+		sheetName := createSheetName(i+1, query.Name)
+		rows, err := f.GetRows(sheetName)
+		exists := err == nil && len(rows) > 0
+
+		if completed {
+			row := summary[sheetName]
+			if exists && len(row) > 5 && row[5] == statusSuccess {
+				count, _ := strconv.Atoi(row[3])
+				ms, _ := strconv.ParseInt(row[4], 10, 64)
+				kept[sheetName] = keptQuery{Rows: count, Duration: time.Duration(ms) * time.Millisecond}
+				continue
+			}
+		} else if exists {
+			kept[sheetName] = keptQuery{Rows: len(resultTableRows(rows)) - 1}
+			continue
+		}
+
+		// The query runs again, the sheets of its earlier attempt and their -summarize statistics are replaced
+		deleteSheet := func(name string) {
+			f.DeleteSheet(name)
+			if idx, _ := f.GetSheetIndex(statsSheetName(name)); idx != -1 {
+				f.DeleteSheet(statsSheetName(name))
+			}
+		}
+		if idx, _ := f.GetSheetIndex(sheetName); idx != -1 {
+			deleteSheet(sheetName)
+		}
+		for resultSet := 2; ; resultSet++ {
+			further := resultSetSheetName(sheetName, resultSet)
+			if idx, _ := f.GetSheetIndex(further); idx == -1 {
+				break
+			}
+			deleteSheet(further)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,123 @@
+package diagnostics
+
+import (
+	"fmt"     // For formatted I/O operations
+	"os"      // For checking whether stdin is a terminal
+	"regexp"  // For matching the risky patterns
+	"strings" // For string manipulation
+
+	"golang.org/x/term" // For detecting a non-interactive run
+)
+
+/*
+ * riskyPattern is a construct that makes a query more than a plain read, listed in the confirmation prompt.
+ */
+type riskyPattern struct {
+	name    string         // Shown to the user next to the query
+	pattern *regexp.Regexp // Matched against the query with comments and string literals removed
+}
+
+// Patterns that require explicit acknowledgment before a query runs
+var riskyPatterns = []riskyPattern{
+	{"dynamic SQL (sp_executesql)", regexp.MustCompile(`(?i)\bsp_executesql\b`)},
+	{"EXEC / EXECUTE", regexp.MustCompile(`(?i)\bEXEC(UTE)?\b`)},
+	{"linked server (OPENQUERY / OPENROWSET / OPENDATASOURCE)", regexp.MustCompile(`(?i)\bOPEN(QUERY|ROWSET|DATASOURCE)\b`)},
+	{"linked server four-part name", regexp.MustCompile(`(?:(?:\[[^\]]+\]|[A-Za-z_@#][\w@#$]*)\.){3}(?:\[[^\]]+\]|[A-Za-z_@#][\w@#$]*)`)},
+	{"data or schema modification", regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE|TRUNCATE|DROP|ALTER|CREATE|GRANT|REVOKE|DENY)\b`)},
+}
+
+// Comments and string literals, removed before matching so text in them is not flagged
+var sqlCommentsAndLiterals = regexp.MustCompile(`(?s)--[^\n]*|/\*.*?\*/|N?'(?:[^']|'')*'`)
+
+/*
+ * riskyQuery is a query matching one or more risky patterns.
+ */
+type riskyQuery struct {
+	Index    int      // 1 based position of the query in the file
+	Name     string   // Name of the query
+	Patterns []string // Names of the matched patterns
+}
+
+/*
+ * findRiskyQueries scans the queries selected by `-only` and `-tag` for the risky patterns.
+ *
+ * Parameters:
+ * - queries: The queries read from the queries file.
+ * - opts: The run options, the queries `selectedQuery` leaves out never run and are not scanned.
+ *
+ * Returns:
+ * - The selected queries matching at least one pattern, in file order. Queries not listed are plain reads or
+ *   not selected.
+ *
+ * Notes:
+ * - Comments and string literals are ignored, so a note like '-- does not UPDATE anything' is not flagged.
+ * - Temporary table and table variable writes (INSERT INTO #t) are flagged too, the scan cannot tell them apart.
+ */
+func findRiskyQueries(queries Queries, opts RunOptions) []riskyQuery {
+	var risky []riskyQuery
+	for i, query := range queries.Queries {
+		if !selectedQuery(opts, query) {
+			continue
+		}
+		text := sqlCommentsAndLiterals.ReplaceAllString(query.Query, " ")
+		var matched []string
+		for _, p := range riskyPatterns {
+			if p.pattern.MatchString(text) {
+				matched = append(matched, p.name)
+			}
+		}
+		if len(matched) > 0 {
+			risky = append(risky, riskyQuery{Index: i + 1, Name: query.Name, Patterns: matched})
+		}
+	}
+	return risky
+}
+
+/*
+ * confirmRiskyQueries lists the risky queries of the run and asks the user to acknowledge them.
+ *
+ * Parameters:
+ * - queries: The queries read from the queries file.
+ * - opts: The run options, only the queries selected by `-only` and `-tag` are listed.
+ * - ackRisky: The `-ack-risky` or `-yes` flag, acknowledges the listed queries without prompting.
+ *
+ * Returns:
+ * - true when the run may proceed: no query is risky, `ackRisky` is set, stdin is not a terminal or the user
+ *   typed "yes".
+ *
+ * Notes:
+ * - A run from cron, a scheduled task or a pipe has no terminal to answer the prompt, it proceeds without
+ *   confirmation as with `-yes`. This bypasses the safety prompt, review the listed queries before scheduling
+ *   a queries file.
+ */
+func confirmRiskyQueries(queries Queries, opts RunOptions, ackRisky bool) bool {
+	risky := findRiskyQueries(queries, opts)
+	selected := selectedCount(opts, queries)
+	if len(risky) == 0 {
+		logInfo("All %d queries are plain reads, no confirmation needed.", selected)
+		return true
+	}
+
+	fmt.Println("=======================================================================================================================================================")
+	fmt.Println("IMPORTANT - Please Read !!!")
+	fmt.Printf("%d of the %d queries use constructs that can do more than read diagnostic data:\n", len(risky), selected)
+	for _, r := range risky {
+		fmt.Printf("  %3d. %s: %s\n", r.Index, r.Name, strings.Join(r.Patterns, ", "))
+	}
+	fmt.Println("Review these queries in the queries file and make sure they will not delete data or maliciously alter the database.")
+	fmt.Println("=======================================================================================================================================================")
+
+	if ackRisky {
+		fmt.Println("Acknowledged with -ack-risky or -yes.")
+		return true
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		logWarn("Stdin is not a terminal, proceeding with the %d risky queries without confirmation.", len(risky))
+		return true
+	}
+
+	fmt.Println("Type 'yes' to confirm these queries and proceed, or any other key to exit.")
+	var confirmation string
+	fmt.Scanln(&confirmation)
+	return strings.ToLower(confirmation) == "yes"
+}
@@ -0,0 +1,39 @@
+package diagnostics
+
+import (
+	"fmt"     // For formatting the risky queries
+	"testing" // For the test framework
+)
+
+/*
+ * TestFindRiskyQueries checks only the queries selected by -only and -tag are scanned, keeping their position in
+ * the queries file, so a run never asks to confirm a query it will not execute.
+ */
+func TestFindRiskyQueries(t *testing.T) {
+	queries := Queries{Queries: []Query{
+		{Name: "Wait Stats", Query: "SELECT * FROM sys.dm_os_wait_stats", Tags: []string{"waits"}},
+		{Name: "Clear Wait Stats", Query: "DBCC SQLPERF('sys.dm_os_wait_stats', CLEAR); EXEC sp_who2", Tags: []string{"waits"}},
+		{Name: "Index Rebuild", Query: "ALTER INDEX ALL ON dbo.t REBUILD", Tags: []string{"maintenance"}},
+		{Name: "Commented", Query: "SELECT 1 -- does not UPDATE anything", Tags: []string{"maintenance"}},
+	}}
+	tests := []struct {
+		name string
+		opts RunOptions
+		want string
+	}{
+		{name: "every query", opts: RunOptions{}, want: "[{2 Clear Wait Stats [EXEC / EXECUTE]} {3 Index Rebuild [data or schema modification]}]"},
+		{name: "only a plain read", opts: RunOptions{Only: []string{"Wait Stats"}}, want: "[]"},
+		{name: "only a risky query", opts: RunOptions{Only: []string{"index rebuild"}}, want: "[{3 Index Rebuild [data or schema modification]}]"},
+		{name: "tag", opts: RunOptions{Tags: []string{"waits"}}, want: "[{2 Clear Wait Stats [EXEC / EXECUTE]}]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmt.Sprint(findRiskyQueries(queries, tt.opts)); got != tt.want {
+				t.Errorf("findRiskyQueries = %s, want %s", got, tt.want)
+			}
+		})
+	}
+	if !confirmRiskyQueries(queries, RunOptions{Only: []string{"Wait Stats"}}, false) {
+		t.Errorf("confirmRiskyQueries asked to confirm the risky queries -only leaves out")
+	}
+}
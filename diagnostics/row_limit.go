@@ -0,0 +1,38 @@
+package diagnostics
+
+import (
+	"fmt" // For formatted I/O operations
+)
+
+/*
+ * rowLimitReached reports whether a result with `written` rows already written has reached `-max-rows`.
+ *
+ * Notes:
+ * - It is checked once the next row was read, so a result of exactly `-max-rows` rows is never reported as
+ *   truncated.
+ */
+func rowLimitReached(opts RunOptions, written int) bool {
+	return opts.MaxRows > 0 && written >= opts.MaxRows
+}
+
+/*
+ * rowLimitNote returns the text of the row written below a result cut by `-max-rows`. The total is not known,
+ * the remaining rows are not read.
+ */
+func rowLimitNote(limit int) string {
+	return fmt.Sprintf("… truncated, first %d rows shown (-max-rows), the remaining rows were not read", limit)
+}
+
+/*
+ * prefetchRowLimit returns how many rows of each result set a `-parallel` worker keeps in memory, one more than
+ * `-max-rows` so the sheet knows the result was truncated, 0 for all of them.
+ *
+ * Notes:
+ * - A query with `sortRows` keeps all its rows, the first rows after sorting are not the first ones returned.
+ */
+func prefetchRowLimit(opts RunOptions, query Query) int {
+	if opts.MaxRows <= 0 || len(query.SortRows) > 0 {
+		return 0
+	}
+	return opts.MaxRows + 1
+}
@@ -0,0 +1,204 @@
+package diagnostics
+
+import (
+	"bytes"   // For comparing binary values
+	"sort"    // For sorting the buffered rows
+	"strconv" // For formatting boolean values
+	"strings" // For string manipulation
+	"time"    // For comparing date and time values
+)
+
+// The `sortRows` entry sorting by every column
+const sortAllColumns = "*"
+
+/*
+ * rowSource is what the result writers read rows from, *sql.Rows or the sorted rows of a query with `sortRows`.
+ */
+type rowSource interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+}
+
+/*
+ * bufferedRows holds every row of a result set in memory, sorted, and hands them out like *sql.Rows.
+ *
+ * Fields:
+ * - rows: The scanned values of every row, in result column order.
+ * - current: The index of the row returned by the next Scan, -1 before the first Next.
+ * - err: The error that ended the reading of the result set, if any.
+ */
+type bufferedRows struct {
+	rows    [][]interface{}
+	current int
+	err     error
+}
+
+/*
+ * sortedRows reads the current result set of `rows` into memory and sorts it for the query's `sortRows`, so the
+ * rows are written in the same order whatever order the server returned them in.
+ *
+ * Parameters:
+ * - rows: The query rows, positioned on the result set to sort.
+ * - columns: The column names of the result set, in the order the query returns them.
+ * - query: The query, whose `sortRows` names the key columns or "*" for every column.
+ *
+ * Returns:
+ * - The sorted rows, read with Next and Scan like *sql.Rows.
+ *
+ * Notes:
+ * - Rows are sorted by the key columns in order, then by every column, so rows with equal keys are ordered too.
+ * - Key columns missing from the result are ignored with a warning.
+ * - Rows that fail to scan are logged and skipped, as when the rows stream.
+ * - The whole result set is held in memory before the first row is written, a result set of millions of rows
+ *   needs memory for all of them at once. Only opt in for results of a manageable size.
+ */
+func sortedRows(rows rowSource, columns []string, query Query) *bufferedRows {
+	keys := sortKeyIndexes(columns, query)
+
+	buffered := readRows(rows, len(columns), 0)
+	sort.SliceStable(buffered.rows, func(i, j int) bool {
+		a, b := buffered.rows[i], buffered.rows[j]
+		for _, key := range keys {
+			if c := compareValues(a[key], b[key]); c != 0 {
+				return c < 0
+			}
+		}
+		for column := range a {
+			if c := compareValues(a[column], b[column]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return buffered
+}
+
+/*
+ * readRows reads the remaining rows of the current result set of `rows` into memory, in the order they are
+ * returned, at most `limit` of them when it is above 0. Rows that fail to scan are logged and skipped, as when
+ * the rows stream.
+ */
+func readRows(rows rowSource, width int, limit int) *bufferedRows {
+	buffered := &bufferedRows{current: -1}
+	for (limit <= 0 || len(buffered.rows) < limit) && rows.Next() {
+		values := make([]interface{}, width)
+		targets := make([]interface{}, width)
+		for i := range values {
+			targets[i] = &values[i]
+		}
+		if err := rows.Scan(targets...); err != nil {
+			logError("Failed to scan row: %v", err)
+			continue
+		}
+		buffered.rows = append(buffered.rows, values)
+	}
+	buffered.err = rows.Err()
+	return buffered
+}
+
+/*
+ * sortKeyIndexes resolves the query's `sortRows` to result column indexes, matching column names or labels
+ * case insensitively. For "*", or a list naming no column of the result, no key is returned and the rows are
+ * sorted by every column.
+ */
+func sortKeyIndexes(columns []string, query Query) []int {
+	labels := applyColumnLabels(columns, query)
+	var keys []int
+	var missing []string
+	for _, key := range query.SortRows {
+		if key == sortAllColumns {
+			return nil
+		}
+		found := false
+		for i := range columns {
+			if strings.EqualFold(columns[i], key) || strings.EqualFold(labels[i], key) {
+				keys = append(keys, i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		logWarn("Query %s: sort columns not found in the result are ignored: %s", query.Name, strings.Join(missing, ", "))
+	}
+	return keys
+}
+
+/*
+ * compareValues orders two scanned values: NULL first, then numbers, dates and text by their natural order.
+ * DECIMAL and NUMERIC values, scanned as text, are compared as numbers.
+ */
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	if x, ok := numericValue(a); ok {
+		if y, ok := numericValue(b); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	if x, ok := a.(time.Time); ok {
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y)
+		}
+	}
+	if x, ok := a.([]byte); ok {
+		if y, ok := b.([]byte); ok {
+			return bytes.Compare(x, y)
+		}
+	}
+	return strings.Compare(sortText(a), sortText(b))
+}
+
+/*
+ * sortText returns the text a value is compared by when it is neither a number nor a date.
+ */
+func sortText(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	case bool:
+		return strconv.FormatBool(value)
+	}
+	return cleanCellValue(v)
+}
+
+// Next moves to the next buffered row
+func (b *bufferedRows) Next() bool {
+	if b.current+1 >= len(b.rows) {
+		return false
+	}
+	b.current++
+	return true
+}
+
+// Scan copies the current row into the destinations, each a *interface{} as the result writers scan into
+func (b *bufferedRows) Scan(dest ...interface{}) error {
+	for i, value := range b.rows[b.current] {
+		*(dest[i].(*interface{})) = value
+	}
+	return nil
+}
+
+// Err returns the error that ended the reading of the result set
+func (b *bufferedRows) Err() error {
+	return b.err
+}
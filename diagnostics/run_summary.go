@@ -0,0 +1,87 @@
+package diagnostics
+
+import (
+	"fmt"     // For formatted I/O operations
+	"strings" // For joining the messages of a query
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+// Name of the sheet listing the row count, duration and status of every query of the run
+const runSummarySheetName = "run_summary"
+
+/*
+ * writeRunSummarySheet writes the "run_summary" sheet, one row per query telling whether it returned data and
+ * how long it took.
+ *
+ * Parameters:
+ * - f: A pointer to the excelize.File object representing the Excel file.
+ * - queries: The queries of the run, in query order.
+ * - findings: The findings holding the outcome of every query run.
+ * - opts: The run options, `MaxRows` is the limit written for the truncated queries.
+ *
+ * Notes:
+ * - The sheet is created right after executed_queries before the queries run, so it keeps that position.
+ * - Queries without an outcome, those left out by `-only` or after the failure that stopped a
+ *   `-stop-on-first-error` run, are Skipped with no rows or duration.
+ * - A failed or timed out query has its error in the Error column.
+ * - The informational messages the server sent for a query, PRINT and RAISERROR output, are listed one per
+ *   line in the Messages column.
+ * - A query with a result sheet cut by `-max-rows` has the limit in the Truncated column, its Rows only count the
+ *   rows written.
+ * - A query with an `alert` rule has the number of rows meeting it in the Alerts column, filled red when not 0.
+ */
+func writeRunSummarySheet(f *excelize.File, queries Queries, findings *ReportFindings, opts RunOptions) {
+	if idx, _ := f.GetSheetIndex(runSummarySheetName); idx == -1 {
+		f.NewSheet(runSummarySheetName)
+	}
+
+	headers := []string{"Sr.No", "Query Name", "Sheet", "Rows", "Duration (ms)", "Status", "Error", "Messages", "Truncated", "Alerts", "Alert Rule"}
+	for colIndex, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(colIndex+1, 1)
+		f.SetCellValue(runSummarySheetName, cell, header)
+	}
+
+	// Red fill of the Alerts cells of the queries with rows meeting their alert rule
+	alertStyle, err := f.NewStyle(&excelize.Style{
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}},
+		Font: &excelize.Font{Color: "9C0006", Bold: true},
+	})
+	if err != nil {
+		logError("Failed to create the alert style: %v", err)
+	}
+
+	for i, query := range queries.Queries {
+		rowNum := i + 2 // Start from row 2 (after header)
+		sheetName := createSheetName(i+1, query.Name)
+		f.SetCellValue(runSummarySheetName, fmt.Sprintf("A%d", rowNum), i+1)
+		f.SetCellValue(runSummarySheetName, fmt.Sprintf("B%d", rowNum), query.Name)
+		f.SetCellValue(runSummarySheetName, fmt.Sprintf("C%d", rowNum), sheetName)
+
+		outcome := findings.outcomeOf(sheetName)
+		if outcome == nil {
+			f.SetCellValue(runSummarySheetName, fmt.Sprintf("F%d", rowNum), statusSkipped)
+			continue
+		}
+		f.SetCellValue(runSummarySheetName, fmt.Sprintf("D%d", rowNum), outcome.Rows)
+		f.SetCellValue(runSummarySheetName, fmt.Sprintf("E%d", rowNum), outcome.Duration.Milliseconds())
+		f.SetCellValue(runSummarySheetName, fmt.Sprintf("F%d", rowNum), outcome.Status)
+		f.SetCellValue(runSummarySheetName, fmt.Sprintf("G%d", rowNum), outcome.Error)
+		f.SetCellValue(runSummarySheetName, fmt.Sprintf("H%d", rowNum), strings.Join(outcome.Messages, "\n"))
+		if outcome.Truncated {
+			f.SetCellValue(runSummarySheetName, fmt.Sprintf("I%d", rowNum), fmt.Sprintf("first %d rows written (-max-rows)", opts.MaxRows))
+		}
+
+		if query.Alert != nil {
+			f.SetCellValue(runSummarySheetName, fmt.Sprintf("J%d", rowNum), outcome.Alerts)
+			f.SetCellValue(runSummarySheetName, fmt.Sprintf("K%d", rowNum), query.Alert.String())
+			if outcome.Alerts > 0 && alertStyle != 0 {
+				f.SetCellStyle(runSummarySheetName, fmt.Sprintf("J%d", rowNum), fmt.Sprintf("J%d", rowNum), alertStyle)
+			}
+		}
+
+		if idx, _ := f.GetSheetIndex(sheetName); idx != -1 {
+			f.SetCellHyperLink(runSummarySheetName, fmt.Sprintf("C%d", rowNum), fmt.Sprintf("'%s'!A1", sheetName), "Location")
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package diagnostics
+
+import (
+	"context"       // For stopping an interrupted run
+	"crypto/sha256" // For deriving the default run ID
+	"encoding/hex"  // For encoding the default run ID
+	"encoding/json" // For reading and writing the run state file
+	"fmt"           // For formatted I/O operations
+	"math/rand"     // For the random jitter added to the interval
+	"os"            // For interacting with the operating system (e.g., file operations)
+	"path/filepath" // For naming the run state file
+	"strconv"       // For parsing the jitter percentage
+	"strings"       // For string manipulation
+	"time"          // For working with date and time
+)
+
+/*
+ * ScheduleOptions holds the command line settings for running the queries repeatedly.
+ *
+ * Fields:
+ * - Interval: Minutes between iterations.
+ * - Duration: Hours to keep running iterations.
+ * - RunID: Identifies the scheduled run, names the state file used to resume it.
+ * - Resume: Continue a previously interrupted run with the same run ID instead of starting over.
+ * - ChangesQuery: Name of the query whose results are compared between iterations in the changes workbook.
+ * - ChangesKey: Columns identifying a row of the changes query, the whole row when empty.
+ * - Jitter: Maximum random offset added to or removed from every interval, as parsed by `parseJitter`.
+ * - AlignToClock: Start the iterations on the wall clock boundaries of the interval, see `alignedStart`.
+ */
+type ScheduleOptions struct {
+	Interval     int      // Minutes between iterations
+	Duration     int      // Hours to keep running
+	RunID        string   // Run identifier keying the state file
+	Resume       bool     // Resume an interrupted run from its state file
+	ChangesQuery string   // Query compared between iterations
+	ChangesKey   []string // Key columns of the changes query
+	Jitter       jitter   // Random offset applied to each interval
+	AlignToClock bool     // Start the iterations on the clock boundaries of the interval
+}
+
+/*
+ * jitter is the random offset applied to the intervals, either a fixed duration or a
+ * percentage of the interval. The zero value applies no jitter.
+ */
+type jitter struct {
+	Duration time.Duration // Fixed maximum offset
+	Percent  float64       // Maximum offset as a percentage of the interval
+}
+
+/*
+ * parseJitter parses the `-jitter` flag, a Go duration such as "30s" or "2m", or a percentage of the
+ * interval such as "10%". An empty value means no jitter.
+ */
+func parseJitter(value string) (jitter, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return jitter{}, nil
+	}
+	if strings.HasSuffix(value, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return jitter{}, fmt.Errorf("invalid jitter percentage %q, expected a value between 0%% and 100%%", value)
+		}
+		return jitter{Percent: percent}, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return jitter{}, fmt.Errorf("invalid jitter %q, expected a duration such as 30s or a percentage such as 10%%", value)
+	}
+	return jitter{Duration: duration}, nil
+}
+
+/*
+ * apply returns the interval shifted by a random offset of at most the jitter either way, never less than zero.
+ */
+func (j jitter) apply(interval time.Duration, random *rand.Rand) time.Duration {
+	spread := j.Duration
+	if j.Percent > 0 {
+		spread = time.Duration(float64(interval) * j.Percent / 100)
+	}
+	if spread <= 0 {
+		return interval
+	}
+	sleep := interval + time.Duration(random.Int63n(int64(2*spread)+1)) - spread
+	if sleep < 0 {
+		return 0
+	}
+	return sleep
+}
+
+/*
+ * runState is the progress of a scheduled run persisted after every iteration so an interrupted
+ * run can be resumed with `-resume`.
+ */
+type runState struct {
+	RunID           string               `json:"runId"`           // Run identifier
+	StartedAt       time.Time            `json:"startedAt"`       // When the first iteration started
+	IntervalMinutes int                  `json:"intervalMinutes"` // Minutes between iterations
+	DurationHours   int                  `json:"durationHours"`   // Hours the run lasts
+	TotalIterations int                  `json:"totalIterations"` // Most iterations that fit the window, for progress messages
+	Completed       []completedIteration `json:"completed"`       // Iterations completed so far
+}
+
+/*
+ * completedIteration records a single finished iteration of a scheduled run.
+ */
+type completedIteration struct {
+	Iteration   int       `json:"iteration"`   // 1 based iteration number
+	StartedAt   time.Time `json:"startedAt"`   // When the iteration started
+	CompletedAt time.Time `json:"completedAt"` // When the iteration finished
+}
+
+/*
+ * defaultRunID derives a stable run ID from the files and schedule of a run, so restarting the same
+ * command line with `-resume` finds the state of the interrupted run without passing `-run-id`.
+ */
+func defaultRunID(sqlConfigProp string, sqlQueries string, interval int, duration int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%d", sqlConfigProp, sqlQueries, interval, duration)))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+/*
+ * runStateFile returns the path of the state file for a run ID, kept in the current directory.
+ */
+func runStateFile(runID string) string {
+	return filepath.Clean(fmt.Sprintf("sql_diagnostics_run_%s.state.json", runID))
+}
+
+/*
+ * loadRunState reads the state file of a run, returning an error if it does not exist or cannot be parsed.
+ */
+func loadRunState(runID string) (runState, error) {
+	var state runState
+	content, err := os.ReadFile(runStateFile(runID))
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(content, &state)
+	return state, err
+}
+
+/*
+ * saveRunState writes the state file of a run, going through a temporary file so an interruption
+ * while saving never leaves a truncated state behind.
+ */
+func saveRunState(state runState) error {
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tempFile := runStateFile(state.RunID) + ".tmp"
+	if err := os.WriteFile(tempFile, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tempFile, runStateFile(state.RunID))
+}
+
+/*
+ * plannedIterations returns the most iterations starting every interval that fit a window, the first one
+ * starting with the window. An interval longer than the window still runs once.
+ */
+func plannedIterations(window time.Duration, interval time.Duration) int {
+	if interval <= 0 || window <= 0 {
+		return 1
+	}
+	return int((window + interval - 1) / interval)
+}
+
+/*
+ * alignedStart returns the first wall clock boundary of the interval at or after `now`, the boundaries being
+ * counted from the local midnight, so a 15 minute interval starts on :00, :15, :30 or :45 of the hour.
+ *
+ * Notes:
+ * - An interval not dividing a day, such as 7 minutes, restarts its boundaries at every midnight, an interval of
+ *   a day or more starts at the next midnight.
+ */
+func alignedStart(now time.Time, interval time.Duration) time.Time {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if interval <= 0 || !now.After(midnight) {
+		return midnight
+	}
+	start := midnight.Add((now.Sub(midnight) + interval - 1) / interval * interval)
+	if next := midnight.AddDate(0, 0, 1); start.After(next) {
+		return next
+	}
+	return start
+}
+
+/*
+ * nextTick returns the tick of the fixed cadence due after tick `last`, tick n being due n intervals after the
+ * run started, together with the number of ticks skipped because they were already past at `now`.
+ *
+ * Notes:
+ * - An iteration overrunning the interval is not followed right away, the ticks it missed are skipped rather than
+ *   queued and the next iteration waits for the first tick still ahead, so iterations never pile up on a busy server.
+ */
+func nextTick(anchor time.Time, interval time.Duration, last int, now time.Time) (int, int) {
+	next := last + 1
+	if interval > 0 && anchor.Add(time.Duration(next)*interval).Before(now) {
+		next = int((now.Sub(anchor) + interval - 1) / interval)
+	}
+	return next, next - last - 1
+}
+
+/*
+ * runScheduled runs the queries every `Interval` minutes for `Duration` hours, persisting the progress
+ * after every iteration so an interrupted run can be resumed.
+ *
+ * Parameters:
+ * - sqlConfigProp: A string representing the path to the SQL Server configuration file.
+ * - sqlQueries: A string representing the path to the JSON file containing the SQL queries.
+ * - schedule: A `ScheduleOptions` struct with the interval, duration and resume settings.
+ * - opts: A `RunOptions` struct passed to every iteration.
+ *
+ * Functionality:
+ * 1. Without `Resume`, starts a new run state whose capture window ends `Duration` hours after its start.
+ * 2. With `Resume`, loads the state of the interrupted run and continues after its last completed iteration.
+ *    - The interval and duration stored by the interrupted run are always used, so its cadence and capture window
+ *      do not change. An `Interval` or `Duration` given on the command line that differs is ignored with a warning.
+ *    - If the capture window (start + duration) has already passed, the state is removed and the run exits cleanly.
+ *    - If no state exists for the run ID, a new run is started.
+ * 3. Starts the iterations on a fixed cadence, iteration n is due (n - 1) intervals after the run started, so the
+ *    time an iteration takes counts toward the interval. A new iteration never starts while the previous one runs,
+ *    the ticks an overrunning iteration missed are skipped with a "skipped iteration due to overrun" message,
+ *    see `nextTick`. The run stops at the first tick at or after the window end, so it runs as many iterations as
+ *    fit whether or not the interval divides the duration. Saves the state after every completed iteration.
+ *    With `AlignToClock`, the run starts on the first clock boundary of the interval ahead, see `alignedStart`, so
+ *    every iteration starts on a boundary, such as :00, :15, :30 and :45 with a 15 minute interval, and the
+ *    capture window is counted from that boundary.
+ * 4. Removes the state file once the run completes.
+ * 5. With `ChangesQuery`, keeps the rows of that query from the previous iteration in memory and appends the
+ *    new, removed and changed rows of every iteration to the "changes" sheet of "sql_diagnostics_run_<id>_changes.xlsx".
+ *    The first iteration, and the first after resuming, only records the baseline. A changes workbook left by the
+ *    interrupted run that cannot be opened stops the run before its first iteration.
+ * 6. With `Jitter`, every interval is shifted by a random offset, so instances started on the same schedule do
+ *    not all hit the server at the same instant.
+ * 7. Opens the connection pool once and reuses it across the iterations, see `connectionPool`. The pool is
+ *    rebuilt when it fails its health check or the configuration file changes the connection settings, and
+ *    the number of pools opened against the iterations reusing one is printed at the end.
+ * 8. An iteration whose configuration or queries file cannot be read, whose server cannot be reached, whose setup
+ *    hook fails or whose output cannot be saved is logged and not recorded as completed, the run continues at the
+ *    next tick.
+ * 9. When `ctx` is cancelled, by Ctrl-C or SIGTERM, the current iteration saves the results written so far and
+ *    the run stops without recording it as completed. The state file is kept, so the run continues with `-resume`.
+ *
+ * Returns:
+ * - The exit code of the run, the highest `runExitCode` of the iterations of this process: 2 when any iteration
+ *   could not reach the server, else 1 when a query of any iteration failed, 0 when every iteration ran every
+ *   query. The iterations of the process interrupted before a `-resume` are not counted.
+ */
+func runScheduled(ctx context.Context, sqlConfigProp string, sqlQueries string, schedule ScheduleOptions, opts RunOptions) int {
+	var state runState
+
+	if schedule.Resume {
+		loaded, err := loadRunState(schedule.RunID)
+		if err != nil {
+			logWarn("No resumable state for run %s (%v), starting a new run.", schedule.RunID, err)
+		} else {
+			state = loaded
+			if (schedule.Interval > 0 && schedule.Interval != state.IntervalMinutes) || (schedule.Duration > 0 && schedule.Duration != state.DurationHours) {
+				logWarn("Run %s keeps its interval of %d minute(s) and duration of %d hour(s), the -interval and -duration given are ignored.", state.RunID, state.IntervalMinutes, state.DurationHours)
+			}
+		}
+	}
+
+	if state.RunID == "" {
+		if schedule.Interval <= 0 || schedule.Duration <= 0 {
+			logError("Both -interval and -duration are required to start scheduled run %s.", schedule.RunID)
+			return exitQueriesFailed
+		}
+		startedAt := time.Now()
+		if schedule.AlignToClock {
+			startedAt = alignedStart(startedAt, time.Duration(schedule.Interval)*time.Minute)
+		}
+		state = runState{
+			RunID:           schedule.RunID,
+			StartedAt:       startedAt,
+			IntervalMinutes: schedule.Interval,
+			DurationHours:   schedule.Duration,
+			TotalIterations: plannedIterations(time.Duration(schedule.Duration)*time.Hour, time.Duration(schedule.Interval)*time.Minute),
+		}
+	}
+
+	windowEnd := state.StartedAt.Add(time.Duration(state.DurationHours) * time.Hour)
+	if time.Now().After(windowEnd) {
+		logInfo("The capture window of run %s ended at %s, nothing left to resume. Exiting.", state.RunID, windowEnd.Format(time.RFC3339))
+		os.Remove(runStateFile(state.RunID))
+		return exitSuccess
+	}
+
+	if len(state.Completed) > 0 {
+		logInfo("Resuming run %s after %d completed iteration(s), the capture window ends at %s.", state.RunID, len(state.Completed), windowEnd.Format(time.RFC3339))
+	} else {
+		logInfo("Running the program every %d minute(s) until %s (up to %d iterations), run ID %s.", state.IntervalMinutes, windowEnd.Format(time.RFC3339), state.TotalIterations, state.RunID)
+		if wait := time.Until(state.StartedAt); wait > 0 {
+			logInfo("Waiting %s for the first clock boundary of the interval at %s.", wait.Round(time.Second), state.StartedAt.Format(time.RFC3339))
+		}
+	}
+
+	// Seeded per process so instances started together draw different offsets
+	random := rand.New(rand.NewSource(time.Now().UnixNano() + int64(os.Getpid())))
+
+	var changes *changeLog
+	if schedule.ChangesQuery != "" {
+		var err error
+		if changes, err = newChangeLog(state.RunID, schedule.ChangesQuery, schedule.ChangesKey); err != nil {
+			logError("Run %s not started: %v", state.RunID, err)
+			return exitQueriesFailed
+		}
+		opts.SnapshotQuery = schedule.ChangesQuery
+		logInfo("Recording the changes of query %s between iterations in %s.", schedule.ChangesQuery, changes.fileName)
+	}
+
+	// The connections are opened once and reused by every iteration, rebuilt only on failure or changed settings
+	pool := &connectionPool{}
+	defer pool.close()
+
+	// The highest exit code of the iterations run so far
+	exitCode := exitSuccess
+
+	// The first iteration runs right away, a resumed run continues at the first tick still ahead
+	interval := time.Duration(state.IntervalMinutes) * time.Minute
+	tick := 0
+	if n := len(state.Completed); n > 0 {
+		tick, _ = nextTick(state.StartedAt, interval, state.Completed[n-1].Iteration-1, time.Now())
+	}
+
+	for {
+		due := state.StartedAt.Add(time.Duration(tick) * interval)
+		if !due.Before(windowEnd) {
+			break
+		}
+
+		// Every tick but the first is shifted by the jitter
+		if tick > 0 {
+			if shifted := schedule.Jitter.apply(interval, random); shifted != interval {
+				due = due.Add(shifted - interval)
+				logInfo("Next iteration at %s (interval with jitter).", due.Format(time.RFC3339))
+			}
+		}
+		if wait := time.Until(due); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+			case <-timer.C:
+			}
+		}
+		if ctx.Err() != nil {
+			interruptedRun(state)
+			return exitCode
+		}
+
+		iteration := tick + 1
+		logInfo("Iteration %d/%d: Executing SQL queries...", iteration, state.TotalIterations)
+		started := time.Now()
+		findings, err := executeSQLQueries(ctx, sqlConfigProp, sqlQueries, opts, pool)
+		exitCode = max(exitCode, runExitCode(findings, err))
+		if ctx.Err() != nil {
+			// The queries after the interruption did not run, the iteration is left to the resumed run
+			interruptedRun(state)
+			return exitCode
+		}
+		if err != nil {
+			// A configuration that cannot be read or a server that cannot be reached only costs this iteration
+			logError("Iteration %d failed, retrying at the next interval: %v", iteration, err)
+		} else {
+			if changes != nil {
+				changes.record(iteration, started, findings.Snapshot)
+			}
+
+			state.Completed = append(state.Completed, completedIteration{Iteration: iteration, StartedAt: started, CompletedAt: time.Now()})
+			if err := saveRunState(state); err != nil {
+				logError("Failed to save the state of run %s: %v", state.RunID, err)
+			}
+		}
+
+		// The ticks that passed while this iteration ran are skipped, not queued
+		var skipped int
+		tick, skipped = nextTick(state.StartedAt, interval, tick, time.Now())
+		if skipped > 0 {
+			logWarn("Iteration %d took %s, longer than the %s interval: skipped iteration due to overrun (%d tick(s) skipped).", iteration, time.Since(started).Round(time.Second), interval, skipped)
+		}
+	}
+
+	logInfo("The capture window of run %s ended at %s after %d iteration(s).", state.RunID, windowEnd.Format(time.RFC3339), len(state.Completed))
+	logInfo("Run %s: %s.", state.RunID, pool)
+	os.Remove(runStateFile(state.RunID))
+	logInfo("Program has completed all iterations. Exiting.")
+	return exitCode
+}
+
+/*
+ * interruptedRun reports a scheduled run stopped by Ctrl-C or SIGTERM, its state file is kept for `-resume`.
+ */
+func interruptedRun(state runState) {
+	logWarn("Run %s was interrupted after %d completed iteration(s), continue it with -resume -run-id %s.", state.RunID, len(state.Completed), state.RunID)
+}
@@ -0,0 +1,221 @@
+package diagnostics
+
+import (
+	"math/rand" // For the seeded jitter draws
+	"testing"   // For the test framework
+	"time"      // For the scheduler durations and times
+)
+
+/*
+ * TestPlannedIterations checks the iterations fitting a capture window, including the intervals that do not
+ * divide the duration and the interval longer than the window.
+ */
+func TestPlannedIterations(t *testing.T) {
+	tests := []struct {
+		name     string
+		window   time.Duration
+		interval time.Duration
+		want     int
+	}{
+		{name: "interval divides the window", window: time.Hour, interval: 15 * time.Minute, want: 4},
+		{name: "interval does not divide the window", window: time.Hour, interval: 25 * time.Minute, want: 3},
+		{name: "last tick just inside the window", window: 61 * time.Minute, interval: 15 * time.Minute, want: 5},
+		{name: "interval longer than the window", window: 30 * time.Minute, interval: time.Hour, want: 1},
+		{name: "no window", window: 0, interval: 15 * time.Minute, want: 1},
+		{name: "no interval", window: time.Hour, interval: 0, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := plannedIterations(tt.window, tt.interval); got != tt.want {
+				t.Errorf("plannedIterations(%s, %s) = %d, want %d", tt.window, tt.interval, got, tt.want)
+			}
+		})
+	}
+}
+
+/*
+ * TestNextTickDoesNotDrift checks that an iteration finishing within its interval is followed by the next tick
+ * of the fixed cadence, whatever it took, so the time an iteration runs never shifts the later ones.
+ */
+func TestNextTickDoesNotDrift(t *testing.T) {
+	anchor := time.Date(2025, 11, 27, 10, 0, 0, 0, time.UTC)
+	interval := 10 * time.Minute
+	tests := []struct {
+		name string
+		last int
+		now  time.Time
+	}{
+		{name: "quick iteration", last: 0, now: anchor.Add(3 * time.Minute)},
+		{name: "iteration ending just before the next tick", last: 1, now: anchor.Add(19*time.Minute + 59*time.Second)},
+		{name: "iteration ending on the next tick", last: 0, now: anchor.Add(interval)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, skipped := nextTick(anchor, interval, tt.last, tt.now)
+			if next != tt.last+1 || skipped != 0 {
+				t.Errorf("nextTick(last %d) = %d, %d skipped, want %d, 0 skipped", tt.last, next, skipped, tt.last+1)
+			}
+		})
+	}
+}
+
+/*
+ * TestAlignedStart checks the first clock boundary of the interval, counted from the local midnight.
+ */
+func TestAlignedStart(t *testing.T) {
+	day := func(hour, minute, second int) time.Time {
+		return time.Date(2025, 11, 27, hour, minute, second, 0, time.UTC)
+	}
+	nextMidnight := time.Date(2025, 11, 28, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		now      time.Time
+		interval time.Duration
+		want     time.Time
+	}{
+		{name: "between boundaries", now: day(10, 7, 30), interval: 15 * time.Minute, want: day(10, 15, 0)},
+		{name: "on a boundary", now: day(10, 15, 0), interval: 15 * time.Minute, want: day(10, 15, 0)},
+		{name: "at midnight", now: day(0, 0, 0), interval: 15 * time.Minute, want: day(0, 0, 0)},
+		{name: "interval not dividing a day", now: day(10, 1, 0), interval: 7 * time.Minute, want: day(10, 2, 0)},
+		{name: "boundary past midnight", now: day(23, 58, 0), interval: 7 * time.Minute, want: nextMidnight},
+		{name: "interval of a day", now: day(10, 7, 0), interval: 24 * time.Hour, want: nextMidnight},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := alignedStart(tt.now, tt.interval); !got.Equal(tt.want) {
+				t.Errorf("alignedStart(%s, %s) = %s, want %s", tt.now.Format(time.TimeOnly), tt.interval, got, tt.want)
+			}
+		})
+	}
+}
+
+/*
+ * TestParseJitter checks the durations and percentages accepted by -jitter and the values rejected.
+ */
+func TestParseJitter(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    jitter
+		wantErr bool
+	}{
+		{value: "", want: jitter{}},
+		{value: " 30s ", want: jitter{Duration: 30 * time.Second}},
+		{value: "2m", want: jitter{Duration: 2 * time.Minute}},
+		{value: "10%", want: jitter{Percent: 10}},
+		{value: "0%", want: jitter{}},
+		{value: "100%", want: jitter{Percent: 100}},
+		{value: "150%", wantErr: true},
+		{value: "-1%", wantErr: true},
+		{value: "-5s", wantErr: true},
+		{value: "fast", wantErr: true},
+		{value: "10", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseJitter(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseJitter(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseJitter(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+/*
+ * TestJitterApplyBounds draws many jittered intervals and checks each stays within the jitter either way of the
+ * interval and never goes below zero.
+ */
+func TestJitterApplyBounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		jitter   jitter
+		interval time.Duration
+		min, max time.Duration
+	}{
+		{name: "no jitter", jitter: jitter{}, interval: time.Minute, min: time.Minute, max: time.Minute},
+		{name: "fixed duration", jitter: jitter{Duration: 30 * time.Second}, interval: time.Minute, min: 30 * time.Second, max: 90 * time.Second},
+		{name: "percentage", jitter: jitter{Percent: 10}, interval: 10 * time.Minute, min: 9 * time.Minute, max: 11 * time.Minute},
+		{name: "jitter larger than the interval", jitter: jitter{Duration: 2 * time.Minute}, interval: time.Minute, min: 0, max: 3 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			random := rand.New(rand.NewSource(1))
+			for i := 0; i < 1000; i++ {
+				if got := tt.jitter.apply(tt.interval, random); got < tt.min || got > tt.max {
+					t.Fatalf("apply(%s) = %s, want between %s and %s", tt.interval, got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+/*
+ * TestNextTickSkipsOverrunTicks checks that the ticks an overrunning iteration missed are skipped, not queued,
+ * and the next iteration waits for the first tick still ahead.
+ */
+func TestNextTickSkipsOverrunTicks(t *testing.T) {
+	anchor := time.Date(2025, 11, 27, 10, 0, 0, 0, time.UTC)
+	interval := 10 * time.Minute
+	tests := []struct {
+		name        string
+		last        int
+		now         time.Time
+		wantNext    int
+		wantSkipped int
+	}{
+		{name: "overrun by part of an interval", last: 0, now: anchor.Add(15 * time.Minute), wantNext: 2, wantSkipped: 1},
+		{name: "overrun ending on a tick", last: 0, now: anchor.Add(20 * time.Minute), wantNext: 2, wantSkipped: 1},
+		{name: "overrun by several intervals", last: 0, now: anchor.Add(25 * time.Minute), wantNext: 3, wantSkipped: 2},
+		{name: "overrun of a later iteration", last: 2, now: anchor.Add(45 * time.Minute), wantNext: 5, wantSkipped: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, skipped := nextTick(anchor, interval, tt.last, tt.now)
+			if next != tt.wantNext || skipped != tt.wantSkipped {
+				t.Errorf("nextTick(last %d, %s after the start) = %d, %d skipped, want %d, %d skipped", tt.last, tt.now.Sub(anchor), next, skipped, tt.wantNext, tt.wantSkipped)
+			}
+			if due := anchor.Add(time.Duration(next) * interval); due.Before(tt.now) {
+				t.Errorf("tick %d was due at %s, before the iteration ended", next, due)
+			}
+		})
+	}
+}
+
+/*
+ * TestScheduleSimulatedOverrun replays a run whose iterations take the given times, as runScheduled does, and
+ * checks that no iteration starts while the previous one runs and every one starts on the cadence.
+ */
+func TestScheduleSimulatedOverrun(t *testing.T) {
+	anchor := time.Date(2025, 11, 27, 10, 0, 0, 0, time.UTC)
+	interval := 10 * time.Minute
+	took := []time.Duration{3 * time.Minute, 12 * time.Minute, 2 * time.Minute, 31 * time.Minute, time.Minute}
+
+	var ticks []int
+	var skippedTotal int
+	tick := 0
+	previousEnd := anchor
+	for _, duration := range took {
+		due := anchor.Add(time.Duration(tick) * interval)
+		if due.Before(previousEnd) {
+			t.Fatalf("tick %d starts at %s, before the previous iteration ended at %s", tick, due, previousEnd)
+		}
+		ticks = append(ticks, tick)
+		previousEnd = due.Add(duration)
+
+		var skipped int
+		tick, skipped = nextTick(anchor, interval, tick, previousEnd)
+		skippedTotal += skipped
+	}
+
+	want := []int{0, 1, 3, 4, 8}
+	for i := range want {
+		if ticks[i] != want[i] {
+			t.Fatalf("ran ticks %v, want %v", ticks, want)
+		}
+	}
+	if skippedTotal != 4 {
+		t.Errorf("skipped %d ticks, want 4 (tick 2 after the 12 minute iteration, ticks 5 to 7 after the 31 minute one)", skippedTotal)
+	}
+}
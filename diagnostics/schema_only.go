@@ -0,0 +1,142 @@
+package diagnostics
+
+import (
+	"database/sql" // Database/sql package for database operations
+	"fmt"          // For formatted I/O operations
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+// Name of the sheet listing the result columns of every query for `-schema-only`
+const schemasSheetName = "schemas"
+
+// Describes the first result set of a batch without running it, one row per column or one row carrying the error
+const describeFirstResultSetSQL = `SELECT column_ordinal, name, system_type_name, is_nullable, error_message
+FROM sys.dm_exec_describe_first_result_set(@p1, @p2, 0)
+WHERE is_hidden = 0 OR is_hidden IS NULL
+ORDER BY column_ordinal`
+
+/*
+ * schemaColumn is one column of the first result set of a query, as described by SQL Server.
+ *
+ * Fields:
+ * - Ordinal: The 1 based position of the column in the result.
+ * - Name: The column name, empty for an unnamed expression.
+ * - Type: The system type name, for example "nvarchar(128)".
+ * - Nullable: Whether the column may be NULL.
+ */
+type schemaColumn struct {
+	Ordinal  int
+	Name     string
+	Type     string
+	Nullable bool
+}
+
+/*
+ * describeQuery gets the columns of the first result set of a query with sys.dm_exec_describe_first_result_set,
+ * the function form of sp_describe_first_result_set, so the query itself is never run and no row is fetched.
+ *
+ * Parameters:
+ * - db: The database connection.
+ * - query: The query to describe.
+ * - params: The parameters of the run, the parameters the query references are declared, see `paramDeclarations`.
+ *
+ * Returns:
+ * - columns: The columns in result order, empty when the query could not be described.
+ * - note: Why the query has no columns, SQL Server's message for a batch it cannot describe, such as one using
+ *   dynamic SQL or temporary tables, or a note when the batch returns no result set. Empty otherwise.
+ * - error: An error if the description itself failed, for example without permission on the function.
+ */
+func describeQuery(db *sql.DB, query Query, params paramValues) ([]schemaColumn, string, error) {
+	declarations := paramDeclarations(query, params)
+	rows, err := db.Query(describeFirstResultSetSQL, query.Query, sql.NullString{String: declarations, Valid: declarations != ""})
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var columns []schemaColumn
+	note := ""
+	for rows.Next() {
+		var ordinal sql.NullInt64
+		var name, typeName, message sql.NullString
+		var nullable sql.NullBool
+		if err := rows.Scan(&ordinal, &name, &typeName, &nullable, &message); err != nil {
+			return nil, "", err
+		}
+		if message.Valid {
+			note = message.String
+			continue
+		}
+		columns = append(columns, schemaColumn{Ordinal: int(ordinal.Int64), Name: name.String, Type: typeName.String, Nullable: nullable.Bool})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	if len(columns) == 0 && note == "" {
+		note = "the query returns no result set"
+	}
+	return columns, note, nil
+}
+
+/*
+ * writeSchemasSheet writes the "schemas" sheet for `-schema-only`, documenting the output contract of the
+ * queries file: the columns and types each query returns, without running any of them.
+ *
+ * Parameters:
+ * - f: The Excel file.
+ * - db: The database connection the queries are described on.
+ * - queries: The queries of the file.
+ * - opts: The run options, the queries selected by `-only` and `-tag` are described with the `-params` and
+ *   `-param` parameters.
+ *
+ * Functionality:
+ * 1. Describes every selected query with `describeQuery`.
+ * 2. Writes one row per result column with the Sr.No and name of its query, the ordinal, name, type and nullability.
+ * 3. Writes a single row with a note for a query that cannot be described, the run continues with the next query.
+ *
+ * Notes:
+ * - Only the first result set is described, as SQL Server does for sp_describe_first_result_set.
+ */
+func writeSchemasSheet(f *excelize.File, db *sql.DB, queries Queries, opts RunOptions) {
+	f.NewSheet(schemasSheetName)
+
+	headers := []string{"Sr.No", "Name", "Column Ordinal", "Column", "Type", "Nullable", "Note"}
+	for colIndex, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(colIndex+1, 1)
+		f.SetCellValue(schemasSheetName, cell, header)
+	}
+
+	rowNum := 2 // Start from row 2 (after header)
+	described := 0
+	for i, query := range queries.Queries {
+		if !selectedQuery(opts, query) {
+			continue
+		}
+		columns, note, err := describeQuery(db, query, opts.Params)
+		if err != nil {
+			logError("Failed to describe query %s: %v", query.Name, err)
+			note = fmt.Sprintf("could not be described: %v", err)
+		}
+		if len(columns) == 0 {
+			f.SetCellValue(schemasSheetName, fmt.Sprintf("A%d", rowNum), i+1)
+			f.SetCellValue(schemasSheetName, fmt.Sprintf("B%d", rowNum), query.Name)
+			f.SetCellValue(schemasSheetName, fmt.Sprintf("G%d", rowNum), note)
+			rowNum++
+			continue
+		}
+
+		described++
+		for _, column := range columns {
+			f.SetCellValue(schemasSheetName, fmt.Sprintf("A%d", rowNum), i+1)
+			f.SetCellValue(schemasSheetName, fmt.Sprintf("B%d", rowNum), query.Name)
+			f.SetCellValue(schemasSheetName, fmt.Sprintf("C%d", rowNum), column.Ordinal)
+			f.SetCellValue(schemasSheetName, fmt.Sprintf("D%d", rowNum), column.Name)
+			f.SetCellValue(schemasSheetName, fmt.Sprintf("E%d", rowNum), column.Type)
+			f.SetCellValue(schemasSheetName, fmt.Sprintf("F%d", rowNum), column.Nullable)
+			rowNum++
+		}
+	}
+
+	logInfo("Described the result columns of %d of %d queries.", described, selectedCount(opts, queries))
+}
@@ -0,0 +1,88 @@
+package diagnostics
+
+import (
+	"context" // For routing the driver messages to the running query
+	"strings" // For string manipulation
+	"sync"    // For guarding the messages added by the driver
+
+	mssql "github.com/microsoft/go-mssqldb" // For receiving the server messages
+	"github.com/microsoft/go-mssqldb/msdsn" // For enabling the message log flag
+)
+
+/*
+ * queryMessages collects the informational messages SQL Server sent while a query ran: PRINT output and
+ * RAISERROR messages of severity 10 or less, which do not fail the query.
+ *
+ * Fields:
+ * - mu: Guards the messages, added by the driver while the rows are read.
+ * - messages: The messages, in the order they were received.
+ */
+type queryMessages struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+// Context key of the queryMessages receiving the messages of a query
+type queryMessagesKey struct{}
+
+/*
+ * serverMessageLogger receives the informational messages of the driver and hands them to the query context
+ * they belong to: the STATISTICS TIME figures to its queryTiming, every other message to its queryMessages.
+ */
+type serverMessageLogger struct{}
+
+/*
+ * Log implements mssql.ContextLogger, ignoring the messages of other categories and of contexts that collect nothing,
+ * such as the messages sent while logging in.
+ */
+func (serverMessageLogger) Log(ctx context.Context, category msdsn.Log, msg string) {
+	if category != msdsn.LogMessages {
+		return
+	}
+	if timing, ok := ctx.Value(queryTimingKey{}).(*queryTiming); ok && timing.addMessage(msg) {
+		return
+	}
+	if messages, ok := ctx.Value(queryMessagesKey{}).(*queryMessages); ok {
+		messages.mu.Lock()
+		messages.messages = append(messages.messages, strings.TrimSpace(msg))
+		messages.mu.Unlock()
+	}
+}
+
+// Installs the serverMessageLogger in the driver once
+var installServerMessageLogger sync.Once
+
+/*
+ * enableServerMessages enables the driver's message log on the connection configuration, so the messages of
+ * the queries and of SET STATISTICS TIME reach the serverMessageLogger.
+ *
+ * Parameters:
+ * - config: The parsed connection configuration, updated in place.
+ */
+func enableServerMessages(config *msdsn.Config) {
+	config.LogFlags |= msdsn.LogMessages
+
+	installServerMessageLogger.Do(func() {
+		mssql.SetContextLogger(serverMessageLogger{})
+	})
+}
+
+/*
+ * withQueryMessages returns a context collecting the server messages of the query run in it, derived from `parent`.
+ */
+func withQueryMessages(parent context.Context) (context.Context, *queryMessages) {
+	messages := &queryMessages{}
+	return context.WithValue(parent, queryMessagesKey{}, messages), messages
+}
+
+/*
+ * list returns the messages collected so far, none for a nil queryMessages.
+ */
+func (m *queryMessages) list() []string {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.messages...)
+}
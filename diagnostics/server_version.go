@@ -0,0 +1,112 @@
+package diagnostics
+
+import (
+	"database/sql" // Database/sql package for database operations
+	"fmt"          // For the error of a version mismatch with -strict-version
+	"strconv"      // For parsing the product major version
+	"strings"      // For string manipulation
+)
+
+// Query reading the major version and the engine edition of the server, 5 is Azure SQL Database and 8 Azure SQL Managed Instance
+const serverVersionQuery = "SELECT CONVERT(int, SERVERPROPERTY('ProductMajorVersion')), CONVERT(int, SERVERPROPERTY('EngineEdition')), CONVERT(varchar(128), SERVERPROPERTY('ProductVersion'))"
+
+// Release names of the SQL Server product major versions, as written in the querysource sqlserverversion
+var sqlServerReleases = map[int]string{
+	11: "2012", 12: "2014", 13: "2016", 14: "2017", 15: "2019", 16: "2022", 17: "2025",
+}
+
+// Name of the Azure SQL engines in the querysource sqlserverversion, written "Azure SQL" or "AzureSQL"
+const azureSQLRelease = "azuresql"
+
+/*
+ * declaredReleases parses the querysource `sqlserverversion` of a queries file into the releases it targets,
+ * such as ["2022", "2025", "azuresql"] for "2022-2025-AzureSQL". A major version number such as "16" is read as
+ * its release name. The releases are separated by "-", "," or "/", spaces are ignored.
+ *
+ * Returns:
+ * - The releases, nil when the version is empty.
+ * - The parts that name no known release.
+ */
+func declaredReleases(version string) ([]string, []string) {
+	var releases, unknown []string
+	parts := strings.FieldsFunc(strings.ToLower(strings.ReplaceAll(version, " ", "")), func(r rune) bool {
+		return r == '-' || r == ',' || r == '/'
+	})
+	for _, part := range parts {
+		if major, err := strconv.Atoi(part); err == nil {
+			if release, ok := sqlServerReleases[major]; ok {
+				part = release
+			}
+		}
+		known := part == azureSQLRelease
+		for _, release := range sqlServerReleases {
+			known = known || part == release
+		}
+		if known {
+			releases = append(releases, part)
+		} else {
+			unknown = append(unknown, part)
+		}
+	}
+	return releases, unknown
+}
+
+/*
+ * checkServerVersion compares the version of the server with the `sqlserverversion` the queries file declares,
+ * before any query runs.
+ *
+ * Parameters:
+ * - db: The database connection.
+ * - source: The querysource of the queries file.
+ * - opts: The run options, `StrictVersion` aborts the run on a mismatch.
+ *
+ * Returns:
+ * - An error with `StrictVersion` when the server is not a declared release, nil otherwise.
+ *
+ * Functionality:
+ * 1. Reads SERVERPROPERTY('ProductMajorVersion') and SERVERPROPERTY('EngineEdition') once, the Azure SQL Database
+ *    and Managed Instance engines count as "Azure SQL" whatever their major version.
+ * 2. Warns prominently when the server is none of the releases declared, such as "2022" or "2022-2025-AzureSQL".
+ * 3. With `StrictVersion`, returns an error on a mismatch instead of warning, the caller runs no query then. A
+ *    `-config-dir` run goes on with the next server and a scheduled run retries at its next iteration.
+ *
+ * Notes:
+ * - A queries file without `sqlserverversion`, or one naming no known release, is not checked, the
+ *   CheckVersion query of the bundled files still reports the version in the workbook.
+ * - A failing version query only warns, the queries decide for themselves.
+ */
+func checkServerVersion(db *sql.DB, source QuerySource, opts RunOptions) error {
+	releases, unknown := declaredReleases(source.SQLServerVersion)
+	if len(unknown) > 0 {
+		logWarn("The sqlserverversion %q of the queries file names no known release in %s, the server version is not checked against it.", source.SQLServerVersion, strings.Join(unknown, ", "))
+	}
+	if len(releases) == 0 {
+		return nil
+	}
+
+	var major, edition sql.NullInt64
+	var productVersion sql.NullString
+	if err := db.QueryRow(serverVersionQuery).Scan(&major, &edition, &productVersion); err != nil {
+		logWarn("Failed to read the server version, it is not checked against the queries file: %v", err)
+		return nil
+	}
+	server := sqlServerReleases[int(major.Int64)]
+	if edition.Int64 == 5 || edition.Int64 == 8 {
+		server = azureSQLRelease
+	}
+	for _, release := range releases {
+		if release == server {
+			logInfo("The server version %s matches the sqlserverversion %q of the queries file.", productVersion.String, source.SQLServerVersion)
+			return nil
+		}
+	}
+
+	if opts.StrictVersion {
+		return fmt.Errorf("the queries file is written for SQL Server %q but the server runs version %s (-strict-version)", source.SQLServerVersion, productVersion.String)
+	}
+	logWarn("********************************************************************************")
+	logWarn("The queries file is written for SQL Server %q but the server runs version %s.", source.SQLServerVersion, productVersion.String)
+	logWarn("Many queries may fail or return misleading results, use the queries file matching this server.")
+	logWarn("********************************************************************************")
+	return nil
+}
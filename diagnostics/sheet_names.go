@@ -0,0 +1,119 @@
+package diagnostics
+
+import (
+	"fmt"     // For formatted I/O operations
+	"regexp"  // For sanitizing the query names as createSheetName does
+	"strings" // For string manipulation
+)
+
+// Longest sheet name Excel accepts
+const maxSheetNameLength = 31
+
+// Sheets written by the report itself, a query sheet must not take their names
+var reservedSheetNames = []string{
+	executedQueriesSheetName, aboutSheetName, changesSheetName, recommendationsSheetName, overviewSheetName,
+	permissionsSheetName, planAnalysisSheetName, dataIssuesSheetName, schemasSheetName,
+	runSummarySheetName, tocSheetName,
+}
+
+// Characters createSheetName drops from a query name, everything but letters, digits and underscores
+var sheetNameDroppedCharacters = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+/*
+ * sheetNameIssue is a problem found in the sheet name of a query before running it.
+ *
+ * Fields:
+ * - Index: The 1 based Sr.No of the query.
+ * - Query: The name of the query.
+ * - Sheet: The sheet name computed by createSheetName.
+ * - Issue: A human readable description of the problem.
+ */
+type sheetNameIssue struct {
+	Index int
+	Query string
+	Sheet string
+	Issue string
+}
+
+/*
+ * validateSheetNames computes the sheet name of every query with createSheetName and reports the names that
+ * would not come out as the query name suggests.
+ *
+ * Parameters:
+ * - queries: The queries of the run.
+ *
+ * Returns:
+ * - The issues found, in query order, empty when every sheet name is fine.
+ *
+ * Functionality:
+ * 1. Reports names truncated to the 31 characters Excel allows, and names left empty once sanitized.
+ * 2. Reports sheet names colliding, case insensitively as in Excel, with another query's sheet, the sheets of
+ *    its further result sets, or a sheet written by the report itself.
+ * 3. Reports query names used by more than one query, which makes selecting a query by name ambiguous.
+ */
+func validateSheetNames(queries Queries) []sheetNameIssue {
+	var issues []sheetNameIssue
+
+	owners := make(map[string]int, len(queries.Queries))
+	for _, name := range reservedSheetNames {
+		owners[strings.ToLower(name)] = 0
+	}
+	names := make(map[string]int, len(queries.Queries))
+
+	for i, query := range queries.Queries {
+		index := i + 1
+		sheetName := createSheetName(index, query.Name)
+		report := func(format string, args ...interface{}) {
+			issues = append(issues, sheetNameIssue{Index: index, Query: query.Name, Sheet: sheetName, Issue: fmt.Sprintf(format, args...)})
+		}
+
+		sanitized := sheetNameDroppedCharacters.ReplaceAllString(strings.ReplaceAll(query.Name, " ", "_"), "")
+		if sanitized == "" {
+			report("the name has no letter, digit or underscore, the sheet is named by its Sr.No only")
+		} else if full := fmt.Sprintf("%d_%s", index, sanitized); len(full) > maxSheetNameLength {
+			report("the sheet name %s is truncated to %d characters", full, maxSheetNameLength)
+		}
+
+		if previous, ok := names[strings.ToLower(query.Name)]; ok {
+			report("the name is also used by query %d, selecting it by name is ambiguous", previous)
+		} else {
+			names[strings.ToLower(query.Name)] = index
+		}
+
+		// The further result sets are only known when the query runs, the first suffixes are checked
+		sheets := []string{sheetName}
+		for resultSet := 2; resultSet <= 9; resultSet++ {
+			sheets = append(sheets, resultSetSheetName(sheetName, resultSet))
+		}
+		for _, sheet := range sheets {
+			key := strings.ToLower(sheet)
+			owner, taken := owners[key]
+			switch {
+			case taken && owner == 0:
+				report("the sheet %s collides with the report's own %s sheet", sheet, sheet)
+			case taken && owner != index:
+				report("the sheet %s collides with a sheet of query %d", sheet, owner)
+			case !taken:
+				owners[key] = index
+			}
+		}
+	}
+	return issues
+}
+
+/*
+ * checkSheetNames runs validateSheetNames for `-validate-sheet-names` and prints every issue.
+ *
+ * Returns:
+ * - The number of issues found.
+ */
+func checkSheetNames(queries Queries) int {
+	issues := validateSheetNames(queries)
+	for _, issue := range issues {
+		fmt.Printf("Query %d %q (sheet %s): %s\n", issue.Index, issue.Query, issue.Sheet, issue.Issue)
+	}
+	if len(issues) == 0 {
+		fmt.Printf("The sheet names of the %d queries are valid.\n", len(queries.Queries))
+	}
+	return len(issues)
+}
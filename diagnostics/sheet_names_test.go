@@ -0,0 +1,116 @@
+package diagnostics
+
+import (
+	"fmt"     // For formatting the issues
+	"strings" // For checking the issue descriptions
+	"testing" // For the test framework
+)
+
+/*
+ * TestCreateSheetName checks the sanitizing of query names into sheet names and the truncation to the 31
+ * characters Excel allows, which keeps the Sr.No prefix.
+ */
+func TestCreateSheetName(t *testing.T) {
+	tests := []struct {
+		index int
+		name  string
+		want  string
+	}{
+		{index: 1, name: "Sample Query Name!", want: "1_Sample_Query_Name"},
+		{index: 2, name: "CPU [Top 10]: by/query?", want: "2_CPU_Top_10_byquery"},
+		{index: 3, name: "Tempdb-Usage (MB)", want: "3_TempdbUsage_MB"},
+		{index: 4, name: "***", want: "4_"},
+		{index: 5, name: "Index Usage Statistics For All Databases", want: "5_Index_Usage_Statistics_For_Al"},
+		{index: 123, name: "Index Usage Statistics For All Databases", want: "123_Index_Usage_Statistics_For_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := createSheetName(tt.index, tt.name)
+			if got != tt.want {
+				t.Errorf("createSheetName(%d, %q) = %q, want %q", tt.index, tt.name, got, tt.want)
+			}
+			if len(got) > maxSheetNameLength {
+				t.Errorf("createSheetName(%d, %q) = %q is longer than %d characters", tt.index, tt.name, got, maxSheetNameLength)
+			}
+		})
+	}
+}
+
+/*
+ * TestValidateSheetNames checks the issues reported for the truncated, empty and duplicate names, that valid names
+ * report none, and that checkSheetNames counts them for the exit status of -validate-sheet-names.
+ */
+func TestValidateSheetNames(t *testing.T) {
+	tests := []struct {
+		name       string
+		queries    []string
+		wantIssues []string
+	}{
+		{name: "valid names", queries: []string{"Wait Stats", "CPU Utilization", "Database Sizes"}},
+		{name: "truncated name", queries: []string{"Wait Stats", "Index Usage Statistics For All Databases"}, wantIssues: []string{"query 2: the sheet name 2_Index_Usage_Statistics_For_All_Databases is truncated to 31 characters"}},
+		{name: "name without a letter", queries: []string{"!!!"}, wantIssues: []string{"query 1: the name has no letter"}},
+		{name: "duplicate name", queries: []string{"Wait Stats", "Blocking", "wait stats"}, wantIssues: []string{"query 3: the name is also used by query 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queries Queries
+			for _, name := range tt.queries {
+				queries.Queries = append(queries.Queries, Query{Name: name})
+			}
+			issues := validateSheetNames(queries)
+			if len(issues) != len(tt.wantIssues) {
+				t.Fatalf("validateSheetNames(%q) = %+v, want %d issues", tt.queries, issues, len(tt.wantIssues))
+			}
+			for i, issue := range issues {
+				if got := fmt.Sprintf("query %d: %s", issue.Index, issue.Issue); !strings.HasPrefix(got, tt.wantIssues[i]) {
+					t.Errorf("issue %d = %q, want %q", i, got, tt.wantIssues[i])
+				}
+				if issue.Sheet != createSheetName(issue.Index, issue.Query) {
+					t.Errorf("issue %d names the sheet %q, want the one of createSheetName", i, issue.Sheet)
+				}
+			}
+			if count := checkSheetNames(queries); count != len(tt.wantIssues) {
+				t.Errorf("checkSheetNames = %d, want %d", count, len(tt.wantIssues))
+			}
+		})
+	}
+}
+
+/*
+ * TestCreateSheetNameCollidingNames checks that names truncated to the same text still get distinct sheet names,
+ * made unique by their Sr.No prefix, and that validateSheetNames reports the truncation but no collision.
+ */
+func TestCreateSheetNameCollidingNames(t *testing.T) {
+	names := []string{
+		"Wait Statistics By Database Alpha",
+		"Wait Statistics By Database Beta",
+		"Wait Statistics By Database Alpha Replica",
+		"Wait_Statistics_By_Database_Alpha",
+	}
+	want := []string{
+		"1_Wait_Statistics_By_Database_A",
+		"2_Wait_Statistics_By_Database_B",
+		"3_Wait_Statistics_By_Database_A",
+		"4_Wait_Statistics_By_Database_A",
+	}
+
+	var queries Queries
+	seen := map[string]string{}
+	for i, name := range names {
+		sheet := createSheetName(i+1, name)
+		if sheet != want[i] {
+			t.Errorf("createSheetName(%d, %q) = %q, want %q", i+1, name, sheet, want[i])
+		}
+		if previous, ok := seen[strings.ToLower(sheet)]; ok {
+			t.Errorf("%q and %q both get the sheet %q", previous, name, sheet)
+		}
+		seen[strings.ToLower(sheet)] = name
+		queries.Queries = append(queries.Queries, Query{Name: name})
+	}
+
+	for _, issue := range validateSheetNames(queries) {
+		if strings.Contains(issue.Issue, "collides") {
+			t.Errorf("query %d: unexpected collision %q", issue.Index, issue.Issue)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package diagnostics
+
+import (
+	"bufio"        // For buffering the script writes
+	"database/sql" // Database/sql package for column type information
+	"encoding/hex" // For writing binary values as 0x literals
+	"fmt"          // For formatted I/O operations
+	"os"           // For creating the script file
+	"strconv"      // For formatting numbers
+	"strings"      // For string manipulation
+	"time"         // For working with date and time
+)
+
+// Rows per INSERT statement, SQL Server accepts at most 1000 rows in a VALUES list
+const sqlInsertBatchRows = 1000
+
+// Kinds of SQL literals a column's values are written as
+const (
+	sqlLiteralText      = iota // N'...' Unicode string, the fallback for every other type
+	sqlLiteralNumber           // Number written as is, the integer, float, decimal and money types
+	sqlLiteralBit              // 1 or 0
+	sqlLiteralBinary           // 0x... hexadecimal
+	sqlLiteralDate             // 'yyyy-mm-dd'
+	sqlLiteralTime             // 'hh:mm:ss.fffffff'
+	sqlLiteralDateTime         // 'yyyy-mm-ddThh:mm:ss.fff', DATETIME and SMALLDATETIME only take milliseconds
+	sqlLiteralDateTime2        // 'yyyy-mm-ddThh:mm:ss.fffffff'
+	sqlLiteralOffset           // 'yyyy-mm-ddThh:mm:ss.fffffff+hh:mm'
+)
+
+/*
+ * sqlColumn is a result column with the column it is created as in the script.
+ */
+type sqlColumn struct {
+	name       string // Bracket quoted column name
+	definition string // SQL Server type of the created column
+	literal    int    // One of the sqlLiteral* kinds
+}
+
+/*
+ * sqlWriter is the ResultWriter for `-format=sql`. Every result becomes a CREATE TABLE named after its sheet
+ * followed by batched multi-row INSERT statements, all in a single "<baseName>.sql" script, so a point in time
+ * snapshot can be loaded into another database for analysis.
+ *
+ * Fields:
+ * - fileName: The name of the script.
+ * - file: The script file.
+ * - out: The buffered writer of the script.
+ * - table: The bracket quoted table of the current result.
+ * - columns: The columns of the current result.
+ * - pending: The VALUES rows not written yet, flushed as one INSERT every `sqlInsertBatchRows` rows.
+ */
+type sqlWriter struct {
+	fileName string
+	file     *os.File
+	out      *bufio.Writer
+	table    string
+	columns  []sqlColumn
+	pending  []string
+}
+
+/*
+ * newSQLWriter creates the SQL script writer.
+ *
+ * Parameters:
+ * - opts: Unused, the SQL format has no options.
+ * - baseName: The timestamped name of the script, without its .sql extension.
+ *
+ * Returns:
+ * - ResultWriter: The writer, ready to receive results.
+ * - error: An error if the script file cannot be created.
+ */
+func newSQLWriter(opts RunOptions, baseName string) (ResultWriter, error) {
+	fileName := baseName + ".sql"
+	file, err := os.Create(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %v", fileName, err)
+	}
+	return &sqlWriter{fileName: fileName, file: file, out: bufio.NewWriter(file)}, nil
+}
+
+/*
+ * quoteSQLName bracket quotes an identifier, doubling any closing bracket it contains.
+ */
+func quoteSQLName(name string) string {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+}
+
+/*
+ * uniqueColumnNames returns the column names of a result as table column names, empty and repeated names made
+ * unique as "column_<n>" and "<name>_<n>", compared case insensitively as SQL Server and SQLite compare them.
+ */
+func uniqueColumnNames(columns []string) []string {
+	names := make([]string, len(columns))
+	used := make(map[string]bool, len(columns))
+	for i, column := range columns {
+		columnName := strings.TrimSpace(column)
+		if columnName == "" {
+			columnName = fmt.Sprintf("column_%d", i+1)
+		}
+		unique := columnName
+		for n := 2; used[strings.ToLower(unique)]; n++ {
+			unique = fmt.Sprintf("%s_%d", columnName, n)
+		}
+		used[strings.ToLower(unique)] = true
+		names[i] = unique
+	}
+	return names
+}
+
+/*
+ * sqlColumnType maps a driver column type to the type of the created column and the literal its values are
+ * written as.
+ *
+ * Notes:
+ * - Every column is created NULL, the result does not tell which columns the source declared NOT NULL.
+ * - Character and binary types keep their length, MAX when the length is unknown or beyond the in row limit.
+ * - DECIMAL and NUMERIC keep their precision and scale, the driver returns their exact text.
+ * - TIMESTAMP (rowversion) cannot be inserted and becomes BINARY(8), UNIQUEIDENTIFIER is written as its 16 bytes,
+ *   which SQL Server converts back to the same GUID.
+ * - XML, SQL_VARIANT, the deprecated TEXT, NTEXT and IMAGE types, the CLR types and columns without a type,
+ *   such as the executed_queries result, are created as NVARCHAR(MAX) or VARBINARY(MAX).
+ */
+func sqlColumnType(columnType *sql.ColumnType) (string, int) {
+	if columnType == nil {
+		return "NVARCHAR(MAX)", sqlLiteralText
+	}
+	typeName := strings.ToUpper(columnType.DatabaseTypeName())
+	switch typeName {
+	case "BIGINT", "INT", "SMALLINT", "TINYINT", "FLOAT", "REAL", "MONEY", "SMALLMONEY":
+		return typeName, sqlLiteralNumber
+	case "DECIMAL", "NUMERIC":
+		if precision, scale, ok := columnType.DecimalSize(); ok {
+			return fmt.Sprintf("%s(%d,%d)", typeName, precision, scale), sqlLiteralNumber
+		}
+		return "DECIMAL(38,10)", sqlLiteralNumber
+	case "BIT":
+		return typeName, sqlLiteralBit
+	case "DATE":
+		return typeName, sqlLiteralDate
+	case "TIME":
+		return "TIME(7)", sqlLiteralTime
+	case "DATETIME", "SMALLDATETIME":
+		return typeName, sqlLiteralDateTime
+	case "DATETIME2":
+		return "DATETIME2(7)", sqlLiteralDateTime2
+	case "DATETIMEOFFSET":
+		return "DATETIMEOFFSET(7)", sqlLiteralOffset
+	case "CHAR", "VARCHAR", "NCHAR", "NVARCHAR":
+		limit := int64(8000)
+		if strings.HasPrefix(typeName, "N") {
+			limit = 4000
+		}
+		if length, ok := columnType.Length(); ok && length > 0 && length <= limit {
+			return fmt.Sprintf("%s(%d)", typeName, length), sqlLiteralText
+		}
+		return "NVARCHAR(MAX)", sqlLiteralText
+	case "BINARY", "VARBINARY":
+		if length, ok := columnType.Length(); ok && length > 0 && length <= 8000 {
+			return fmt.Sprintf("%s(%d)", typeName, length), sqlLiteralBinary
+		}
+		return "VARBINARY(MAX)", sqlLiteralBinary
+	case "TIMESTAMP":
+		return "BINARY(8)", sqlLiteralBinary
+	case "UNIQUEIDENTIFIER":
+		return typeName, sqlLiteralBinary
+	case "IMAGE":
+		return "VARBINARY(MAX)", sqlLiteralBinary
+	}
+	return "NVARCHAR(MAX)", sqlLiteralText
+}
+
+/*
+ * BeginResult writes the CREATE TABLE of a result, named after its sheet, with one column per result column.
+ * Empty and repeated column names are made unique as "column_<n>" and "<name>_<n>".
+ */
+func (w *sqlWriter) BeginResult(name string, query Query, columns []string, columnTypes []*sql.ColumnType) error {
+	if err := w.EndResult(); err != nil {
+		return err
+	}
+
+	w.table = quoteSQLName(name)
+	w.columns = make([]sqlColumn, len(columns))
+	names := uniqueColumnNames(columns)
+	definitions := make([]string, len(columns))
+	for i, column := range columns {
+		var columnType *sql.ColumnType
+		if i < len(columnTypes) {
+			columnType = columnTypes[i]
+		}
+		definition, literal := sqlColumnType(columnType)
+		if valueMappedColumn(column, query) {
+			definition, literal = "NVARCHAR(MAX)", sqlLiteralText
+		}
+
+		w.columns[i] = sqlColumn{name: quoteSQLName(names[i]), definition: definition, literal: literal}
+		definitions[i] = fmt.Sprintf("    %s %s NULL", w.columns[i].name, definition)
+	}
+
+	fmt.Fprintf(w.out, "-- %s\nCREATE TABLE %s (\n%s\n);\nGO\n\n", name, w.table, strings.Join(definitions, ",\n"))
+	return nil
+}
+
+/*
+ * sqlLiteral writes a scanned value as the SQL literal of its column.
+ */
+func sqlLiteral(column sqlColumn, v interface{}) string {
+	if v == nil {
+		return "NULL"
+	}
+	switch value := v.(type) {
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	case bool:
+		if value {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		switch column.literal {
+		case sqlLiteralDate:
+			return "'" + value.Format("2006-01-02") + "'"
+		case sqlLiteralTime:
+			return "'" + value.Format("15:04:05.9999999") + "'"
+		case sqlLiteralDateTime:
+			return "'" + value.Format("2006-01-02T15:04:05.999") + "'"
+		case sqlLiteralOffset:
+			return "'" + value.Format("2006-01-02T15:04:05.9999999-07:00") + "'"
+		}
+		return "'" + value.Format("2006-01-02T15:04:05.9999999") + "'"
+	case []byte:
+		switch column.literal {
+		case sqlLiteralBinary:
+			return "0x" + strings.ToUpper(hex.EncodeToString(value))
+		case sqlLiteralNumber:
+			// DECIMAL, NUMERIC and MONEY are returned as their exact text
+			return string(value)
+		}
+		return "N'" + strings.ReplaceAll(string(value), "'", "''") + "'"
+	case string:
+		return "N'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+	return "N'" + strings.ReplaceAll(fmt.Sprint(v), "'", "''") + "'"
+}
+
+/*
+ * WriteRow adds a row to the pending INSERT, written once `sqlInsertBatchRows` rows are pending.
+ */
+func (w *sqlWriter) WriteRow(values []interface{}) error {
+	literals := make([]string, len(values))
+	for i, v := range values {
+		literals[i] = sqlLiteral(w.columns[i], v)
+	}
+	w.pending = append(w.pending, "("+strings.Join(literals, ", ")+")")
+	if len(w.pending) >= sqlInsertBatchRows {
+		return w.flush()
+	}
+	return nil
+}
+
+/*
+ * flush writes the pending rows as one multi-row INSERT, followed by GO so sqlcmd and SSMS run the script in
+ * batches of bounded size.
+ */
+func (w *sqlWriter) flush() error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	names := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		names[i] = column.name
+	}
+	_, err := fmt.Fprintf(w.out, "INSERT INTO %s (%s) VALUES\n%s;\nGO\n\n", w.table, strings.Join(names, ", "), strings.Join(w.pending, ",\n"))
+	w.pending = w.pending[:0]
+	return err
+}
+
+/*
+ * EndResult writes the rows still pending for the current result.
+ */
+func (w *sqlWriter) EndResult() error {
+	if w.table == "" {
+		return nil
+	}
+	err := w.flush()
+	w.table, w.columns = "", nil
+	return err
+}
+
+/*
+ * Close completes the script and closes its file.
+ */
+func (w *sqlWriter) Close() error {
+	err := w.EndResult()
+	if flushErr := w.out.Flush(); err == nil {
+		err = flushErr
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	logInfo("SQL script written: %s", w.fileName)
+	return err
+}
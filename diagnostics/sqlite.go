@@ -0,0 +1,290 @@
+//go:build sqlite
+
+package diagnostics
+
+import (
+	"database/sql" // For writing the SQLite database and the column type information
+	"fmt"          // For formatted I/O operations
+	"os"           // For replacing the database of an earlier run with the same name
+	"strings"      // For string manipulation
+	"time"         // For the start time of the run
+
+	_ "modernc.org/sqlite" // Pure Go SQLite driver, registered as "sqlite"
+)
+
+/*
+ * The SQLite writer is only built with `-tags sqlite`, keeping the SQLite driver out of the default binary:
+ *
+ *   go build -tags sqlite
+ */
+func init() {
+	resultWriterFactories["sqlite"] = newSQLiteWriter
+}
+
+// Table recording when and against which server the results of the database were captured
+const sqliteRunMetadataTable = "run_metadata"
+
+// Rows held at most while a column has only NULL values, its type is then TEXT
+const sqliteMaxPendingRows = 1000
+
+/*
+ * sqliteWriter is the ResultWriter for `-format=sqlite`. Every result becomes a table of a single
+ * "<baseName>.sqlite" database, named after its sheet, so the results of several runs can be attached and
+ * joined with SQL.
+ *
+ * Fields:
+ * - fileName: The name of the database file.
+ * - db: The SQLite database.
+ * - tx: The transaction the rows of the current result are inserted in.
+ * - table: The quoted name of the current result's table, empty when no result is open.
+ * - columns: The quoted column names of the current result.
+ * - columnTypes: The driver column types of the current result, nil for results without types.
+ * - types: The SQLite type of every column, empty until a non-null value was seen in it.
+ * - pending: The rows read before every column had a non-null value, inserted once the table is created, at most
+ *   sqliteMaxPendingRows.
+ * - insert: The INSERT statement of the current table, nil until the table is created.
+ * - tables: The number of result tables written so far.
+ */
+type sqliteWriter struct {
+	fileName    string
+	db          *sql.DB
+	tx          *sql.Tx
+	table       string
+	columns     []string
+	columnTypes []*sql.ColumnType
+	types       []string
+	pending     [][]interface{}
+	insert      *sql.Stmt
+	tables      int
+}
+
+/*
+ * newSQLiteWriter creates the SQLite database and records the run in its run_metadata table.
+ *
+ * Parameters:
+ * - opts: The run options, whose `Server` is recorded in run_metadata.
+ * - baseName: The timestamped name of the database file, without its .sqlite extension.
+ *
+ * Returns:
+ * - ResultWriter: The writer, ready to receive results.
+ * - error: An error if the database cannot be created.
+ *
+ * Notes:
+ * - A database of the same name, left by an earlier run of an `-output` template without `{timestamp}`, is
+ *   removed first, as the Excel workbook is, so its tables do not collide with the new ones.
+ */
+func newSQLiteWriter(opts RunOptions, baseName string) (ResultWriter, error) {
+	fileName := baseName + ".sqlite"
+	if _, err := os.Stat(fileName); err == nil {
+		if err := os.Remove(fileName); err != nil {
+			return nil, fmt.Errorf("failed to remove the existing %s: %v", fileName, err)
+		}
+	}
+	db, err := sql.Open("sqlite", fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %v", fileName, err)
+	}
+
+	_, err = db.Exec(fmt.Sprintf("CREATE TABLE %s (started_at TEXT, server TEXT, database TEXT)", quoteSQLiteName(sqliteRunMetadataTable)))
+	if err == nil {
+		_, err = db.Exec(fmt.Sprintf("INSERT INTO %s VALUES (?, ?, ?)", quoteSQLiteName(sqliteRunMetadataTable)), time.Now().Format(time.RFC3339), opts.Server.Host, opts.Server.Database)
+	}
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to write the %s table of %s: %v", sqliteRunMetadataTable, fileName, err)
+	}
+	return &sqliteWriter{fileName: fileName, db: db}, nil
+}
+
+/*
+ * quoteSQLiteName double quotes an identifier, doubling any double quote it contains.
+ */
+func quoteSQLiteName(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+/*
+ * sqliteColumnType infers the SQLite type of a column from its first non-null scanned value.
+ *
+ * Notes:
+ * - DECIMAL, NUMERIC and MONEY values are scanned as their exact text, the column is NUMERIC so SQLite stores
+ *   them as numbers when that loses nothing.
+ * - Dates are stored as RFC 3339 text, which sorts and compares in time order.
+ * - Other []byte values are BLOB for the binary types and TEXT otherwise.
+ */
+func sqliteColumnType(v interface{}, columnType *sql.ColumnType) string {
+	switch v.(type) {
+	case int64, bool:
+		return "INTEGER"
+	case float64:
+		return "REAL"
+	case []byte:
+		if columnType != nil {
+			switch strings.ToUpper(columnType.DatabaseTypeName()) {
+			case "DECIMAL", "NUMERIC", "MONEY", "SMALLMONEY":
+				return "NUMERIC"
+			case "BINARY", "VARBINARY", "IMAGE", "TIMESTAMP", "UNIQUEIDENTIFIER":
+				return "BLOB"
+			}
+		}
+	}
+	return "TEXT"
+}
+
+/*
+ * sqliteValue converts a scanned value to the value stored in a column of the given SQLite type.
+ */
+func sqliteValue(v interface{}, columnType string) interface{} {
+	switch value := v.(type) {
+	case nil:
+		return nil
+	case int64, float64:
+		return value
+	case bool:
+		if value {
+			return 1
+		}
+		return 0
+	case []byte:
+		if columnType == "BLOB" {
+			return value
+		}
+	}
+	text, _ := exportText(v)
+	return text
+}
+
+/*
+ * BeginResult opens a result, its table is created once the type of every column is known.
+ * Empty and repeated column names are made unique as "column_<n>" and "<name>_<n>".
+ */
+func (w *sqliteWriter) BeginResult(name string, query Query, columns []string, columnTypes []*sql.ColumnType) error {
+	if err := w.EndResult(); err != nil {
+		return err
+	}
+
+	w.table = quoteSQLiteName(name)
+	w.columns = uniqueColumnNames(columns)
+	for i := range w.columns {
+		w.columns[i] = quoteSQLiteName(w.columns[i])
+	}
+	w.columnTypes = columnTypes
+	w.types = make([]string, len(columns))
+	w.pending = nil
+	return nil
+}
+
+/*
+ * WriteRow holds the row until the first non-null value of every column was seen, then creates the table and
+ * inserts the rows directly. A column still without a value after sqliteMaxPendingRows rows is created as TEXT,
+ * so a mostly NULL column does not keep the whole result in memory.
+ */
+func (w *sqliteWriter) WriteRow(values []interface{}) error {
+	if w.insert != nil {
+		return w.insertRow(values)
+	}
+
+	typed := true
+	for i, v := range values {
+		if w.types[i] == "" && v != nil {
+			var columnType *sql.ColumnType
+			if i < len(w.columnTypes) {
+				columnType = w.columnTypes[i]
+			}
+			w.types[i] = sqliteColumnType(v, columnType)
+		}
+		typed = typed && w.types[i] != ""
+	}
+	w.pending = append(w.pending, append([]interface{}(nil), values...))
+	if typed || len(w.pending) >= sqliteMaxPendingRows {
+		return w.createTable()
+	}
+	return nil
+}
+
+/*
+ * createTable creates the table of the current result, columns with only NULL values as TEXT, and inserts the
+ * rows held so far in a transaction committed by EndResult.
+ */
+func (w *sqliteWriter) createTable() error {
+	definitions := make([]string, len(w.columns))
+	placeholders := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		if w.types[i] == "" {
+			w.types[i] = "TEXT"
+		}
+		definitions[i] = column + " " + w.types[i]
+		placeholders[i] = "?"
+	}
+
+	if _, err := w.db.Exec(fmt.Sprintf("CREATE TABLE %s (%s)", w.table, strings.Join(definitions, ", "))); err != nil {
+		return fmt.Errorf("failed to create table %s: %v", w.table, err)
+	}
+	w.tables++
+
+	tx, err := w.db.Begin()
+	if err != nil {
+		return err
+	}
+	w.tx = tx
+	w.insert, err = tx.Prepare(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", w.table, strings.Join(w.columns, ", "), strings.Join(placeholders, ", ")))
+	if err != nil {
+		return fmt.Errorf("failed to prepare the insert into %s: %v", w.table, err)
+	}
+
+	pending := w.pending
+	w.pending = nil
+	for _, values := range pending {
+		if err := w.insertRow(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+ * insertRow inserts a row into the table of the current result.
+ */
+func (w *sqliteWriter) insertRow(values []interface{}) error {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = sqliteValue(v, w.types[i])
+	}
+	_, err := w.insert.Exec(args...)
+	return err
+}
+
+/*
+ * EndResult creates the table of a result whose columns were never all non-null, and commits its rows.
+ */
+func (w *sqliteWriter) EndResult() error {
+	if w.table == "" {
+		return nil
+	}
+	var err error
+	if w.insert == nil {
+		err = w.createTable()
+	}
+	if w.insert != nil {
+		w.insert.Close()
+	}
+	if w.tx != nil {
+		if commitErr := w.tx.Commit(); err == nil {
+			err = commitErr
+		}
+	}
+	w.table, w.columns, w.columnTypes, w.types, w.pending, w.insert, w.tx = "", nil, nil, nil, nil, nil, nil
+	return err
+}
+
+/*
+ * Close commits a result left open by a failed query and closes the database.
+ */
+func (w *sqliteWriter) Close() error {
+	err := w.EndResult()
+	if closeErr := w.db.Close(); err == nil {
+		err = closeErr
+	}
+	logInfo("SQLite database with %d result table(s) written: %s", w.tables, w.fileName)
+	return err
+}
@@ -0,0 +1,138 @@
+//go:build sqlite
+
+package diagnostics
+
+import (
+	"database/sql"  // For reading the written database back
+	"path/filepath" // For the database file in the test folder
+	"testing"       // For the test framework
+)
+
+/*
+ * writeSQLiteResult writes one result through a new sqliteWriter and closes it.
+ */
+func writeSQLiteResult(t *testing.T, baseName string, name string, columns []string, rows [][]interface{}) {
+	t.Helper()
+	writer, err := newSQLiteWriter(RunOptions{Server: runTarget{Host: "sql01", Database: "master"}}, baseName)
+	if err != nil {
+		t.Fatalf("newSQLiteWriter: %v", err)
+	}
+	if err := writer.BeginResult(name, Query{Name: name}, columns, nil); err != nil {
+		t.Fatalf("BeginResult: %v", err)
+	}
+	for _, row := range rows {
+		if err := writer.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+/*
+ * TestSQLiteWriterRoundTrip writes a result with typed and NULL values and reads it back through database/sql,
+ * checking the column types inferred from the first non-null values, the values and the run_metadata row.
+ */
+func TestSQLiteWriterRoundTrip(t *testing.T) {
+	baseName := filepath.Join(t.TempDir(), "diag")
+	writeSQLiteResult(t, baseName, "1_WaitStats", []string{"wait_type", "wait_ms", "pct", "is_idle", "wait_type"}, [][]interface{}{
+		{[]byte("CXPACKET"), nil, 12.5, true, []byte("a")},
+		{[]byte("LCK_M_X"), int64(420), nil, false, []byte("b")},
+	})
+
+	db, err := sql.Open("sqlite", baseName+".sqlite")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	columnTypes := map[string]string{}
+	info, err := db.Query(`SELECT name, type FROM pragma_table_info('1_WaitStats')`)
+	if err != nil {
+		t.Fatalf("table info: %v", err)
+	}
+	for info.Next() {
+		var name, columnType string
+		if err := info.Scan(&name, &columnType); err != nil {
+			t.Fatalf("scan table info: %v", err)
+		}
+		columnTypes[name] = columnType
+	}
+	info.Close()
+	want := map[string]string{"wait_type": "TEXT", "wait_ms": "INTEGER", "pct": "REAL", "is_idle": "INTEGER", "wait_type_2": "TEXT"}
+	for name, columnType := range want {
+		if columnTypes[name] != columnType {
+			t.Errorf("column %s has type %q, want %q (all: %v)", name, columnTypes[name], columnType, columnTypes)
+		}
+	}
+
+	var waitType string
+	var waitMS sql.NullInt64
+	var pct sql.NullFloat64
+	var idle int64
+	if err := db.QueryRow(`SELECT wait_type, wait_ms, pct, is_idle FROM "1_WaitStats" WHERE wait_type = 'LCK_M_X'`).Scan(&waitType, &waitMS, &pct, &idle); err != nil {
+		t.Fatalf("select row: %v", err)
+	}
+	if !waitMS.Valid || waitMS.Int64 != 420 || pct.Valid || idle != 0 {
+		t.Errorf("read back wait_ms=%v pct=%v is_idle=%d, want 420, NULL, 0", waitMS, pct, idle)
+	}
+
+	var server, database string
+	if err := db.QueryRow(`SELECT server, database FROM run_metadata`).Scan(&server, &database); err != nil {
+		t.Fatalf("select run_metadata: %v", err)
+	}
+	if server != "sql01" || database != "master" {
+		t.Errorf("run_metadata holds %s/%s, want sql01/master", server, database)
+	}
+}
+
+/*
+ * TestSQLiteWriterReplacesExistingDatabase writes twice to the same base name, as an -output template without
+ * {timestamp} does, the second run must replace the tables of the first instead of failing.
+ */
+func TestSQLiteWriterReplacesExistingDatabase(t *testing.T) {
+	baseName := filepath.Join(t.TempDir(), "diag")
+	writeSQLiteResult(t, baseName, "1_Version", []string{"version"}, [][]interface{}{{[]byte("16.0")}})
+	writeSQLiteResult(t, baseName, "1_Version", []string{"version"}, [][]interface{}{{[]byte("17.0")}, {[]byte("17.1")}})
+
+	db, err := sql.Open("sqlite", baseName+".sqlite")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	var rows, runs int
+	if err := db.QueryRow(`SELECT COUNT(*), (SELECT COUNT(*) FROM run_metadata) FROM "1_Version"`).Scan(&rows, &runs); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if rows != 2 || runs != 1 {
+		t.Errorf("got %d rows and %d runs, want the 2 rows and the run of the second run", rows, runs)
+	}
+}
+
+/*
+ * TestSQLiteWriterBoundsPendingRows writes a result whose second column is NULL in every row, the rows must be
+ * inserted once sqliteMaxPendingRows are held, with the column created as TEXT.
+ */
+func TestSQLiteWriterBoundsPendingRows(t *testing.T) {
+	writer, err := newSQLiteWriter(RunOptions{}, filepath.Join(t.TempDir(), "diag"))
+	if err != nil {
+		t.Fatalf("newSQLiteWriter: %v", err)
+	}
+	defer writer.Close()
+	w := writer.(*sqliteWriter)
+	if err := w.BeginResult("2_Sessions", Query{}, []string{"session_id", "blocked_by"}, nil); err != nil {
+		t.Fatalf("BeginResult: %v", err)
+	}
+	for i := 0; i < sqliteMaxPendingRows+10; i++ {
+		if err := w.WriteRow([]interface{}{int64(i), nil}); err != nil {
+			t.Fatalf("WriteRow %d: %v", i, err)
+		}
+		if len(w.pending) >= sqliteMaxPendingRows {
+			t.Fatalf("%d rows pending after row %d, want fewer than %d", len(w.pending), i, sqliteMaxPendingRows)
+		}
+	}
+	if w.insert == nil || w.types[1] != "TEXT" {
+		t.Errorf("table not created after %d rows, the all NULL column has type %q", sqliteMaxPendingRows, w.types[1])
+	}
+}
@@ -0,0 +1,111 @@
+package diagnostics
+
+import (
+	"context" // For routing the driver messages to the running query
+	"fmt"     // For formatted I/O operations
+	"regexp"  // For parsing the SET STATISTICS TIME messages
+	"strconv" // For converting strings to numbers and vice versa
+	"sync"    // For guarding the timing updated by the driver
+	"time"    // For working with date and time
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+// The CPU and elapsed times reported by SET STATISTICS TIME, for parse and compile and for each execution
+var statisticsTimeMessage = regexp.MustCompile(`CPU time = (\d+) ms,\s*elapsed time = (\d+) ms`)
+
+/*
+ * queryTiming is the duration of a query for `-statistics-time`.
+ *
+ * Fields:
+ * - mu: Guards the server times, added by the driver while the rows are read.
+ * - Duration: The wall clock duration measured by the client, from sending the query to the last row written.
+ * - ServerCPU: The CPU time reported by SQL Server, summed over parse, compile and every statement.
+ * - ServerElapsed: The elapsed time reported by SQL Server, summed the same way.
+ * - Reported: Whether SQL Server sent any STATISTICS TIME message for the query.
+ */
+type queryTiming struct {
+	mu            sync.Mutex
+	Duration      time.Duration
+	ServerCPU     time.Duration
+	ServerElapsed time.Duration
+	Reported      bool
+}
+
+// Context key of the queryTiming receiving the messages of a query
+type queryTimingKey struct{}
+
+/*
+ * addMessage adds the STATISTICS TIME figures of a server message to the timing, received through the
+ * serverMessageLogger.
+ *
+ * Returns:
+ * - true when the message was a STATISTICS TIME message, which is then not recorded as a query message.
+ */
+func (t *queryTiming) addMessage(msg string) bool {
+	matches := statisticsTimeMessage.FindAllStringSubmatch(msg, -1)
+	for _, match := range matches {
+		cpu, _ := strconv.ParseInt(match[1], 10, 64)
+		elapsed, _ := strconv.ParseInt(match[2], 10, 64)
+		t.mu.Lock()
+		t.ServerCPU += time.Duration(cpu) * time.Millisecond
+		t.ServerElapsed += time.Duration(elapsed) * time.Millisecond
+		t.Reported = true
+		t.mu.Unlock()
+	}
+	return len(matches) > 0
+}
+
+/*
+ * timedQueryContext returns the context to run a query in, with a queryTiming collecting its STATISTICS TIME
+ * messages when `-statistics-time` is set, nil otherwise. The context is derived from `parent`, so an
+ * interrupted run cancels the query.
+ */
+func timedQueryContext(parent context.Context, opts RunOptions) (context.Context, *queryTiming) {
+	if !opts.StatisticsTime {
+		return parent, nil
+	}
+	timing := &queryTiming{}
+	return context.WithValue(parent, queryTimingKey{}, timing), timing
+}
+
+/*
+ * timedQueryText prefixes the query with SET STATISTICS TIME ON when the context collects a queryTiming.
+ */
+func timedQueryText(ctx context.Context, query string) string {
+	if _, ok := ctx.Value(queryTimingKey{}).(*queryTiming); !ok {
+		return query
+	}
+	return "SET STATISTICS TIME ON;\n" + query
+}
+
+/*
+ * String formats the timing for the console.
+ */
+func (t *queryTiming) String() string {
+	if !t.Reported {
+		return fmt.Sprintf("duration %d ms, no server time reported", t.Duration.Milliseconds())
+	}
+	return fmt.Sprintf("duration %d ms, server CPU %d ms, server elapsed %d ms", t.Duration.Milliseconds(), t.ServerCPU.Milliseconds(), t.ServerElapsed.Milliseconds())
+}
+
+/*
+ * writeQueryTimingHeaders adds the server time columns to the "executed_queries" sheet, after the duration
+ * the landing page shows for every query.
+ */
+func writeQueryTimingHeaders(f *excelize.File) {
+	f.SetCellValue(executedQueriesSheetName, "H1", "Server CPU (ms)")
+	f.SetCellValue(executedQueriesSheetName, "I1", "Server Elapsed (ms)")
+}
+
+/*
+ * writeQueryTiming writes the server time of the query at the 0 based position `index` to the "executed_queries" sheet.
+ * The server columns stay empty when SQL Server reported no time, for example for a failed query.
+ */
+func writeQueryTiming(f *excelize.File, index int, timing *queryTiming) {
+	row := index + 2
+	if timing.Reported {
+		f.SetCellValue(executedQueriesSheetName, fmt.Sprintf("H%d", row), timing.ServerCPU.Milliseconds())
+		f.SetCellValue(executedQueriesSheetName, fmt.Sprintf("I%d", row), timing.ServerElapsed.Milliseconds())
+	}
+}
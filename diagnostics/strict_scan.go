@@ -0,0 +1,129 @@
+package diagnostics
+
+import (
+	"database/sql" // Database/sql package for column type information
+	"fmt"          // For formatted I/O operations
+	"math"         // For detecting NaN and infinite floating point values
+	"reflect"      // For comparing scanned values against the driver scan type
+	"strconv"      // For validating decimal and money values
+	"strings"      // For string manipulation
+	"unicode/utf8" // For detecting invalid UTF-8 text
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+// Name of the sheet holding the cells flagged by strict scanning
+const dataIssuesSheetName = "data_issues"
+
+/*
+ * DataIssue records a single cell whose scanned value failed to scan or looked coerced or lossy.
+ *
+ * Fields:
+ * - Sheet: The result sheet the cell was written to.
+ * - Cell: The Excel cell coordinate, for example "C12", or the row of the result for the other formats, "row 12".
+ * - Column: The source column name, "*" when the whole row failed to scan.
+ * - Issue: A human readable description of the problem.
+ */
+type DataIssue struct {
+	Sheet  string // Result sheet the cell belongs to
+	Cell   string // Excel cell coordinate
+	Column string // Source column name
+	Issue  string // Description of the problem
+}
+
+/*
+ * checkScannedValue inspects a single scanned value against the column type reported by the driver
+ * and describes anything that suggests the value was coerced or is lossy.
+ *
+ * Parameters:
+ * - v: The value scanned into an interface{} for the cell.
+ * - colType: The driver column type for the cell's column, may be nil.
+ *
+ * Returns:
+ * - A description of the issue, or an empty string when the value looks sound.
+ *
+ * Functionality:
+ * 1. Flags values whose Go type does not match the scan type the driver advertises for the column.
+ * 2. Flags NaN and infinite floating point values, which Excel cannot represent.
+ * 3. Flags DECIMAL, NUMERIC and MONEY values that do not parse as a number.
+ * 4. Flags text that is not valid UTF-8, where characters would be lost on write.
+ *
+ * Notes:
+ * - NULL values are never flagged, they are written as "NULL".
+ */
+func checkScannedValue(v interface{}, colType *sql.ColumnType) string {
+	if v == nil {
+		return ""
+	}
+
+	if colType != nil {
+		if scanType := colType.ScanType(); scanType != nil && scanType.Kind() != reflect.Interface {
+			if actual := reflect.TypeOf(v); actual != scanType && !actual.ConvertibleTo(scanType) {
+				return fmt.Sprintf("scanned as %s but driver reports %s (%s)", actual, scanType, colType.DatabaseTypeName())
+			}
+		}
+	}
+
+	switch value := v.(type) {
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Sprintf("non finite floating point value %v", value)
+		}
+	case []byte:
+		if colType != nil && isDecimalType(colType.DatabaseTypeName()) {
+			if _, err := strconv.ParseFloat(string(value), 64); err != nil {
+				return fmt.Sprintf("%s value %q is not a valid number", colType.DatabaseTypeName(), string(value))
+			}
+		} else if !utf8.Valid(value) {
+			return "binary or text value is not valid UTF-8, characters will be lost"
+		}
+	case string:
+		if !utf8.ValidString(value) {
+			return "text value is not valid UTF-8, characters will be lost"
+		}
+	}
+
+	return ""
+}
+
+/*
+ * isDecimalType reports whether the database type name is a fixed precision numeric type
+ * that the driver returns as its textual representation.
+ */
+func isDecimalType(typeName string) bool {
+	switch strings.ToUpper(typeName) {
+	case "DECIMAL", "NUMERIC", "MONEY", "SMALLMONEY":
+		return true
+	}
+	return false
+}
+
+/*
+ * writeDataIssuesSheet writes the collected strict scan issues to the "data_issues" sheet.
+ *
+ * Parameters:
+ * - f: A pointer to the excelize.File object representing the Excel file.
+ * - issues: The issues collected across all executed queries.
+ *
+ * Notes:
+ * - The sheet is created if it does not already exist.
+ * - An empty issue list still writes the header row so readers can tell strict scanning ran.
+ */
+func writeDataIssuesSheet(f *excelize.File, issues []DataIssue) {
+	if idx, _ := f.GetSheetIndex(dataIssuesSheetName); idx == -1 {
+		f.NewSheet(dataIssuesSheetName)
+	}
+
+	f.SetCellValue(dataIssuesSheetName, "A1", "Sheet")
+	f.SetCellValue(dataIssuesSheetName, "B1", "Cell")
+	f.SetCellValue(dataIssuesSheetName, "C1", "Column")
+	f.SetCellValue(dataIssuesSheetName, "D1", "Issue")
+
+	for i, issue := range issues {
+		rowNum := i + 2 // Start from row 2 (after header)
+		f.SetCellValue(dataIssuesSheetName, fmt.Sprintf("A%d", rowNum), issue.Sheet)
+		f.SetCellValue(dataIssuesSheetName, fmt.Sprintf("B%d", rowNum), issue.Cell)
+		f.SetCellValue(dataIssuesSheetName, fmt.Sprintf("C%d", rowNum), issue.Column)
+		f.SetCellValue(dataIssuesSheetName, fmt.Sprintf("D%d", rowNum), issue.Issue)
+	}
+}
@@ -0,0 +1,134 @@
+package diagnostics
+
+import (
+	"strconv" // For parsing decimal and money values
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+/*
+ * columnStats accumulates descriptive statistics for a single result column while rows are streamed.
+ *
+ * Fields:
+ * - count: The number of non NULL numeric values seen.
+ * - sum: The sum of the numeric values.
+ * - min: The smallest numeric value.
+ * - max: The largest numeric value.
+ * - nonNumeric: Set once a non NULL value that is not a number is seen, the column is then skipped.
+ */
+type columnStats struct {
+	count      int64
+	sum        float64
+	min        float64
+	max        float64
+	nonNumeric bool
+}
+
+/*
+ * add folds a scanned value into the statistics. NULL values are ignored and any value that is not
+ * numeric marks the column as non numeric.
+ */
+func (s *columnStats) add(v interface{}) {
+	if v == nil || s.nonNumeric {
+		return
+	}
+
+	n, ok := numericValue(v)
+	if !ok {
+		s.nonNumeric = true
+		return
+	}
+
+	if s.count == 0 || n < s.min {
+		s.min = n
+	}
+	if s.count == 0 || n > s.max {
+		s.max = n
+	}
+	s.sum += n
+	s.count++
+}
+
+/*
+ * numericValue converts a scanned value to a float64 when it is a number. DECIMAL, NUMERIC and MONEY
+ * values are scanned by the driver as byte arrays holding their textual representation.
+ */
+func numericValue(v interface{}) (float64, bool) {
+	switch value := v.(type) {
+	case int64:
+		return float64(value), true
+	case int32:
+		return float64(value), true
+	case int:
+		return float64(value), true
+	case float64:
+		return value, true
+	case float32:
+		return float64(value), true
+	case []byte:
+		n, err := strconv.ParseFloat(string(value), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// Suffix of the sheet holding the `-summarize` statistics of a result sheet
+const statsSheetSuffix = "_stats"
+
+/*
+ * statsSheetName names the `-summarize` statistics sheet of a result sheet by suffixing its name with "_stats",
+ * truncating the base so the name stays within Excel's 31 character limit, as resultSetSheetName does.
+ *
+ * Example:
+ * Input: sheetName = "3_WaitStats"
+ * Output: "3_WaitStats_stats"
+ */
+func statsSheetName(sheetName string) string {
+	if len(sheetName)+len(statsSheetSuffix) > 31 {
+		sheetName = sheetName[:31-len(statsSheetSuffix)]
+	}
+	return sheetName + statsSheetSuffix
+}
+
+/*
+ * writeStatsSheet writes the statistics of the numeric columns of a result sheet to its own sheet for `-summarize`.
+ *
+ * Parameters:
+ * - f: A pointer to the excelize.File object representing the Excel file.
+ * - sheetName: The result sheet the statistics describe, the statistics sheet is named by statsSheetName.
+ * - headers: The headers of the result sheet, in column order.
+ * - stats: The statistics collected for each column, in column order.
+ *
+ * Functionality:
+ * 1. Writes the statistic labels Count, Sum, Min, Max and Avg down the first column.
+ * 2. Writes one column per numeric column of the result, headed by its header, every column including the first
+ *    one of the result is summarized.
+ * 3. Columns with non numeric values, or with only NULL values, are left out, no sheet is written when none is left.
+ */
+func writeStatsSheet(f *excelize.File, sheetName string, headers []string, stats []columnStats) {
+	statsSheet := statsSheetName(sheetName)
+	column := 1
+	for colIndex, s := range stats {
+		if s.nonNumeric || s.count == 0 {
+			continue
+		}
+		if column == 1 {
+			f.NewSheet(statsSheet)
+			labels := []string{"Statistic", "Count", "Sum", "Min", "Max", "Avg"}
+			for i, label := range labels {
+				cell, _ := excelize.CoordinatesToCellName(1, 1+i)
+				f.SetCellValue(statsSheet, cell, label)
+			}
+		}
+		column++
+
+		values := []interface{}{headers[colIndex], s.count, s.sum, s.min, s.max, s.sum / float64(s.count)}
+		for i, value := range values {
+			cell, _ := excelize.CoordinatesToCellName(column, 1+i)
+			f.SetCellValue(statsSheet, cell, value)
+		}
+	}
+	if column == 1 {
+		logDebug("Sheet %s has no numeric column to summarize", sheetName)
+	}
+}
@@ -0,0 +1,100 @@
+package diagnostics
+
+import (
+	"database/sql/driver" // For the values of the fake result
+	"strings"             // For comparing the rows
+	"testing"             // For the test framework
+
+	"github.com/xuri/excelize/v2" // For reading back the statistics sheet
+)
+
+/*
+ * TestWriteStatsSheet writes a result whose first column is numeric with -summarize and checks every numeric
+ * column, the first one included, is summarized on the "<sheet>_stats" sheet under its label, the text column
+ * left out and nothing appended below the data.
+ */
+func TestWriteStatsSheet(t *testing.T) {
+	rows := queryFakeRows(t, fakeResultSet{
+		columns: []string{"session_id", "status", "cpu_ms"},
+		types:   []string{"INT", "NVARCHAR", "DECIMAL"},
+		scales:  map[int][2]int64{2: {10, 2}},
+		rows: [][]driver.Value{
+			{int64(51), "running", []byte("10.50")},
+			{int64(52), "sleeping", nil},
+			{int64(60), "running", []byte("4.50")},
+		},
+	})
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("ColumnTypes: %v", err)
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	report := newExcelReport(f, RunOptions{Summarize: true})
+	query := Query{Name: "Sessions", ColumnLabels: map[string]string{"session_id": "Session"}}
+	s := newResultSheet(report, "1_Sessions", query, []string{"session_id", "status", "cpu_ms"}, columnTypes, false)
+	if err := s.writeRows(rows, 1); err != nil {
+		t.Fatalf("writeRows: %v", err)
+	}
+	s.finish()
+
+	got, err := f.GetRows("1_Sessions_stats")
+	if err != nil {
+		t.Fatalf("GetRows of the statistics sheet: %v", err)
+	}
+	want := [][]string{
+		{"Statistic", "Session", "cpu_ms"},
+		{"Count", "3", "2"},
+		{"Sum", "163", "15"},
+		{"Min", "51", "4.5"},
+		{"Max", "60", "10.5"},
+		{"Avg", "54.3333333333333", "7.5"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("statistics sheet has %d rows %q, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if strings.Join(got[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("statistics row %d = %q, want %q", i+1, got[i], want[i])
+		}
+	}
+
+	if dataRows, _ := f.GetRows("1_Sessions"); len(dataRows) != resultFirstDataRow+2 {
+		t.Errorf("result sheet has %d rows, want the 3 data rows only", len(dataRows))
+	}
+}
+
+/*
+ * TestStatsSheetName checks the "_stats" suffix and the truncation of the base name to Excel's 31 characters.
+ */
+func TestStatsSheetName(t *testing.T) {
+	tests := []struct {
+		sheetName string
+		want      string
+	}{
+		{sheetName: "3_WaitStats", want: "3_WaitStats_stats"},
+		{sheetName: "5_Index_Usage_Statistics_For_Al", want: "5_Index_Usage_Statistics__stats"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sheetName, func(t *testing.T) {
+			if got := statsSheetName(tt.sheetName); got != tt.want {
+				t.Errorf("statsSheetName(%q) = %q, want %q", tt.sheetName, got, tt.want)
+			}
+		})
+	}
+}
+
+/*
+ * TestWriteStatsSheetNoNumericColumn checks no statistics sheet is written for a result without numeric values.
+ */
+func TestWriteStatsSheetNoNumericColumn(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+	stats := make([]columnStats, 2)
+	stats[0].add("running")
+	writeStatsSheet(f, "Sheet1", []string{"status", "empty"}, stats)
+	if idx, _ := f.GetSheetIndex("Sheet1_stats"); idx != -1 {
+		t.Errorf("wrote a statistics sheet for a result without numeric values")
+	}
+}
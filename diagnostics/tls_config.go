@@ -0,0 +1,129 @@
+package diagnostics
+
+import (
+	"fmt"     // For formatted I/O operations
+	"net/url" // For escaping connection string parameters
+	"os"      // For checking the CA certificate file
+	"strconv" // For parsing TRUST_SERVER_CERT
+	"strings" // For string manipulation
+
+	"github.com/magiconair/properties" // For reading the TLS properties
+)
+
+// Values of the ENCRYPT property, passed to the driver's `encrypt` parameter
+const (
+	encryptTrue   = "true"   // Encrypt the connection after the TDS login handshake
+	encryptFalse  = "false"  // Only encrypt the login packet, as the driver does by default
+	encryptStrict = "strict" // TDS 8.0, TLS is negotiated before any TDS packet, required by hardened servers
+)
+
+/*
+ * readTLSProperties reads the encryption properties of a configuration file into `sqlServerConfig`.
+ *
+ * Parameters:
+ * - sqlProperties: The loaded properties file.
+ * - sqlServerConfig: The configuration being read, updated in place.
+ *
+ * Returns:
+ * - An error for an unknown `ENCRYPT` value, an invalid `TRUST_SERVER_CERT`, an unreadable CA certificate, or
+ *   settings contradicting each other.
+ *
+ * Functionality:
+ * 1. `CA_CERT_FILE` is the path to the CA certificate the server certificate must chain to, `CA_CERT` is still
+ *    read when it is not set.
+ * 2. `ENCRYPT` is true, false or strict, `TRUST_SERVER_CERT` true skips the validation of the server certificate.
+ *    Either property set makes the configuration explicit: ENCRYPT defaults to true and TRUST_SERVER_CERT to
+ *    false, the certificate is validated against the system roots or the CA certificate.
+ * 3. Without `ENCRYPT` and `TRUST_SERVER_CERT`, the configuration is an existing one and `Encrypt` stays empty,
+ *    see `tlsParameters`. A warning suggests setting ENCRYPT.
+ *
+ * Notes:
+ * - TRUST_SERVER_CERT=true with a CA certificate is rejected, the CA would silently not be checked.
+ * - Azure AD connections cannot be unencrypted, ENCRYPT=false is rejected with AUTH_MODE=azuread.
+ */
+func readTLSProperties(sqlProperties *properties.Properties, sqlServerConfig *SQLServerConfig) error {
+	sqlServerConfig.CACert = strings.TrimSpace(sqlProperties.GetString("CA_CERT_FILE", ""))
+	caCertProperty := "CA_CERT_FILE"
+	if sqlServerConfig.CACert == "" {
+		sqlServerConfig.CACert = strings.TrimSpace(sqlProperties.GetString("CA_CERT", ""))
+		caCertProperty = "CA_CERT"
+	}
+	if sqlServerConfig.CACert != "" {
+		if _, err := os.Stat(sqlServerConfig.CACert); err != nil {
+			return fmt.Errorf("%s %s cannot be read: %v", caCertProperty, sqlServerConfig.CACert, err)
+		}
+	}
+	sqlServerConfig.HostNameInCertificate = strings.TrimSpace(sqlProperties.GetString("HOST_NAME_IN_CERTIFICATE", ""))
+
+	encrypt, encryptSet := sqlProperties.Get("ENCRYPT")
+	trustProperty, trustSet := sqlProperties.Get("TRUST_SERVER_CERT")
+	if !encryptSet && !trustSet {
+		if sqlServerConfig.CACert == "" && sqlServerConfig.AuthMode != authModeAzureAD {
+			logWarn("The connection is not encrypted and the server certificate is not validated, set ENCRYPT=true (or strict) in the configuration to encrypt it")
+		}
+		return nil
+	}
+
+	sqlServerConfig.Encrypt = encryptTrue
+	if encryptSet {
+		sqlServerConfig.Encrypt = strings.ToLower(strings.TrimSpace(encrypt))
+		switch sqlServerConfig.Encrypt {
+		case encryptTrue, encryptFalse, encryptStrict:
+		default:
+			return fmt.Errorf("ENCRYPT has the unknown value %q, expected %s, %s or %s", encrypt, encryptTrue, encryptFalse, encryptStrict)
+		}
+	}
+	if trustSet {
+		trust, err := strconv.ParseBool(strings.TrimSpace(trustProperty))
+		if err != nil {
+			return fmt.Errorf("TRUST_SERVER_CERT has the invalid value %q, expected true or false", trustProperty)
+		}
+		sqlServerConfig.TrustServerCert = trust
+	}
+
+	if sqlServerConfig.TrustServerCert && sqlServerConfig.CACert != "" {
+		return fmt.Errorf("TRUST_SERVER_CERT=true skips the validation of the server certificate against %s %s, remove one of them", caCertProperty, sqlServerConfig.CACert)
+	}
+	if sqlServerConfig.Encrypt == encryptFalse && sqlServerConfig.AuthMode == authModeAzureAD {
+		return fmt.Errorf("ENCRYPT=false cannot be used with AUTH_MODE=%s, Azure SQL only accepts encrypted connections", authModeAzureAD)
+	}
+	return nil
+}
+
+/*
+ * tlsParameters returns the encryption parameters appended to a connection string built from the configuration.
+ *
+ * Parameters:
+ * - sqlConfig: The configuration, with the properties read by `readTLSProperties`.
+ *
+ * Returns:
+ * - The `encrypt`, `trustservercertificate`, `certificate` and `hostnameincertificate` parameters, each prefixed with "&".
+ *
+ * Notes:
+ * - With `ENCRYPT` or `TRUST_SERVER_CERT` set, `encrypt` is the ENCRYPT value and the server certificate is validated
+ *   unless TRUST_SERVER_CERT=true.
+ * - Otherwise existing configurations keep their behaviour: with a CA certificate the connection is encrypted and
+ *   validated against it, Azure AD connections are encrypted and validated against the system roots, and the
+ *   others are not encrypted and trust any server certificate.
+ * - The CA is passed with the driver's `certificate` parameter, which loads the PEM (or DER) file into the
+ *   tls.Config RootCAs and sets its ServerName to the host, so the host name is verified against the certificate.
+ *   `hostnameincertificate` overrides the expected name when connecting by IP address or alias.
+ */
+func tlsParameters(sqlConfig SQLServerConfig) string {
+	encrypt, trust := sqlConfig.Encrypt, sqlConfig.TrustServerCert
+	if encrypt == "" {
+		encrypt, trust = encryptFalse, true
+		if sqlConfig.CACert != "" || sqlConfig.AuthMode == authModeAzureAD {
+			encrypt, trust = encryptTrue, false
+		}
+	}
+
+	parameters := "&encrypt=" + encrypt + "&trustservercertificate=" + strconv.FormatBool(trust)
+	if sqlConfig.CACert != "" {
+		parameters += "&certificate=" + url.QueryEscape(sqlConfig.CACert)
+	}
+	if sqlConfig.HostNameInCertificate != "" && !trust {
+		parameters += "&hostnameincertificate=" + url.QueryEscape(sqlConfig.HostNameInCertificate)
+	}
+	return parameters
+}
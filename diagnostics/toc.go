@@ -0,0 +1,130 @@
+package diagnostics
+
+import (
+	"fmt" // For formatted I/O operations
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+// Name of the table of contents sheet, the first sheet of the workbook
+const tocSheetName = "TOC"
+
+// Descriptions of the report sheets listed at the top of the TOC sheet, sheets not listed have none
+var tocReportSheetDescriptions = map[string]string{
+	executedQueriesSheetName: "The queries of the run, their notes and outcome",
+	runSummarySheetName:      "Row count, duration and status of every query",
+	overviewSheetName:        "One line digest of every query (-overview)",
+	permissionsSheetName:     "Permissions needed by the queries (-check-permissions)",
+	dataIssuesSheetName:      "Scan errors and lossy conversions (-strict-scan)",
+	recommendationsSheetName: "Missing index recommendations (-explain-missing-index)",
+	planAnalysisSheetName:    "Estimated and actual rows per plan operator (-plan-analysis)",
+}
+
+/*
+ * writeTOCSheet writes the "TOC" sheet, a table of contents linking to every sheet of the workbook, and moves
+ * it first.
+ *
+ * Parameters:
+ * - f: A pointer to the excelize.File object representing the Excel file.
+ * - queries: The queries of the run, in query order.
+ * - findings: The findings holding the outcome of every query run.
+ *
+ * Functionality:
+ * 1. Lists the report sheets first, executed_queries, run_summary and every other sheet that is not the result
+ *    of a query, such as the -pre-sql output, in workbook order.
+ * 2. Lists every query with its Sr.No, name, description, sheet and status. The further result sets of a query
+ *    and the -summarize statistics sheets follow on their own rows with only their sheet.
+ * 3. Every sheet name links to its sheet with an internal hyperlink.
+ *
+ * Notes:
+ * - Written last, so it only lists and links the sheets actually created. A failed query keeps its status and
+ *   error and is linked when its sheet was created before the failure, a skipped query has no sheet.
+ * - The workbook still opens on the executed_queries landing page, `-active-sheet=TOC` opens it on the TOC.
+ */
+func writeTOCSheet(f *excelize.File, queries Queries, findings *ReportFindings) {
+	if idx, _ := f.GetSheetIndex(tocSheetName); idx == -1 {
+		f.NewSheet(tocSheetName)
+	}
+
+	headers := []string{"Sr.No", "Query Name", "Description", "Sheet", "Status"}
+	for colIndex, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(colIndex+1, 1)
+		f.SetCellValue(tocSheetName, cell, header)
+	}
+
+	// The sheets of every query, its result sheet followed by the sheets of its further result sets, each followed
+	// by its -summarize statistics sheet
+	querySheets := make([][]string, len(queries.Queries))
+	isQuerySheet := map[string]bool{tocSheetName: true}
+	for i, query := range queries.Queries {
+		sheetName := createSheetName(i+1, query.Name)
+		if idx, _ := f.GetSheetIndex(sheetName); idx == -1 {
+			continue
+		}
+		querySheets[i] = append(querySheets[i], sheetName)
+		for resultSet := 2; ; resultSet++ {
+			further := resultSetSheetName(sheetName, resultSet)
+			if idx, _ := f.GetSheetIndex(further); idx == -1 {
+				break
+			}
+			querySheets[i] = append(querySheets[i], further)
+		}
+		var withStats []string
+		for _, name := range querySheets[i] {
+			withStats = append(withStats, name)
+			if idx, _ := f.GetSheetIndex(statsSheetName(name)); idx != -1 {
+				withStats = append(withStats, statsSheetName(name))
+			}
+		}
+		querySheets[i] = withStats
+		for _, name := range querySheets[i] {
+			isQuerySheet[name] = true
+		}
+	}
+
+	rowNum := 2 // Start from row 2 (after header)
+	link := func(sheetName string) {
+		f.SetCellValue(tocSheetName, fmt.Sprintf("D%d", rowNum), sheetName)
+		f.SetCellHyperLink(tocSheetName, fmt.Sprintf("D%d", rowNum), fmt.Sprintf("'%s'!A1", sheetName), "Location")
+	}
+
+	for _, sheetName := range f.GetSheetList() {
+		if isQuerySheet[sheetName] {
+			continue
+		}
+		f.SetCellValue(tocSheetName, fmt.Sprintf("B%d", rowNum), sheetName)
+		f.SetCellValue(tocSheetName, fmt.Sprintf("C%d", rowNum), tocReportSheetDescriptions[sheetName])
+		link(sheetName)
+		rowNum++
+	}
+
+	for i, query := range queries.Queries {
+		f.SetCellValue(tocSheetName, fmt.Sprintf("A%d", rowNum), i+1)
+		f.SetCellValue(tocSheetName, fmt.Sprintf("B%d", rowNum), query.Name)
+		f.SetCellValue(tocSheetName, fmt.Sprintf("C%d", rowNum), query.Description)
+
+		status := statusSkipped
+		if outcome := findings.outcomeOf(createSheetName(i+1, query.Name)); outcome != nil {
+			status = outcome.Status
+			if outcome.Error != "" {
+				status += ": " + outcome.Error
+			}
+		}
+		f.SetCellValue(tocSheetName, fmt.Sprintf("E%d", rowNum), status)
+
+		if len(querySheets[i]) == 0 {
+			rowNum++
+			continue
+		}
+		for _, sheetName := range querySheets[i] {
+			link(sheetName)
+			rowNum++
+		}
+	}
+
+	if first := f.GetSheetList()[0]; first != tocSheetName {
+		if err := f.MoveSheet(tocSheetName, first); err != nil {
+			logError("Failed to move the %s sheet first: %v", tocSheetName, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package diagnostics
+
+import (
+	"database/sql" // Database/sql package for database operations
+	"fmt"          // For formatted I/O operations
+	"strings"      // For string manipulation
+
+	"github.com/xuri/excelize/v2" // For creating and manipulating Excel files
+)
+
+/*
+ * TopHighlight is a query's `highlightTop` setting, flagging the rows holding the highest or lowest values of
+ * a numeric column for quick triage.
+ *
+ * Fields:
+ * - Column: The numeric column ranked, its name or its column label.
+ * - Count: The number of rows highlighted, those holding the Count highest (or lowest) values.
+ * - Bottom: Highlight the lowest values instead of the highest.
+ */
+type TopHighlight struct {
+	Column string `json:"column" toml:"column"`           // Numeric column ranked
+	Count  int    `json:"count" toml:"count"`             // Number of rows highlighted
+	Bottom bool   `json:"bottom,omitempty" toml:"bottom"` // Highlight the lowest values instead
+}
+
+// Database types of the columns `highlightTop` can rank
+var rankableColumnTypes = map[string]bool{
+	"BIGINT": true, "INT": true, "SMALLINT": true, "TINYINT": true, "DECIMAL": true, "NUMERIC": true,
+	"FLOAT": true, "REAL": true, "MONEY": true, "SMALLMONEY": true,
+}
+
+/*
+ * rankedColumn returns the index of the query's `highlightTop` column among the sheet columns, matching its
+ * name or label case insensitively, or -1 when the query highlights nothing. A missing or non numeric column
+ * and a count below 1 are reported and return -1.
+ */
+func rankedColumn(columns []string, columnTypes []*sql.ColumnType, query Query) int {
+	top := query.HighlightTop
+	if top == nil {
+		return -1
+	}
+	if top.Count <= 0 {
+		logWarn("Query %s: highlightTop count must be greater than 0, nothing is highlighted", query.Name)
+		return -1
+	}
+
+	labels := applyColumnLabels(columns, query)
+	for i := range columns {
+		if !strings.EqualFold(columns[i], top.Column) && !strings.EqualFold(labels[i], top.Column) {
+			continue
+		}
+		if typeName := strings.ToUpper(columnTypes[i].DatabaseTypeName()); !rankableColumnTypes[typeName] {
+			logWarn("Query %s: highlightTop column %q is %s, not numeric, nothing is highlighted", query.Name, top.Column, typeName)
+			return -1
+		}
+		return i
+	}
+	logWarn("Query %s: highlightTop column %q is not a column of the result, nothing is highlighted", query.Name, top.Column)
+	return -1
+}
+
+/*
+ * highlightTopRows highlights the rows of the sheet holding the `highlightTop` highest or lowest values of the
+ * ranked column.
+ *
+ * Notes:
+ * - The conditional format compares each row's value to LARGE (or SMALL) of the column, so the highlight follows
+ *   the values when the rows are sorted or filtered in Excel later. Ties with the last ranked value are highlighted too.
+ * - The ranked column is written as numbers, DECIMAL and MONEY values included, so Excel can rank them.
+ */
+func (s *resultSheet) highlightTopRows() {
+	if s.rankedColumn == -1 || s.rowIndex <= resultFirstDataRow {
+		return
+	}
+	top := s.query.HighlightTop
+
+	column, _ := excelize.ColumnNumberToName(s.rankedColumn + s.firstColumn())
+	lastRow := s.rowIndex - 1
+	first := resultFirstDataRow
+	rank := fmt.Sprintf("$%s$%d:$%s$%d", column, first, column, lastRow)
+	formula := fmt.Sprintf("AND(ISNUMBER($%s%d),$%s%d>=LARGE(%s,MIN(%d,COUNT(%s))))", column, first, column, first, rank, top.Count, rank)
+	if top.Bottom {
+		formula = fmt.Sprintf("AND(ISNUMBER($%s%d),$%s%d<=SMALL(%s,MIN(%d,COUNT(%s))))", column, first, column, first, rank, top.Count, rank)
+	}
+
+	styleID, err := s.report.conditionalStyle("highlight_top", &excelize.Style{
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFEB9C"}},
+		Font: &excelize.Font{Color: "9C5700", Bold: true},
+	})
+	if err != nil {
+		logError("Failed to create the highlight style: %v", err)
+		return
+	}
+	lastCell, _ := excelize.CoordinatesToCellName(len(s.columns)+s.firstColumn()-1, lastRow)
+	err = s.f.SetConditionalFormat(s.name, fmt.Sprintf("A%d:%s", first, lastCell), []excelize.ConditionalFormatOptions{
+		{Type: "formula", Criteria: formula, Format: &styleID},
+	})
+	if err != nil {
+		logError("Failed to highlight the top rows of sheet %s: %v", s.name, err)
+	}
+}
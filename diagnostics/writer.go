@@ -0,0 +1,602 @@
+package diagnostics
+
+import (
+	"context"      // For the query context
+	"database/sql" // Database/sql package for database operations
+	"fmt"          // For formatted I/O operations
+	"sort"         // For listing the registered formats
+	"strings"      // For string manipulation
+	"time"         // For working with date and time
+)
+
+// Default output format, the Excel workbook written by executeSQLQueriesAndCreateExcel
+const formatExcel = "xlsx"
+
+/*
+ * ResultWriter is implemented by every non Excel output format. Results are streamed to the writer
+ * one query at a time so a writer never needs to hold more than the current result in memory.
+ *
+ * Methods:
+ * - BeginResult: Starts a new result, named with the sanitized sheet name, with its column names and types.
+ * - WriteRow: Writes a single row of scanned values for the current result.
+ * - EndResult: Completes the current result.
+ * - Close: Flushes and releases anything held by the writer once all results are written.
+ */
+type ResultWriter interface {
+	BeginResult(name string, query Query, columns []string, columnTypes []*sql.ColumnType) error
+	WriteRow(values []interface{}) error
+	EndResult() error
+	Close() error
+}
+
+/*
+ * resultWriterFactories is the registry of the non Excel output formats accepted by the `-format` flag.
+ * Each factory receives the run options and the timestamped base name used for any output files.
+ */
+var resultWriterFactories = map[string]func(opts RunOptions, baseName string) (ResultWriter, error){
+	"console": newConsoleWriter,
+	"csv":     newCSVWriter,
+	"gsheets": newGoogleSheetsWriter,
+	"html":    newHTMLWriter,
+	"json":    newJSONWriter,
+	"sql":     newSQLWriter,
+}
+
+/*
+ * valueMappedColumn reports whether a result column, possibly renamed by a column label, has a value map,
+ * in which case it holds display text and is written as a string.
+ */
+func valueMappedColumn(header string, query Query) bool {
+	if _, ok := query.ValueMaps[header]; ok {
+		return true
+	}
+	for column, label := range query.ColumnLabels {
+		if label == header {
+			if _, ok := query.ValueMaps[column]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*
+ * supportedFormats lists every value accepted by the `-format` flag, used in flag help and error messages.
+ */
+func supportedFormats() []string {
+	formats := []string{formatExcel}
+	for name := range resultWriterFactories {
+		formats = append(formats, name)
+	}
+	sort.Strings(formats[1:])
+	return formats
+}
+
+/*
+ * parseFormats parses the `-format` flag, a single format or a comma separated list such as "xlsx,gsheets".
+ *
+ * Returns:
+ * - The formats in the order given, without duplicates, "excel" is accepted for "xlsx".
+ * - An error naming the first unsupported format.
+ */
+func parseFormats(value string) ([]string, error) {
+	var formats []string
+	for _, format := range strings.Split(strings.ToLower(value), ",") {
+		format = strings.TrimSpace(format)
+		if format == "excel" {
+			format = formatExcel
+		}
+		if format == "" || containsString(formats, format) {
+			continue
+		}
+		if !containsString(supportedFormats(), format) {
+			return nil, fmt.Errorf("unsupported output format %q, supported formats are: %s", format, strings.Join(supportedFormats(), ", "))
+		}
+		formats = append(formats, format)
+	}
+	if len(formats) == 0 {
+		formats = []string{formatExcel}
+	}
+	return formats, nil
+}
+
+/*
+ * multiWriter fans every result out to the writers of several formats, so each query is executed once
+ * whatever the number of formats.
+ *
+ * Fields:
+ * - formats: The format of each writer, used in messages.
+ * - writers: The writers, in the order of the `-format` flag.
+ * - failed: Set for a writer once it returned an error, it receives nothing more.
+ *
+ * Notes:
+ * - A failing writer is logged and dropped, the other writers carry on, so the methods never return an error
+ *   unless every writer has failed.
+ */
+type multiWriter struct {
+	formats []string
+	writers []ResultWriter
+	failed  []bool
+}
+
+/*
+ * newMultiWriter creates the writer of every format, skipping with a warning those that cannot be created.
+ *
+ * Parameters:
+ * - opts: The run options passed to every factory.
+ * - formats: The formats to write, each registered in `resultWriterFactories`.
+ * - baseName: The timestamped base name used for any output files.
+ *
+ * Returns:
+ * - The multiWriter, or an error when no writer could be created.
+ */
+func newMultiWriter(opts RunOptions, formats []string, baseName string) (*multiWriter, error) {
+	m := &multiWriter{}
+	for _, format := range formats {
+		writer, err := resultWriterFactories[format](opts, baseName)
+		if err != nil {
+			logError("Failed to create %s writer, it is skipped: %v", format, err)
+			continue
+		}
+		m.formats = append(m.formats, format)
+		m.writers = append(m.writers, writer)
+		m.failed = append(m.failed, false)
+	}
+	if len(m.writers) == 0 {
+		return nil, fmt.Errorf("none of the %s writers could be created", strings.Join(formats, ", "))
+	}
+	return m, nil
+}
+
+/*
+ * each calls the function for every writer that has not failed, dropping the writers that return an error.
+ */
+func (m *multiWriter) each(action string, call func(ResultWriter) error) error {
+	active := 0
+	for i, writer := range m.writers {
+		if m.failed[i] {
+			continue
+		}
+		if err := call(writer); err != nil {
+			logError("The %s writer failed to %s and is dropped, the other formats continue: %v", m.formats[i], action, err)
+			m.failed[i] = true
+			continue
+		}
+		active++
+	}
+	if active == 0 {
+		return fmt.Errorf("every output writer has failed")
+	}
+	return nil
+}
+
+// BeginResult starts the result on every writer
+func (m *multiWriter) BeginResult(name string, query Query, columns []string, columnTypes []*sql.ColumnType) error {
+	return m.each("begin "+name, func(w ResultWriter) error { return w.BeginResult(name, query, columns, columnTypes) })
+}
+
+// WriteRow writes the row to every writer
+func (m *multiWriter) WriteRow(values []interface{}) error {
+	return m.each("write a row", func(w ResultWriter) error { return w.WriteRow(values) })
+}
+
+// EndResult completes the result on every writer
+func (m *multiWriter) EndResult() error {
+	return m.each("end a result", func(w ResultWriter) error { return w.EndResult() })
+}
+
+// Close closes every writer, including those that failed so they release their resources
+func (m *multiWriter) Close() error {
+	var firstErr error
+	for i, writer := range m.writers {
+		if err := writer.Close(); err != nil && !m.failed[i] {
+			logError("Error writing %s output: %v", m.formats[i], err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+/*
+ * otherFormats returns the formats other than the one given, in their original order.
+ */
+func otherFormats(formats []string, format string) []string {
+	var others []string
+	for _, f := range formats {
+		if f != format {
+			others = append(others, f)
+		}
+	}
+	return others
+}
+
+/*
+ * fileFormats returns the formats that write output files or sheets, everything but console.
+ */
+func fileFormats(formats []string) []string {
+	var files []string
+	for _, format := range formats {
+		if format != "console" {
+			files = append(files, format)
+		}
+	}
+	return files
+}
+
+/*
+ * executeSQLQueriesWithWriter is the counterpart of executeSQLQueriesAndCreateExcel for the non Excel
+ * output formats. It reads the configuration and queries, executes each query and streams every result
+ * to the writer registered for `opts.Format`.
+ *
+ * Parameters:
+ * - ctx: The context of the run, cancelled on Ctrl-C or SIGTERM.
+ * - sqlConfigProp: A string representing the path to the SQL Server configuration file.
+ * - sqlQueries: A string representing the path to the JSON file containing the SQL queries.
+ * - opts: A `RunOptions` struct with the optional behaviours selected on the command line.
+ * - pool: The `connectionPool` the queries run on, left open for the next iteration of a scheduled run.
+ *
+ * Functionality:
+ * 1. Creates the writer of the requested format, or a multiWriter fanning every result out to several formats.
+ * 2. Reads the SQL Server configuration, connects to the database and reads the queries.
+ * 3. Writes the "executed_queries" metadata as the first result.
+ * 4. Executes each query and streams its rows to the writer, recording its outcome for `-metrics-file` and the
+ *    manifest. A failed query is logged and skipped unless `StopOnFirstError` is set, which stops the run and
+ *    returns the failure after closing the writer. Cancelling `ctx` cancels the running query and stops the run
+ *    before the next one.
+ * 5. Runs the `-pre-sql` and `-post-sql` hooks around the queries, their output is not captured.
+ * 6. Logs the issues found by `StrictScan`, these formats have no data_issues sheet.
+ * 7. Closes the writer so any buffered output is flushed.
+ *
+ * Returns:
+ * - The `ReportFindings` of the run: the outcome of every query run, the strict scan issues and the snapshot of
+ *   `SnapshotQuery`. The other findings of the Excel sheets are not collected for these formats.
+ * - An error when the configuration or the queries cannot be read, the database cannot be reached, the server
+ *   is not the declared version with `StrictVersion`, a setup hook fails, the output cannot be written or
+ *   `StopOnFirstError` stopped the run. The caller decides what it means, as for the Excel workbook.
+ */
+func executeSQLQueriesWithWriter(ctx context.Context, sqlConfigProp string, sqlQueries string, opts RunOptions, pool *connectionPool) (*ReportFindings, error) {
+	// Read the SQL Server Connection Configuration
+	sqlConfig, err := runSQLConfig(sqlConfigProp, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := pool.connect(sqlConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+	opts.Server = connectionRunTarget(buildConnectionString(sqlConfig))
+	opts.Redaction = sqlConfig.Redaction
+
+	// Read the JSON file containing the SQL Server Queries to be executed
+	queries, err := readQueries(sqlQueries)
+	if err != nil {
+		return nil, err
+	}
+
+	// Warn, or abort with -strict-version, when the queries are written for another version of SQL Server
+	if err := checkServerVersion(db, queries.QuerySource, opts); err != nil {
+		return nil, err
+	}
+
+	// Check the login's permissions before any query, only reported on the console for these formats
+	if opts.CheckPermissions {
+		if err := preflightPermissions(db, nil, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	baseName, err := outputBaseName(outputTemplate(opts, sqlConfig), opts.Server, opts.FilePrefix, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	formats := strings.Join(opts.Formats, ", ")
+
+	// Write the SQL of every query next to the output for reproduction
+	if opts.DumpSQL {
+		dumpSQLFiles(queries, baseName, opts)
+	}
+
+	// A single format writes directly, several formats share every result through a multiWriter
+	var writer ResultWriter
+	if len(opts.Formats) == 1 {
+		writer, err = resultWriterFactories[opts.Formats[0]](opts, baseName)
+	} else {
+		writer, err = newMultiWriter(opts, opts.Formats, baseName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s writer: %v", formats, err)
+	}
+
+	if err := writeExecutedQueries(writer, queries); err != nil {
+		logError("Failed to write executed_queries: %v", err)
+	}
+
+	// Run the setup hook on its dedicated connection, output is only captured in Excel workbooks
+	hookConn, err := openHookConnection(db, opts, queries)
+	if err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("failed to prepare the SQL hooks: %v", err)
+	}
+	if hookConn != nil {
+		defer hookConn.Close()
+	}
+	if opts.PreSQL != "" {
+		if err := runSQLHook(hookConn, opts.PreSQL, "pre_sql", nil); err != nil {
+			writer.Close()
+			return nil, fmt.Errorf("the -pre-sql setup failed: %v", err)
+		}
+	}
+	if err := runStatementHooks(hookConn, queries.PreRun, "preRun"); err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("the preRun statements of the queries file failed: %v", err)
+	}
+
+	// Set when -stop-on-first-error aborts the run
+	var firstError error
+
+	findings := &ReportFindings{Server: opts.Server}
+
+	for i, query := range queries.Queries {
+		if ctx.Err() != nil {
+			logWarn("Interrupted, closing the output with the results written so far.")
+			break
+		}
+		if !selectedQuery(opts, query) {
+			logInfo("Skipping Query: %s, not selected by -only or -tag", query.Name)
+			continue
+		}
+
+		logInfo("Executing Query: %s", query.Name)
+		logDebug("Description: %s", query.Description)
+		logDebug("Query: %s", query.Query)
+
+		name := createSheetName(i+1, query.Name)
+
+		// Back off while the server is busy
+		if opts.LoadGuard {
+			waitForServerLoad(db, opts)
+		}
+
+		outcome := findings.addOutcome(query, name)
+		queryCtx, cancel, timing := queryContext(ctx, opts, query.Name)
+		started := time.Now()
+		err := queryTimeoutError(queryCtx, opts, executeQueryToWriter(queryCtx, db, query, opts, writer, name, findings, outcome))
+		cancel()
+		duration := time.Since(started)
+		outcome.finish(duration, err)
+		if timing != nil {
+			timing.Duration = duration
+			logInfo("Query %s: %s", query.Name, timing)
+		}
+		if err != nil {
+			logError("Failed to execute query %s: %v", query.Name, err)
+			findings.FailedQueries++
+			if opts.StopOnFirstError && ctx.Err() == nil {
+				firstError = fmt.Errorf("query %d %s failed: %v\nQuery: %s", i+1, query.Name, err, query.Query)
+				break
+			}
+			continue
+		}
+		logInfo("Finished Query: %s in %s", query.Name, duration.Round(time.Millisecond))
+	}
+
+	if err := runStatementHooks(hookConn, queries.PostRun, "postRun"); err != nil {
+		logWarn("The postRun statements of the queries file failed: %v", err)
+	}
+	if opts.PostSQL != "" {
+		if err := runSQLHook(hookConn, opts.PostSQL, "post_sql", nil); err != nil {
+			logWarn("The -post-sql teardown failed: %v", err)
+		}
+	}
+
+	// These formats have no data_issues sheet, the strict scan issues are logged instead
+	if opts.StrictScan {
+		for _, issue := range findings.DataIssues {
+			logWarn("Data issue in %s %s, column %s: %s", issue.Sheet, issue.Cell, issue.Column, issue.Issue)
+		}
+		logInfo("Strict scan found %d data issue(s).", len(findings.DataIssues))
+	}
+
+	if err := writer.Close(); err != nil {
+		return findings, fmt.Errorf("error writing %s output: %v", formats, err)
+	}
+
+	// The console format writes no file, its output is already on screen
+	if files := fileFormats(opts.Formats); len(files) > 0 {
+		logInfo("%s output created successfully: %s", strings.Join(files, ", "), baseName)
+	}
+
+	if firstError != nil {
+		return findings, fmt.Errorf("stopped on first error, the output holds the results up to the failing query: %v", firstError)
+	}
+
+	return findings, nil
+}
+
+/*
+ * writeExecutedQueries writes the query metadata as an "executed_queries" result, matching the
+ * first sheet of the Excel workbook.
+ */
+func writeExecutedQueries(writer ResultWriter, queries Queries) error {
+	if err := writer.BeginResult("executed_queries", Query{Name: "executed_queries"}, []string{"Sr.No", "Query", "Query Notes"}, nil); err != nil {
+		return err
+	}
+	for i, query := range queries.Queries {
+		if err := writer.WriteRow([]interface{}{int64(i + 1), query.Query, query.Notes}); err != nil {
+			return err
+		}
+	}
+	return writer.EndResult()
+}
+
+/*
+ * executeQueryToWriter runs a SQL query and streams the result to a ResultWriter one row at a time.
+ *
+ * Parameters:
+ * - ctx: The query context, collecting the server time for `-statistics-time`.
+ * - db: A pointer to the `sql.DB` object representing the database connection.
+ * - query: The `Query` to execute.
+ * - opts: The run options, `ColumnsToFront` reorders the columns when the query has no list of its own.
+ * - writer: The ResultWriter receiving the result.
+ * - name: The sanitized name for the result, as produced by createSheetName.
+ * - findings: The findings of the run, receiving the snapshot of `SnapshotQuery` and the strict scan issues.
+ * - outcome: The outcome of the query, counting its rows, truncation and alerts.
+ *
+ * Returns:
+ * - error: Returns an error if the query execution or writing fails, nil otherwise.
+ *
+ * Notes:
+ * - Rows that fail to scan are logged and skipped, matching executeQueryToExcel.
+ * - The query's value maps are applied before the row reaches the writer.
+ * - The columns to front are moved left before the writer sees the result, as on the Excel sheets.
+ * - Every result set of the batch is a result of its own, the further ones named with the result set number
+ *   as their Excel sheets, such as "3_WaitStats_2". Only the first result set is kept in the snapshot and
+ *   counted in the `outcome`, as on the Excel sheets.
+ */
+func executeQueryToWriter(ctx context.Context, db *sql.DB, query Query, opts RunOptions, writer ResultWriter, name string, findings *ReportFindings, outcome *queryOutcome) error {
+	rows, release, err := runQueryRows(ctx, db, opts, query, timedQueryText(ctx, query.Query))
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer release()
+
+	snapshot := findings.startSnapshot(opts.SnapshotQuery, query)
+	for resultSet := 1; ; resultSet++ {
+		resultName := name
+		if resultSet > 1 {
+			resultName, snapshot, outcome = resultSetSheetName(name, resultSet), nil, nil
+		}
+		if err := writeResultSet(rows, query, opts, writer, resultName, findings, snapshot, outcome); err != nil {
+			if resultSet > 1 {
+				return fmt.Errorf("result set %d: %v", resultSet, err)
+			}
+			return err
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	return rows.Err()
+}
+
+/*
+ * writeResultSet streams the current result set of `rows` to the writer as the result `name`, for executeQueryToWriter.
+ * With `-max-rows`, the rows after the limit are not read and a warning is logged, no note row is added to the result.
+ * `-max-columns` fails or truncates a wide result as on the Excel sheets, a truncated result only gets a warning.
+ * With `-strict-scan` the rows that fail to scan and the suspicious values are added to the findings, with the
+ * row number of the result in place of the cell.
+ */
+func writeResultSet(rows *sql.Rows, query Query, opts RunOptions, writer ResultWriter, name string, findings *ReportFindings, snapshot *resultSnapshot, outcome *queryOutcome) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to get columns: %v", err)
+	}
+	if err := checkColumnCount(opts, columns); err != nil {
+		return err
+	}
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return fmt.Errorf("failed to get column types: %v", err)
+	}
+
+	// The columns to front are moved left, every value is scanned straight into its display position
+	rawColumns := columns
+	order, missing := columnOrder(columns, query, frontColumnList(query, opts))
+	if len(missing) > 0 {
+		logWarn("Query %s: columns to front not found in the result are ignored: %s", query.Name, strings.Join(missing, ", "))
+	}
+	columns = reorderColumns(columns, order)
+	columnTypes = reorderColumns(columnTypes, order)
+
+	// Every column is scanned, only the first -max-columns ones are written
+	resultColumns := len(columns)
+	if kept := keptColumns(opts, resultColumns); kept < resultColumns {
+		logWarn("Query %s: the result has %d columns, only the first %d are written to %s (-max-columns)", query.Name, resultColumns, kept, name)
+		columns, columnTypes = columns[:kept], columnTypes[:kept]
+	}
+
+	if err := writer.BeginResult(name, query, applyColumnLabels(columns, query), columnTypes); err != nil {
+		return err
+	}
+
+	// Create a slice of interface{}'s to hold each column value
+	scanned := make([]interface{}, resultColumns)
+	for i := range scanned {
+		scanned[i] = new(interface{})
+	}
+	values := scanned[:len(columns)]
+	targets := scanTargets(scanned, order)
+
+	alert := -1
+	if outcome != nil {
+		alert = alertColumn(columns, columnTypes, query)
+	}
+	valueMaps := columnValueMaps(columns, query)
+	redacted := opts.Redaction.columns(columns)
+	if snapshot != nil {
+		snapshot.Columns = columns
+	}
+
+	// A query with sortRows has its result set buffered and sorted before the first row is written
+	var source rowSource = rows
+	if len(query.SortRows) > 0 {
+		source = sortedRows(rows, rawColumns, query)
+	}
+
+	row := make([]interface{}, len(columns))
+	written := 0
+	for source.Next() {
+		if rowLimitReached(opts, written) {
+			logWarn("Query %s: only the first %d rows are written to %s (-max-rows)", query.Name, opts.MaxRows, name)
+			if outcome != nil {
+				outcome.Truncated = true
+			}
+			break
+		}
+		if err := source.Scan(targets...); err != nil {
+			logError("Failed to scan row: %v", err)
+			if opts.StrictScan {
+				findings.DataIssues = append(findings.DataIssues, DataIssue{Sheet: name, Cell: fmt.Sprintf("row %d", written+1), Column: "*", Issue: fmt.Sprintf("row scan failed and was skipped: %v", err)})
+			}
+			continue
+		}
+		opts.Redaction.apply(values, redacted)
+		if snapshot != nil {
+			snapshot.add(values)
+		}
+		if outcome != nil {
+			outcome.add(columns, values)
+		}
+		for i, val := range values {
+			row[i] = *(val.(*interface{}))
+			if opts.StrictScan && (redacted == nil || !redacted[i]) {
+				if issue := checkScannedValue(row[i], columnTypes[i]); issue != "" {
+					findings.DataIssues = append(findings.DataIssues, DataIssue{Sheet: name, Cell: fmt.Sprintf("row %d", written+1), Column: columns[i], Issue: issue})
+				}
+			}
+			if i == alert && query.Alert.matches(row[i]) {
+				outcome.Alerts++
+			}
+			if valueMaps != nil {
+				if display, ok := mapCellValue(valueMaps[i], row[i]); ok {
+					row[i] = display
+				}
+			}
+		}
+		if err := writer.WriteRow(row); err != nil {
+			return err
+		}
+		written++
+	}
+
+	// Check for errors during row iteration
+	if err = source.Err(); err != nil {
+		return fmt.Errorf("error occurred during row iteration: %v", err)
+	}
+
+	return writer.EndResult()
+}
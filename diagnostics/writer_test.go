@@ -0,0 +1,101 @@
+//go:build sqlite
+
+package diagnostics
+
+import (
+	"database/sql" // For the result sets streamed to the writer
+	"strings"      // For comparing the headers
+	"testing"      // For the test framework
+)
+
+/*
+ * queryTestRows runs a query on an in-memory SQLite database, the rows stand in for a SQL Server result.
+ */
+func queryTestRows(t *testing.T, query string) *sql.Rows {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	t.Cleanup(func() { rows.Close() })
+	return rows
+}
+
+/*
+ * TestWriteResultSetOutcome checks the non Excel formats record the rows, the summary and the -max-rows truncation
+ * of a query in its outcome, which -metrics-file and the manifest read.
+ */
+func TestWriteResultSetOutcome(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxRows       int
+		wantRows      int
+		wantTruncated bool
+	}{
+		{name: "every row", maxRows: 0, wantRows: 3},
+		{name: "cut by -max-rows", maxRows: 2, wantRows: 2, wantTruncated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := queryTestRows(t, `SELECT 'CXPACKET' AS wait_type, 420 AS wait_ms UNION ALL SELECT 'LCK_M_X', 12 UNION ALL SELECT 'PAGEIOLATCH_SH', 7`)
+			query := Query{Name: "Wait Stats", SummaryColumn: "wait_type"}
+			findings := &ReportFindings{}
+			outcome := findings.addOutcome(query, "1_Wait_Stats")
+			writer := &recordingWriter{}
+
+			if err := writeResultSet(rows, query, RunOptions{MaxRows: tt.maxRows}, writer, "1_Wait_Stats", findings, nil, outcome); err != nil {
+				t.Fatalf("writeResultSet: %v", err)
+			}
+			if outcome.Rows != tt.wantRows || outcome.Truncated != tt.wantTruncated {
+				t.Errorf("outcome has %d rows, truncated %v, want %d rows, truncated %v", outcome.Rows, outcome.Truncated, tt.wantRows, tt.wantTruncated)
+			}
+			if outcome.Summary != "CXPACKET" {
+				t.Errorf("outcome summary = %q, want the wait_type of the first row", outcome.Summary)
+			}
+			if got := len(writer.rows["1_Wait_Stats"]); got != tt.wantRows {
+				t.Errorf("wrote %d rows, want %d", got, tt.wantRows)
+			}
+		})
+	}
+}
+
+/*
+ * TestWriteResultSetMaxColumns checks -max-columns truncates a wide result to its first columns, or fails it with
+ * the fail action, as on the Excel sheets.
+ */
+func TestWriteResultSetMaxColumns(t *testing.T) {
+	tests := []struct {
+		name        string
+		action      string
+		wantHeaders []string
+		wantErr     bool
+	}{
+		{name: "truncate", action: maxColumnsTruncate, wantHeaders: []string{"a", "b"}},
+		{name: "fail", action: maxColumnsFail, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := queryTestRows(t, `SELECT 1 AS a, 2 AS b, 3 AS c`)
+			writer := &recordingWriter{}
+			opts := RunOptions{MaxColumns: 2, MaxColumnsAction: tt.action}
+			err := writeResultSet(rows, Query{Name: "Wide"}, opts, writer, "1_Wide", &ReportFindings{}, nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("writeResultSet error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := writer.headers["1_Wide"]; strings.Join(got, ",") != strings.Join(tt.wantHeaders, ",") {
+				t.Errorf("headers = %q, want %q", got, tt.wantHeaders)
+			}
+			if row := writer.rows["1_Wide"][0]; len(row) != 2 || row[0] != int64(1) || row[1] != int64(2) {
+				t.Errorf("row = %v, want the values of the first 2 columns", row)
+			}
+		})
+	}
+}
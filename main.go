@@ -0,0 +1,126 @@
+/*
+Package main
+
+This program connects to a SQL Server database, executes a series of SQL queries defined in a JSON file,
+and generates diagnostic reports in Excel format. It uses a configuration file to define database
+connection details and dynamically processes queries to produce results directly in Excel worksheets.
+
+The source of the SQL Queries come from https://glennsqlperformance.com/ and we acknowledge this great resource for troubleshooting SQL Server Performance.
+
+Another source of SQL Queries come from https://github.com/amachanic/sp_whoisactive/releases and http://whoisactive.com/docs/
+
+SP Who is Active is another great tool to get insights into what is occurring on the database.
+
+Another good source of SQL Troubleshooting is https://www.brentozar.com/archive/2010/09/sql-server-dba-scripts-how-to-find-slow-sql-server-queries/
+
+This tool allows to get diagnostics information for offline analysis so you can format your own queries and get details for offline analysis.
+
+One can easily run SQL Server profiler or configure MS SQL Server Extended Activities to monitor what is occurring on the database for troubleshooting
+performance issues in near real time, which is different from the purpose of this tool which allows for more offline analysis.
+
+
+Author: Malcolm Pereira
+Date: November 27, 2025
+Last Modified: November 27, 2025
+Revision: 2.0.0
+
+Usage:
+
+- Ensure there exists a `config.properties` file contains the correct database connection details.
+  Example:
+			DB_HOST=<host name>
+			DB_PORT=<port>
+			DB_NAME=<database name>
+			USER=<user>
+			PASSWORD=<password
+			TRUSTED=<use integrated security true or false in which case USER and PASSWORD is not needed>
+			ENCRYPT=<optional true, false or strict (TDS 8.0), encrypts the connection and validates the server certificate>
+			TRUST_SERVER_CERT=<optional true to skip validating the server certificate, defaults to false>
+			CA_CERT_FILE=<optional path to a PEM CA certificate the server certificate must chain to>
+
+- Define SQL queries in the json file (or a .toml file using the same keys) with the following structure.
+  Without a queries file, the sql_queries.json embedded in the binary when it was built is run.
+  Example:
+		{
+            ...
+			...
+			...
+			"preRun": ["SET STATISTICS IO OFF"],
+			"postRun": [],
+			"queries": [
+					{
+						"name": "CheckVersion",
+						"description": "Confirm if the SQL Queries will work for the version of SQL Server",
+						"query": "IF NOT EXISTS (SELECT * WHERE CONVERT(varchar(128), SERVERPROPERTY('ProductMajorVersion')) = '16') BEGIN DECLARE @ProductVersion varchar(128) = CONVERT(varchar(128), SERVERPROPERTY('ProductVersion')); SELECT SERVERPROPERTY('ProductMajorVersion') AS SERVER_VERSION, 'Script does not match the ProductVersion [%s] of this instance. Many of these queries may not work on this version.' AS MESSAGE END SELECT SERVERPROPERTY('ProductMajorVersion') AS SERVER_VERSION, 'Valid Server Version for the script.' AS MESSAGE",
+						"notes":"Confirm if the SQL Queries will work for the version of SQL Server"
+					}
+					...
+					...
+					...
+			]
+		}
+
+  The same structure in TOML, where multi-line literal strings avoid escaping the SQL.
+  Example:
+			[querysource]
+			sqlserverversion = "2022"
+
+			[[queries]]
+			name = "CheckVersion"
+			description = "Confirm if the SQL Queries will work for the version of SQL Server"
+			query = '''
+			SELECT SERVERPROPERTY('ProductMajorVersion') AS SERVER_VERSION
+			'''
+			notes = "Confirm if the SQL Queries will work for the version of SQL Server"
+
+- Run the program to generate diagnostic report, that is saved to Excel. The program will directly write query results to Excel worksheets without creating intermediate CSV files, resulting in faster processing and reduced disk I/O.
+
+Dependencies:
+	- github.com/microsoft/go-mssqldb for SQL Server connectivity.
+	- github.com/xuri/excelize/v2 for Excel file generation.
+	- github.com/magiconair/properties for reading configuration files.
+	- github.com/BurntSushi/toml for reading TOML query files.
+	- github.com/parquet-go/parquet-go for -format=parquet, only built with the parquet build tag.
+	- modernc.org/sqlite for -format=sqlite, only built with the sqlite build tag.
+
+Building:
+	//Manage Dependencies
+	- go mod tidy
+
+	//Build
+	- go build -o getSQLServerDiagnostics.exe
+	- go build
+
+	//Build with -format=parquet support
+	- go build -tags parquet
+
+	//Build with -format=sqlite support
+	- go build -tags sqlite
+
+Library:
+	The command is a thin wrapper around the malcolmpereira/getSQLServerDiagnostics/diagnostics package, other Go
+	programs import that package and call diagnostics.GenerateDiagnostics to get the workbook of a run.
+
+*/
+
+package main
+
+import (
+	_ "embed" // For baking the default queries into the binary
+
+	"malcolmpereira/getSQLServerDiagnostics/diagnostics" // The diagnostics engine
+)
+
+/*
+ * embeddedQueries is the default query suite, sql_queries.json as it was when the binary was built, run by
+ * `diagnostics.Main` when the `-queries` file does not exist so the binary runs the standard diagnostics on its own.
+ */
+//go:embed sql_queries.json
+var embeddedQueries []byte
+
+/*
+ * main is the entry point of the application, the command line tool is `diagnostics.Main`.
+ */
+func main() {
+	diagnostics.Main(embeddedQueries)
+}